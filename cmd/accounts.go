@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/auth"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// ListAccounts prints the logged in accounts and when their access tokens expire
+func ListAccounts(configPath string) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	if err := store.Init(); err != nil {
+		return err
+	}
+	accounts := utils.Accounts()
+	if len(accounts) == 0 {
+		fmt.Println("No accounts logged in")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCRM\tACCESS TOKEN EXPIRES")
+	for _, account := range accounts {
+		credentials, err := utils.GetAccountCredentials(account)
+		if err != nil || credentials == nil {
+			fmt.Fprintf(w, "%s\t\t\n", account)
+			continue
+		}
+		expires := "-"
+		if at, err := auth.TokenExpiry(credentials.AccessToken); err == nil {
+			expires = at.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", account, credentials.CRM, expires)
+	}
+	return w.Flush()
+}
+
+// LoginAccount logs in an account with OTP, or with password if password is set.
+// An empty name logs in the default account.
+func LoginAccount(configPath, name string, password bool) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	if name == "" {
+		name = utils.DEFAULT_ACCOUNT
+	}
+	if err := utils.ValidateAccountName(name); err != nil {
+		return err
+	}
+	if password {
+		return LoginPassword(name)
+	}
+	return LoginOTP(name)
+}
+
+// LogoutAccount logs out an account and removes its credentials
+func LogoutAccount(configPath, name string) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	if err := utils.ValidateAccountName(name); err != nil {
+		return err
+	}
+	return Logout(name)
+}
@@ -3,12 +3,15 @@ package cmd
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Varun03-max/JIO/internal/config"
 	"github.com/Varun03-max/JIO/internal/constants"
 	"github.com/Varun03-max/JIO/internal/handlers"
 	"github.com/Varun03-max/JIO/internal/middleware"
+	"github.com/Varun03-max/JIO/pkg/catalogue"
 	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/hdhomerun"
 	"github.com/Varun03-max/JIO/pkg/scheduler"
 	"github.com/Varun03-max/JIO/pkg/secureurl"
 	"github.com/Varun03-max/JIO/pkg/store"
@@ -39,6 +42,10 @@ func JioTVServer(cfg JioTVServerConfig) error {
 
 	utils.Log = utils.GetLogger()
 
+	if err := catalogue.Init(time.Duration(config.Cfg.ChannelsCacheTTL) * time.Minute); err != nil {
+		utils.Log.Println("Failed to load saved channel list:", err)
+	}
+
 	engine := html.NewFileSystem(http.FS(web.GetViewFiles()), ".html")
 	if config.Cfg.Debug {
 		engine.Reload(true)
@@ -86,15 +93,21 @@ func JioTVServer(cfg JioTVServerConfig) error {
 
 		scheduler.Init()
 		defer scheduler.Stop()
+		scheduler.Add("url_key_rotation", time.Hour, secureurl.RotateIfDue)
+		scheduler.Add(catalogue.TASK_ID, catalogue.TTL(), catalogue.Refresh)
 
 		// All protected routes
 		app.Use("/out/", handlers.SLHandler)
 		app.Get("/channels", handlers.ChannelsHandler)
 		app.Get("/playlist.m3u", handlers.PlaylistHandler)
+		app.Get("/playlist/:profile.m3u", handlers.ProfilePlaylistHandler)
 		app.Get("/live/:id", handlers.LiveHandler)
 		app.Get("/live/:quality/:id", handlers.LiveQualityHandler)
 		app.Get("/play/:id", handlers.PlayHandler)
 		app.Get("/player/:id", handlers.PlayerHandler)
+		app.Get("/stream/:id", handlers.StreamHandler)
+		app.Get("/catchup/:id", handlers.CatchupHandler)
+		app.Get("/catchup/:id/:programmeStart", handlers.CatchupProgrammeHandler)
 		app.Get("/render.m3u8", handlers.RenderHandler)
 		app.Get("/render.ts", handlers.RenderTSHandler)
 		app.Get("/render.key", handlers.RenderKeyHandler)
@@ -103,12 +116,56 @@ func JioTVServer(cfg JioTVServerConfig) error {
 		app.Get("/render.mpd", handlers.MpdHandler)
 		app.Use("/render.dash", handlers.DashHandler)
 		app.Get("/epg.xml.gz", handlers.EPGHandler)
+		app.Get("/epg/:profile.xml.gz", handlers.ProfileEPGHandler)
 		app.Get("/epg/:channelID/:offset", handlers.WebEPGHandler)
 		app.Get("/jtvimage/:file", handlers.ImageHandler)
 		app.Get("/jtvposter/:date/:file", handlers.PosterHandler)
 		app.Get("/dashtime", handlers.DASHTimeHandler)
 		app.Get("/logout", handlers.LogoutHandler)
+		app.Get("/api/auth/status", handlers.AuthStatusHandler)
+		app.Get("/api/accounts", handlers.AccountsHandler)
+		app.Delete("/api/accounts/:name", handlers.DeleteAccountHandler)
+		app.Get("/api/cache", handlers.CacheStatsHandler)
+		app.Get("/api/channels/changes", handlers.ChannelChangesHandler)
+		app.Get("/api/channels/overrides", handlers.ChannelOverridesHandler)
+		app.Put("/api/channels/overrides/:id", handlers.SetChannelOverrideHandler)
+		app.Delete("/api/channels/overrides/:id", handlers.DeleteChannelOverrideHandler)
+		app.Get("/changes", handlers.ChannelChangesPageHandler)
+		app.Get("/api/profiles", handlers.ProfilesHandler)
+		app.Get("/api/profiles/:name", handlers.ProfileHandler)
+		app.Put("/api/profiles/:name", handlers.SaveProfileHandler)
+		app.Delete("/api/profiles/:name", handlers.DeleteProfileHandler)
+		app.Get("/api/recordings", handlers.RecordingsHandler)
+		app.Post("/api/recordings", handlers.ScheduleRecordingHandler)
+		app.Get("/api/recordings/:id", handlers.RecordingHandler)
+		app.Post("/api/recordings/:id/stop", handlers.StopRecordingHandler)
+		app.Patch("/api/recordings/:id", handlers.RenameRecordingHandler)
+		app.Delete("/api/recordings/:id", handlers.DeleteRecordingHandler)
+		app.Get("/recordings", handlers.RecordingsPageHandler)
+		app.Get("/recordings/:id/play", handlers.RecordingPlayerHandler)
+		app.Get("/vod/:id/index.m3u8", handlers.VODPlaylistHandler)
+		app.Get("/vod/:id/video.ts", handlers.VODFileHandler)
+		app.Get("/api/rules", handlers.RecordingRulesHandler)
+		app.Post("/api/rules", handlers.AddRecordingRuleHandler)
+		app.Put("/api/rules/:id", handlers.UpdateRecordingRuleHandler)
+		app.Delete("/api/rules/:id", handlers.DeleteRecordingRuleHandler)
+		app.Get("/discover.json", handlers.DiscoverHandler)
+		app.Get("/lineup_status.json", handlers.LineupStatusHandler)
+		app.Get("/lineup.json", handlers.LineupHandler)
+		app.Post("/lineup.post", handlers.LineupPostHandler)
+		app.Get("/device.xml", handlers.DeviceXMLHandler)
+		app.All("/player_api.php", handlers.XtreamPlayerAPIHandler)
+		app.Get("/xmltv.php", handlers.XtreamXMLTVHandler)
+		app.Get("/live/:username/:password/:id", handlers.XtreamLiveHandler)
 		handlers.Init()
+
+		if config.Cfg.HDHomeRunSSDP {
+			go func() {
+				if err := hdhomerun.ListenSSDP(cfg.Port); err != nil {
+					utils.Log.Println("SSDP discovery stopped:", err)
+				}
+			}()
+		}
 	}
 
 	// Always show index page
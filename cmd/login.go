@@ -11,11 +11,11 @@ import (
 	"golang.org/x/term"
 )
 
-// Logout logs the user out by removing the saved login credentials file.
+// Logout logs an account out by removing its saved login credentials.
 // It checks if the file exists before removing to avoid errors.
 // Logs messages to provide feedback to the user.
 // Returns any errors encountered.
-func Logout() error {
+func Logout(account string) error {
 	err := store.Init()
 	if err != nil {
 		return err
@@ -27,7 +27,7 @@ func Logout() error {
 
 	log.Println("Deleting existing login file if exists")
 
-	err = utils.Logout()
+	err = utils.LogoutAccount(account)
 	if err != nil {
 		return err
 	}
@@ -37,11 +37,11 @@ func Logout() error {
 	return nil
 }
 
-// LoginOTP handles the login flow using OTP.
+// LoginOTP handles the login flow of an account using OTP.
 // It takes the mobile number as input, sends an OTP,
 // verifies the entered OTP by the user and logs in the user.
 // Returns any error encountered.
-func LoginOTP() error {
+func LoginOTP(account string) error {
 	utils.Log = utils.GetLogger()
 	err := store.Init()
 	if err != nil {
@@ -67,7 +67,7 @@ func LoginOTP() error {
 		var otp string
 		fmt.Scanln(&otp)
 
-		resultOTP, err := utils.LoginVerifyOTP(mobileNumber, otp)
+		resultOTP, err := utils.LoginVerifyOTP(account, mobileNumber, otp)
 		if err != nil {
 			return err
 		}
@@ -82,12 +82,12 @@ func LoginOTP() error {
 	return nil
 }
 
-// LoginPassword handles the login flow using password.
+// LoginPassword handles the login flow of an account using password.
 // It takes the mobile number and password as input,
 // verifies the credentials by calling the Login API
 // and logs in the user if successful.
 // Returns any error encountered.
-func LoginPassword() error {
+func LoginPassword(account string) error {
 	utils.Log = utils.GetLogger()
 	err := store.Init()
 	if err != nil {
@@ -103,7 +103,7 @@ func LoginPassword() error {
 		return err
 	}
 
-	result, err := utils.Login(mobileNumber, password)
+	result, err := utils.Login(account, mobileNumber, password)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/recorder"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// loadCLIConfig loads the config and logger for commands that run without the server
+func loadCLIConfig(configPath string) error {
+	if err := config.Cfg.Load(configPath); err != nil {
+		return err
+	}
+	utils.Log = utils.GetLogger()
+	return nil
+}
+
+// ListRules prints all recording rules
+func ListRules(configPath string) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	rules, err := recorder.Rules()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		fmt.Println("No recording rules")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCHANNEL\tTITLE\tKEYWORDS\tCATEGORY\tENABLED")
+	for _, rule := range rules {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", rule.ID, rule.ChannelID, rule.Title, strings.Join(rule.Keywords, ","), rule.Category, rule.Enabled)
+	}
+	return w.Flush()
+}
+
+// AddRule saves a new recording rule.
+// The running server picks it up on the next EPG generation or restart.
+func AddRule(configPath string, rule recorder.Rule) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	rule.Enabled = true
+	saved, err := recorder.AddRule(rule)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Added recording rule", saved.ID)
+	return nil
+}
+
+// DeleteRule removes a recording rule
+func DeleteRule(configPath, id string) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	if err := recorder.DeleteRule(id); err != nil {
+		return err
+	}
+	fmt.Println("Deleted recording rule", id)
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Varun03-max/JIO/pkg/store"
+)
+
+// RekeyStore encrypts the store with a new passphrase, read from newKeyFile or asked for on the terminal.
+// With decrypt set the values are stored in plaintext again.
+func RekeyStore(configPath, newKeyFile string, decrypt bool) error {
+	if err := loadCLIConfig(configPath); err != nil {
+		return err
+	}
+	// Opens the store with the current key
+	if err := store.Init(); err != nil {
+		return err
+	}
+
+	var passphrase string
+	switch {
+	case decrypt:
+	case newKeyFile != "":
+		data, err := os.ReadFile(newKeyFile)
+		if err != nil {
+			return err
+		}
+		if passphrase = strings.TrimSpace(string(data)); passphrase == "" {
+			return store.ErrNoStoreKey
+		}
+	default:
+		var err error
+		if passphrase, err = store.ReadPassphrase("New store passphrase: "); err != nil {
+			return err
+		}
+		repeated, err := store.ReadPassphrase("Repeat new store passphrase: ")
+		if err != nil {
+			return err
+		}
+		if repeated != passphrase {
+			return errors.New("passphrases do not match")
+		}
+	}
+
+	if err := store.Rekey(passphrase); err != nil {
+		return err
+	}
+	if decrypt {
+		fmt.Println("Store decrypted. Remove store_key, store_key_file and store_key_prompt from the config.")
+	} else {
+		fmt.Println("Store encrypted with the new passphrase. Update store_key or store_key_file before the next start.")
+	}
+	return nil
+}
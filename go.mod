@@ -9,6 +9,8 @@ require (
 	github.com/ilyakaznacheev/cleanenv v1.5.0
 	github.com/jiotv-go/jiotv_go/v3 v3.13.0
 	github.com/urfave/cli/v2 v2.27.6
+	golang.org/x/crypto v0.38.0
+	golang.org/x/sys v0.33.0
 	golang.org/x/term v0.32.0
 )
 
@@ -35,7 +37,6 @@ require (
 	github.com/valyala/fasthttp v1.62.0
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
 	golang.org/x/net v0.40.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -27,10 +27,36 @@ type JioTVConfig struct {
 	Title string `yaml:"title" env:"JIOTV_TITLE" json:"title" toml:"title"`
 	// Enable Or Disable URL Encryption. URL Encryption prevents hackers from injecting URLs into the server. Default: true
 	DisableURLEncryption bool `yaml:"disable_url_encryption" env:"JIOTV_DISABLE_URL_ENCRYPTION" json:"disable_url_encryption" toml:"disable_url_encryption"`
+	// Serve playlists at /live routes directly instead of redirecting to /render.m3u8, for players that do not follow redirects. Default: false
+	DirectPlaylists bool `yaml:"direct_playlists" env:"JIOTV_DIRECT_PLAYLISTS" json:"direct_playlists" toml:"direct_playlists"`
+	// Hours after which encrypted URLs in playlists expire. Default: 24
+	URLTokenExpiry int `yaml:"url_token_expiry" env:"JIOTV_URL_TOKEN_EXPIRY" json:"url_token_expiry" toml:"url_token_expiry" env-default:"24"`
 	// Proxy URL. Proxy is useful to bypass geo-restrictions and ip-restrictions for JioTV API. Default: ""
 	Proxy string `yaml:"proxy" env:"JIOTV_PROXY" json:"proxy" toml:"proxy"`
 	// PathPrefix is the prefix for all file paths managed by JioTV Go. Default: "$HOME/.jiotv_go"
 	PathPrefix string `yaml:"path_prefix" env:"JIOTV_PATH_PREFIX" json:"path_prefix" toml:"path_prefix"`
+	// Minutes the channel list is served from cache before it is fetched again. Default: 60
+	ChannelsCacheTTL int `yaml:"channels_cache_ttl" env:"JIOTV_CHANNELS_CACHE_TTL" json:"channels_cache_ttl" toml:"channels_cache_ttl" env-default:"60"`
+	// URL to which added, removed and renamed channels are posted as JSON whenever the channel list changes. Default: ""
+	ChannelsWebhook string `yaml:"channels_webhook" env:"JIOTV_CHANNELS_WEBHOOK" json:"channels_webhook" toml:"channels_webhook"`
+	// Size of the in-memory cache for playlists and segments in megabytes. Set to 0 to disable the cache. Default: 64
+	SegmentCacheSize int `yaml:"segment_cache_size" env:"JIOTV_SEGMENT_CACHE_SIZE" json:"segment_cache_size" toml:"segment_cache_size" env-default:"64"`
+	// Size of the on-disk spill area of the segment cache in megabytes. Evicted segments are kept under "$PATH_PREFIX/cache". Default: 0 (disabled)
+	SegmentCacheDiskSize int `yaml:"segment_cache_disk_size" env:"JIOTV_SEGMENT_CACHE_DISK_SIZE" json:"segment_cache_disk_size" toml:"segment_cache_disk_size"`
+	// Enable Or Disable answering SSDP discovery as an HDHomeRun tuner, so media servers find JioTV Go on the LAN. Default: false
+	HDHomeRunSSDP bool `yaml:"hdhomerun_ssdp" env:"JIOTV_HDHOMERUN_SSDP" json:"hdhomerun_ssdp" toml:"hdhomerun_ssdp"`
+	// Number of tuners the HDHomeRun emulation advertises, limiting how many channels media servers stream at once. Default: 4
+	HDHomeRunTuners int `yaml:"hdhomerun_tuners" env:"JIOTV_HDHOMERUN_TUNERS" json:"hdhomerun_tuners" toml:"hdhomerun_tuners" env-default:"4"`
+	// Username and password pairs allowed to use the Xtream Codes API. The API is disabled while empty. Default: {}
+	XtreamUsers map[string]string `yaml:"xtream_users" env:"JIOTV_XTREAM_USERS" json:"xtream_users" toml:"xtream_users"`
+	// Backend of the store that keeps the login tokens: "toml", "json" or "memory". The memory store is lost on restart. Default: "toml"
+	StoreBackend string `yaml:"store_backend" env:"JIOTV_STORE_BACKEND" json:"store_backend" toml:"store_backend" env-default:"toml"`
+	// Passphrase that encrypts the values in the store, like the login tokens. The store is not encrypted while no key is set. Default: ""
+	StoreKey string `yaml:"store_key" env:"JIOTV_STORE_KEY" json:"store_key" toml:"store_key"`
+	// File to read the store passphrase from, used when store_key is empty. Default: ""
+	StoreKeyFile string `yaml:"store_key_file" env:"JIOTV_STORE_KEY_FILE" json:"store_key_file" toml:"store_key_file"`
+	// Ask for the store passphrase on the terminal at startup, when neither store_key nor store_key_file is set. Default: false
+	StoreKeyPrompt bool `yaml:"store_key_prompt" env:"JIOTV_STORE_KEY_PROMPT" json:"store_key_prompt" toml:"store_key_prompt"`
 }
 
 // Cfg is the global config variable
@@ -1,23 +1,124 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
-	"strconv"
-	"time"
+	"slices"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/valyala/fasthttp"
 
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/auth"
+	"github.com/Varun03-max/JIO/pkg/catalogue"
+	"github.com/Varun03-max/JIO/pkg/epg"
 	"github.com/Varun03-max/JIO/pkg/scheduler"
-	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/session"
 	"github.com/Varun03-max/JIO/pkg/utils"
 )
 
-const (
-	REFRESH_TOKEN_TASK_ID    = "jiotv_refresh_token"
-	REFRESH_SSOTOKEN_TASK_ID = "jiotv_refresh_sso_token"
-)
+var sessionHooksOnce sync.Once
+
+// initSession connects the subsystems that depend on the login to the session.
+// Logins and logouts restart the token refresher of the account and drop the upstream URLs of live sessions.
+// Logins of the default account also refresh the channel list and start the EPG if it was not yet.
+// Refreshed tokens are swapped into the session as a new Television.
+func initSession() {
+	sessionHooksOnce.Do(func() {
+		auth.OnRefresh(func(account string, credentials *utils.JIOTV_CREDENTIALS) {
+			session.Update(account, credentials)
+		})
+		session.OnChange(auth.Start)
+		session.OnChange(func(string, *utils.JIOTV_CREDENTIALS) {
+			clearLiveSessions()
+		})
+		session.OnChange(func(account string, credentials *utils.JIOTV_CREDENTIALS) {
+			if credentials == nil || account != utils.DEFAULT_ACCOUNT {
+				return
+			}
+			go func() {
+				if err := catalogue.Refresh(); err != nil {
+					utils.Log.Println("Failed to refresh channel list after login:", err)
+				}
+			}()
+			if config.Cfg.EPG && scheduler.Scheduler != nil {
+				go epg.Init()
+			}
+		})
+	})
+}
+
+// initAccounts logs in the accounts other than the default one with their stored credentials
+func initAccounts() {
+	for _, account := range utils.Accounts() {
+		if account == utils.DEFAULT_ACCOUNT {
+			continue
+		}
+		credentials, err := utils.GetAccountCredentials(account)
+		if err != nil {
+			utils.Log.Println("Login error of account", account+":", err)
+			continue
+		}
+		session.Set(account, credentials)
+	}
+}
+
+// loginAccount replaces the session of an account after it logged in
+func loginAccount(account string) error {
+	credentials, err := utils.GetAccountCredentials(account)
+	if err != nil {
+		return err
+	}
+	session.Set(account, credentials)
+	return nil
+}
+
+// accountName returns the account named in a request, the default account if none is named
+func accountName(account string) string {
+	if account == "" {
+		return utils.DEFAULT_ACCOUNT
+	}
+	return account
+}
+
+// AuthStatusHandler responds with the expiry times of the tokens, the results of their last refreshes
+// and whether a new login is required. The account query parameter selects the account, the default one if empty.
+func AuthStatusHandler(c *fiber.Ctx) error {
+	account := accountName(c.Query("account"))
+	if err := utils.ValidateAccountName(account); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(auth.GetStatus(account))
+}
+
+// AccountsHandler responds with the login status of every account
+func AccountsHandler(c *fiber.Ctx) error {
+	statuses := []auth.Status{}
+	for _, account := range utils.Accounts() {
+		statuses = append(statuses, auth.GetStatus(account))
+	}
+	return c.JSON(statuses)
+}
+
+// DeleteAccountHandler logs out an account and forgets its credentials
+func DeleteAccountHandler(c *fiber.Ctx) error {
+	if isLogoutDisabled {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Logout is disabled"})
+	}
+	account := c.Params("name")
+	if err := utils.ValidateAccountName(account); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if !slices.Contains(utils.Accounts(), account) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Account not found: " + account})
+	}
+	// A refresh finishing after the credentials were deleted would store them again
+	auth.Stop(account)
+	if err := utils.LogoutAccount(account); err != nil {
+		utils.Log.Println("Logout error:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Internal server error"})
+	}
+	session.Set(account, nil)
+	return c.SendStatus(fiber.StatusNoContent)
+}
 
 // LoginSendOTPHandler sends OTP for login
 func LoginSendOTPHandler(c *fiber.Ctx) error {
@@ -51,17 +152,21 @@ func LoginVerifyOTPHandler(c *fiber.Ctx) error {
 	if err := checkFieldExist("OTP", formBody.OTP != "", c); err != nil {
 		return err
 	}
+	account := accountName(formBody.Account)
+	if err := utils.ValidateAccountName(account); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
 
-	result, err := utils.LoginVerifyOTP(formBody.MobileNumber, formBody.OTP)
+	result, err := utils.LoginVerifyOTP(account, formBody.MobileNumber, formBody.OTP)
 	if err != nil {
 		utils.Log.Println("Verify OTP Error:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Internal server error"})
 	}
 
 	if result["status"] == "success" {
-		// Only now we init the handlers safely
-		if err := Init(); err != nil {
-			utils.Log.Println("Init failed:", err)
+		if err := loginAccount(account); err != nil {
+			utils.Log.Println("Login error of account", account+":", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Internal server error"})
 		}
 		return c.JSON(fiber.Map{"status": "success", "message": "Login successful"})
 	}
@@ -71,11 +176,12 @@ func LoginVerifyOTPHandler(c *fiber.Ctx) error {
 
 // LoginPasswordHandler logs in using mobile + password
 func LoginPasswordHandler(c *fiber.Ctx) error {
-	var username, password string
+	var username, password, account string
 
 	if c.Method() == fiber.MethodGet {
 		username = c.Query("username")
 		password = c.Query("password")
+		account = c.Query("account")
 	} else {
 		formBody := new(LoginRequestBodyData)
 		if err := c.BodyParser(formBody); err != nil {
@@ -84,6 +190,7 @@ func LoginPasswordHandler(c *fiber.Ctx) error {
 		}
 		username = formBody.Username
 		password = formBody.Password
+		account = formBody.Account
 	}
 
 	if err := checkFieldExist("Username", username != "", c); err != nil {
@@ -92,16 +199,21 @@ func LoginPasswordHandler(c *fiber.Ctx) error {
 	if err := checkFieldExist("Password", password != "", c); err != nil {
 		return err
 	}
+	account = accountName(account)
+	if err := utils.ValidateAccountName(account); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
 
-	result, err := utils.Login(username, password)
+	result, err := utils.Login(account, username, password)
 	if err != nil {
 		utils.Log.Println("Password login error:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Internal server error"})
 	}
 
 	if result["status"] == "success" {
-		if err := Init(); err != nil {
-			utils.Log.Println("Init failed after password login:", err)
+		if err := loginAccount(account); err != nil {
+			utils.Log.Println("Login error of account", account+":", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Internal server error"})
 		}
 		return c.JSON(fiber.Map{"status": "success", "message": "Login successful"})
 	}
@@ -112,13 +224,12 @@ func LoginPasswordHandler(c *fiber.Ctx) error {
 // LogoutHandler logs out and resets session
 func LogoutHandler(c *fiber.Ctx) error {
 	if !isLogoutDisabled {
+		auth.Stop(utils.DEFAULT_ACCOUNT)
 		if err := utils.Logout(); err != nil {
 			utils.Log.Println("Logout error:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Internal server error"})
 		}
-		if err := Init(); err != nil {
-			utils.Log.Println("Re-init after logout error:", err)
-		}
+		session.Set(utils.DEFAULT_ACCOUNT, nil)
 	}
 	return c.Redirect("/", fiber.StatusFound)
 }
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/cache"
+	"github.com/Varun03-max/JIO/pkg/hls"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// SEGMENT_CACHE_TTL is used for segments whose playlist did not go through the cache
+	SEGMENT_CACHE_TTL = 30 * time.Second
+	// MASTER_PLAYLIST_CACHE_TTL is used for master playlists, which only change with their tokens
+	MASTER_PLAYLIST_CACHE_TTL = 10 * time.Second
+	// VOD_PLAYLIST_CACHE_TTL is used for playlists that will not get new segments
+	VOD_PLAYLIST_CACHE_TTL = 5 * time.Minute
+)
+
+// SegmentCache caches playlists and segments fetched from JioTV servers.
+// It is nil when the cache is disabled.
+var SegmentCache *cache.Cache
+
+// initSegmentCache creates SegmentCache from the config
+func initSegmentCache() {
+	SegmentCache = nil
+	if config.Cfg.SegmentCacheSize <= 0 {
+		utils.Log.Println("Segment cache disabled")
+		return
+	}
+	var dir string
+	if config.Cfg.SegmentCacheDiskSize > 0 {
+		dir = filepath.Join(utils.GetPathPrefix(), "cache")
+	}
+	segmentCache, err := cache.New(int64(config.Cfg.SegmentCacheSize)<<20, dir, int64(config.Cfg.SegmentCacheDiskSize)<<20)
+	if err != nil {
+		utils.Log.Println("Failed to create segment cache:", err)
+		return
+	}
+	SegmentCache = segmentCache
+}
+
+// fetchPlaylist returns the playlist at url of a channel, from SegmentCache if possible
+func fetchPlaylist(channelID, url string) ([]byte, int) {
+	tv := session.ForChannel(channelID)
+	if SegmentCache == nil {
+		body, statusCode, err := tv.Render(url)
+		if err != nil {
+			utils.Log.Println(err)
+			return nil, fiber.StatusBadGateway
+		}
+		return body, statusCode
+	}
+	entry, _, err := SegmentCache.Fetch(url, func() (*cache.Entry, error) {
+		body, statusCode, err := tv.Render(url)
+		if err != nil {
+			return nil, err
+		}
+		entry := &cache.Entry{Body: body, StatusCode: statusCode}
+		if statusCode == fasthttp.StatusOK {
+			entry.TTL = playlistTTL(url, body)
+		}
+		return entry, nil
+	})
+	if err != nil {
+		utils.Log.Println(err)
+		return nil, fiber.StatusBadGateway
+	}
+	return entry.Body, entry.StatusCode
+}
+
+// playlistTTL returns for how long a playlist may be cached and hints the TTL of its segments
+func playlistTTL(url string, body []byte) time.Duration {
+	playlist, err := hls.Parse(body)
+	if err != nil {
+		return 0
+	}
+	if playlist.Type == hls.Master {
+		return MASTER_PLAYLIST_CACHE_TTL
+	}
+	if playlist.EndList {
+		SegmentCache.SetTTLHint(url, VOD_PLAYLIST_CACHE_TTL)
+		return VOD_PLAYLIST_CACHE_TTL
+	}
+	targetDuration := time.Duration(playlist.TargetDuration * float64(time.Second))
+	// Segments stay in the live window for the duration of the whole playlist
+	window := targetDuration * time.Duration(len(playlist.Segments))
+	if window < targetDuration {
+		window = targetDuration
+	}
+	SegmentCache.SetTTLHint(url, window)
+	// A new segment is expected every target duration
+	return targetDuration / 2
+}
+
+// fetchSegment fetches a segment of a channel through SegmentCache.
+// The returned bool reports whether the segment was served from the cache.
+func fetchSegment(channelID, url string) (*cache.Entry, bool, error) {
+	tv := session.ForChannel(channelID)
+	return SegmentCache.Fetch(url, func() (*cache.Entry, error) {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI(url)
+		req.Header.SetMethod("GET")
+		req.Header.SetUserAgent(PLAYER_USER_AGENT)
+
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+		if err := tv.Client.Do(req, resp); err != nil {
+			return nil, fmt.Errorf("fetching segment: %w", err)
+		}
+
+		entry := &cache.Entry{
+			Body:        append([]byte(nil), resp.Body()...),
+			ContentType: string(resp.Header.ContentType()),
+			StatusCode:  resp.StatusCode(),
+		}
+		if entry.StatusCode == fasthttp.StatusOK {
+			entry.TTL = SegmentCache.TTLHint(url, SEGMENT_CACHE_TTL)
+		}
+		return entry, nil
+	})
+}
+
+// CacheStatsHandler responds with the hit and miss counters of the segment cache
+func CacheStatsHandler(c *fiber.Ctx) error {
+	if SegmentCache == nil {
+		return c.JSON(fiber.Map{
+			"enabled": false,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"enabled": true,
+		"stats":   SegmentCache.Stats(),
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/catalogue"
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var catalogueOnce sync.Once
+
+// initCatalogue delivers channel list changes to the configured webhook
+func initCatalogue() {
+	if config.Cfg.ChannelsWebhook == "" {
+		return
+	}
+	catalogueOnce.Do(func() {
+		webhook := catalogue.Webhook(config.Cfg.ChannelsWebhook)
+		catalogue.OnChange(func(changes []catalogue.Change) {
+			go webhook(changes)
+		})
+		utils.Log.Println("Channel changes are delivered to webhook")
+	})
+}
+
+// catalogueNotModified sets the ETag and Last-Modified headers of a response built from the channel list.
+// It reports whether the client already has the response, so it can be answered with 304 status code.
+func catalogueNotModified(c *fiber.Ctx, etag string, modifiedAt time.Time) bool {
+	// Responses also depend on the query and the host the server is reached at
+	h := fnv.New32a()
+	h.Write(c.Request().URI().QueryString())
+	h.Write([]byte(c.Protocol() + "://" + c.Hostname()))
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`W/"%s-%x"`, etag, h.Sum32()))
+	c.Set(fiber.HeaderLastModified, modifiedAt.UTC().Format(http.TimeFormat))
+	return c.Fresh()
+}
+
+// ChannelChangesHandler responds with the added, removed and renamed channels and changed logos.
+// Only changes after the optional `since` query parameter are listed.
+func ChannelChangesHandler(c *fiber.Ctx) error {
+	var since time.Time
+	if c.Query("since") != "" {
+		var err error
+		if since, err = parseTime(c.Query("since")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid since time: " + err.Error(),
+			})
+		}
+	}
+	return c.JSON(fiber.Map{
+		"changes": catalogue.Changes(since),
+	})
+}
+
+// ChannelChangesPageHandler renders the changelog of the channel list
+func ChannelChangesPageHandler(c *fiber.Ctx) error {
+	changes := catalogue.Changes(time.Time{})
+	views := make([]ChannelChangeView, 0, len(changes))
+	for _, change := range changes {
+		view := ChannelChangeView{
+			Change:   change,
+			TimeText: change.Time.Local().Format("Mon, 02 Jan 2006 15:04"),
+		}
+		if change.LogoURL != "" {
+			view.ImageURL = "/jtvimage/" + change.LogoURL
+		}
+		views = append(views, view)
+	}
+	return c.Render("views/changes", fiber.Map{
+		"Title":   Title,
+		"Changes": views,
+	})
+}
+
+// ChannelOverridesHandler responds with the user overrides of channels by channel ID
+func ChannelOverridesHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"overrides": catalogue.Overrides(),
+	})
+}
+
+// SetChannelOverrideHandler saves the override of a channel
+func SetChannelOverrideHandler(c *fiber.Ctx) error {
+	var override catalogue.Override
+	if err := c.BodyParser(&override); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid JSON",
+		})
+	}
+	if err := catalogue.SetOverride(c.Params("id"), override); err != nil {
+		return overrideErrorHandler(c, err)
+	}
+	return c.JSON(override)
+}
+
+// DeleteChannelOverrideHandler removes the override of a channel
+func DeleteChannelOverrideHandler(c *fiber.Ctx) error {
+	if err := catalogue.DeleteOverride(c.Params("id")); err != nil {
+		return overrideErrorHandler(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// overrideErrorHandler maps errors of channel overrides to status codes
+func overrideErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, catalogue.ErrOverrideNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, catalogue.ErrInvalidNumber):
+		status = fiber.StatusBadRequest
+	default:
+		utils.Log.Println(err)
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"message": err.Error(),
+	})
+}
+
+var (
+	filteredEPGMu    sync.Mutex
+	filteredEPGCache = make(map[string]filteredEPGEntry)
+)
+
+// overriddenEPG returns the generated EPG with the display names of the channel overrides.
+// Hidden channels are left out.
+func overriddenEPG(overrides map[string]catalogue.Override) ([]byte, error) {
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	return filteredEPG("", string(data), func(channel epg.Channel) (epg.Channel, bool) {
+		override := overrides[strconv.Itoa(channel.ID)]
+		if override.Name != "" {
+			channel.Display = override.Name
+		}
+		return channel, !override.Hidden
+	})
+}
+
+// filteredEPG returns the generated EPG filtered by fn as gzip.
+// Results are kept by name until the EPG or the key change.
+func filteredEPG(name, key string, fn func(epg.Channel) (epg.Channel, bool)) ([]byte, error) {
+	stat, err := os.Stat(utils.GetPathPrefix() + "epg.xml.gz")
+	if err != nil {
+		return nil, err
+	}
+	key = stat.ModTime().String() + key
+
+	filteredEPGMu.Lock()
+	defer filteredEPGMu.Unlock()
+	if entry, ok := filteredEPGCache[name]; ok && entry.key == key {
+		return entry.data, nil
+	}
+	generated, err := epg.Load()
+	if err != nil {
+		return nil, err
+	}
+	result, err := generated.Filter(fn).Gzip()
+	if err != nil {
+		return nil, err
+	}
+	filteredEPGCache[name] = filteredEPGEntry{key: key, data: result}
+	return result, nil
+}
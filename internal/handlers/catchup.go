@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/secureurl"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CATCHUP_DAYS is how many days of past programmes JioTV keeps
+const CATCHUP_DAYS = 7
+
+// JioTV EPG days follow Indian Standard Time
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+// fetchChannelEPG fetches the EPG of a channel on a day relative to today from JioTV
+var fetchChannelEPG = epg.FetchChannelEPG
+
+// CatchupHandler handles the catch-up route `/catchup/:id?start=...&end=...`.
+// It plays the archived window between start and end of a channel.
+func CatchupHandler(c *fiber.Ctx) error {
+	id := strings.TrimSuffix(c.Params("id"), ".m3u8")
+	start, err := parseTime(c.Query("start"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid start time: " + err.Error(),
+		})
+	}
+	end, err := parseTime(c.Query("end"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid end time: " + err.Error(),
+		})
+	}
+	return catchupRedirect(c, id, start, end)
+}
+
+// CatchupProgrammeHandler handles the catch-up route `/catchup/:id/:programmeStart`.
+// It plays the programme of a channel that started at programmeStart.
+func CatchupProgrammeHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	start, end, err := programmeWindow(id, c.Params("programmeStart"))
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, epg.ErrProgrammeNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	return catchupRedirect(c, id, start, end)
+}
+
+// catchupRedirect requests the archived window from JioTV and redirects to the render path
+func catchupRedirect(c *fiber.Ctx, id string, start, end time.Time) error {
+	now := time.Now()
+	if !start.Before(now) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Programme has not aired yet",
+		})
+	}
+	if start.Before(now.AddDate(0, 0, -CATCHUP_DAYS)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Programme is older than the catch-up window",
+		})
+	}
+	// Programmes still on air are played from their start up to now
+	if end.After(now) {
+		end = now
+	}
+
+	var result *television.LiveURLOutput
+	err := session.WithChannel(id, func(tv *television.Television) error {
+		var err error
+		result, err = tv.Catchup(id, start, end)
+		return err
+	})
+	if errors.Is(err, television.ErrCatchupNotSupported) || errors.Is(err, television.ErrInvalidCatchupWindow) {
+		utils.Log.Println(err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	if err != nil {
+		return upstreamErrorHandler(c, err)
+	}
+	catchupURL := result.Bitrates.Auto
+	if catchupURL == "" {
+		catchupURL = result.Result
+	}
+	if catchupURL == "" {
+		error_message := "No catch-up stream found for channel id: " + id + " Status: " + result.Message
+		utils.Log.Println(error_message)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": error_message,
+		})
+	}
+
+	coded_url, err := secureurl.EncryptURL(catchupURL)
+	if err != nil {
+		utils.Log.Println(err)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	return c.Redirect("/render.m3u8?auth="+coded_url+"&channel_key_id="+id, fiber.StatusFound)
+}
+
+// programmeWindow returns the start and end of the programme of a channel starting at programmeStart.
+// The generated EPG is used if available, otherwise the EPG of that day is fetched from JioTV.
+func programmeWindow(channelID, programmeStart string) (time.Time, time.Time, error) {
+	start, err := parseTime(programmeStart)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if programme, err := findProgramme(channelID, programmeStart); err == nil {
+		end, err := programme.StopTime()
+		return start, end, err
+	}
+
+	channelIntID, err := strconv.Atoi(strings.TrimPrefix(channelID, "sl"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid channel ID")
+	}
+	epgResponse, err := fetchChannelEPG(session.TV().Client, channelIntID, epgDayOffset(start, time.Now()))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	for _, programme := range epgResponse.EPG {
+		if programme.StartEpoch == start.UnixMilli() {
+			return start, time.UnixMilli(programme.EndEpoch), nil
+		}
+	}
+	return time.Time{}, time.Time{}, epg.ErrProgrammeNotFound
+}
+
+// epgDayOffset returns the day of t relative to the day of now in JioTV EPG requests
+func epgDayOffset(t, now time.Time) int {
+	today := now.In(ist)
+	day := t.In(ist)
+	return int(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, ist).
+		Sub(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, ist)).Hours() / 24)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+func TestEPGDayOffset(t *testing.T) {
+	// 10:00 IST
+	now := time.Date(2024, 3, 10, 4, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		t    time.Time
+		want int
+	}{
+		{"now", now, 0},
+		{"IST midnight", time.Date(2024, 3, 9, 18, 30, 0, 0, time.UTC), 0},
+		{"before IST midnight", time.Date(2024, 3, 9, 18, 29, 59, 0, time.UTC), -1},
+		{"before UTC midnight", time.Date(2024, 3, 9, 23, 0, 0, 0, time.UTC), 0},
+		{"last minute of the day", time.Date(2024, 3, 10, 18, 29, 0, 0, time.UTC), 0},
+		{"tomorrow", time.Date(2024, 3, 10, 18, 30, 0, 0, time.UTC), 1},
+		{"week ago", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), -7},
+		{"other zone", time.Date(2024, 3, 9, 20, 0, 0, 0, time.FixedZone("EST", -5*60*60)), 0},
+	}
+	for _, tt := range tests {
+		if got := epgDayOffset(tt.t, now); got != tt.want {
+			t.Errorf("epgDayOffset() %s = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// Shortly after IST midnight the previous IST day is yesterday, even if it is still the same day in UTC
+	now = time.Date(2024, 3, 9, 18, 45, 0, 0, time.UTC)
+	if got := epgDayOffset(now.Add(-20*time.Minute), now); got != -1 {
+		t.Errorf("epgDayOffset() before midnight = %d, want -1", got)
+	}
+}
+
+func TestProgrammeWindow(t *testing.T) {
+	store.KVS = store.NewMemoryStore()
+	session.Set(utils.DEFAULT_ACCOUNT, nil)
+	now := time.Now().In(ist)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, ist)
+
+	var offsets []int
+	fetchChannelEPG = func(client *fasthttp.Client, channelID, offset int) (*epg.EPGResponse, error) {
+		if channelID != 143 {
+			t.Errorf("EPG requested for channel %d, want 143", channelID)
+		}
+		offsets = append(offsets, offset)
+		day := midnight.AddDate(0, 0, offset)
+		// The programmes of a day run from midnight to midnight in IST, one every half an hour
+		var response epg.EPGResponse
+		for start := day; start.Before(day.AddDate(0, 0, 1)); start = start.Add(30 * time.Minute) {
+			response.EPG = append(response.EPG, epg.EPGObject{StartEpoch: start.UnixMilli(), EndEpoch: start.Add(30 * time.Minute).UnixMilli()})
+		}
+		return &response, nil
+	}
+	defer func() { fetchChannelEPG = epg.FetchChannelEPG }()
+
+	tests := []struct {
+		name   string
+		start  time.Time
+		offset int
+	}{
+		{"first programme of today", midnight, 0},
+		{"last programme of yesterday", midnight.Add(-30 * time.Minute), -1},
+		{"week ago", midnight.AddDate(0, 0, -7).Add(12 * time.Hour), -7},
+	}
+	for _, tt := range tests {
+		offsets = nil
+		start, end, err := programmeWindow("143", strconv.FormatInt(tt.start.Unix(), 10))
+		if err != nil || !start.Equal(tt.start) || !end.Equal(tt.start.Add(30*time.Minute)) {
+			t.Errorf("programmeWindow() %s = %v, %v, %v", tt.name, start, end, err)
+		}
+		if len(offsets) != 1 || offsets[0] != tt.offset {
+			t.Errorf("programmeWindow() %s requested days %v, want %d", tt.name, offsets, tt.offset)
+		}
+	}
+
+	// Start times must match a programme
+	if _, _, err := programmeWindow("143", strconv.FormatInt(midnight.Add(10*time.Minute).Unix(), 10)); !errors.Is(err, epg.ErrProgrammeNotFound) {
+		t.Errorf("programmeWindow() between programmes = %v, want %v", err, epg.ErrProgrammeNotFound)
+	}
+	for _, input := range []struct{ channelID, start string }{{"143", "yesterday"}, {"news", "1700000000"}} {
+		if _, _, err := programmeWindow(input.channelID, input.start); err == nil {
+			t.Errorf("programmeWindow(%q, %q) succeeded", input.channelID, input.start)
+		}
+	}
+}
+
+func TestCatchupWindow(t *testing.T) {
+	app := fiber.New()
+	app.Get("/catchup/:id", CatchupHandler)
+	now := time.Now()
+	tests := []struct {
+		name       string
+		start, end time.Time
+		message    string
+	}{
+		{"future", now.Add(time.Minute), now.Add(time.Hour), "Programme has not aired yet"},
+		{"starting now", now.Add(time.Second), now.Add(time.Hour), "Programme has not aired yet"},
+		{"older than catch-up window", now.AddDate(0, 0, -CATCHUP_DAYS).Add(-time.Minute), now.AddDate(0, 0, -CATCHUP_DAYS).Add(time.Hour), "older than the catch-up window"},
+		{"month ago", now.AddDate(0, -1, 0), now.AddDate(0, -1, 0).Add(time.Hour), "older than the catch-up window"},
+	}
+	for _, tt := range tests {
+		target := "/catchup/143.m3u8?start=" + strconv.FormatInt(tt.start.Unix(), 10) + "&end=" + strconv.FormatInt(tt.end.Unix(), 10)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), tt.message) {
+			t.Errorf("catch-up of %s programme = %d %s, want %d %q", tt.name, resp.StatusCode, body, http.StatusBadRequest, tt.message)
+		}
+	}
+}
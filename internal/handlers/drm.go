@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Varun03-max/JIO/pkg/secureurl"
+	"github.com/Varun03-max/JIO/pkg/session"
 	"github.com/Varun03-max/JIO/pkg/utils"
 	"github.com/valyala/fasthttp"
 
@@ -19,7 +20,7 @@ import (
 // getDrmMpd returns required properties for rendering DRM MPD
 func getDrmMpd(channelID, quality string) (*DrmMpdOutput, error) {
 	// Get live stream URL from JioTV API
-	liveResult, err := TV.Live(channelID)
+	liveResult, err := session.Live(channelID)
 	if err != nil {
 		return nil, err
 	}
@@ -81,10 +82,7 @@ func LiveMpdHandler(c *fiber.Ctx) error {
 
 	drmMpdOutput, err := getDrmMpd(channelID, quality)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": err,
-		})
+		return upstreamErrorHandler(c, err)
 	}
 
 	return c.Render("views/flow_player_drm", fiber.Map{
@@ -113,10 +111,7 @@ func DRMKeyHandler(c *fiber.Ctx) error {
 
 	decoded_channel, err := secureurl.DecryptURL(channel)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"message": err,
-		})
+		return urlTokenErrorHandler(c, err)
 	}
 
 	// Make a HEAD request to the decoded_channel to get the cookies
@@ -143,25 +138,23 @@ func DRMKeyHandler(c *fiber.Ctx) error {
 
 	decoded_url, err := secureurl.DecryptURL(auth)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"message": err,
-		})
+		return urlTokenErrorHandler(c, err)
 	}
 
+	tv := session.ForChannel(channel_id)
 	// Add headers to the request
-	c.Request().Header.Set("accesstoken", TV.AccessToken)
+	c.Request().Header.Set("accesstoken", tv.AccessToken)
 	c.Request().Header.Set("Connection", "keep-alive")
 	c.Request().Header.Set("os", "android")
 	c.Request().Header.Set("appName", "RJIL_JioTV")
-	c.Request().Header.Set("subscriberId", TV.Crm)
+	c.Request().Header.Set("subscriberId", tv.Crm)
 	c.Request().Header.Set("User-Agent", PLAYER_USER_AGENT)
-	c.Request().Header.Set("ssotoken", TV.SsoToken)
+	c.Request().Header.Set("ssotoken", tv.SsoToken)
 	c.Request().Header.Set("x-platform", "android")
 	c.Request().Header.Set("srno", generateDateTime())
-	c.Request().Header.Set("crmid", TV.Crm)
+	c.Request().Header.Set("crmid", tv.Crm)
 	c.Request().Header.Set("channelid", channel_id)
-	c.Request().Header.Set("uniqueId", TV.UniqueID)
+	c.Request().Header.Set("uniqueId", tv.UniqueID)
 	c.Request().Header.Set("versionCode", "330")
 	c.Request().Header.Set("usergroup", "tvYR7NSNn7rymo3F")
 	c.Request().Header.Set("devicetype", "phone")
@@ -174,7 +167,7 @@ func DRMKeyHandler(c *fiber.Ctx) error {
 	c.Request().Header.Del("Accept")
 	c.Request().Header.Del("Origin")
 
-	if err := proxy.Do(c, decoded_url, TV.Client); err != nil {
+	if err := proxy.Do(c, decoded_url, tv.Client); err != nil {
 		return err
 	}
 
@@ -192,8 +185,7 @@ func MpdHandler(c *fiber.Ctx) error {
 
 	decryptedUrl, err := secureurl.DecryptURL(proxyUrl)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return err
+		return urlTokenErrorHandler(c, err)
 	}
 	parsedUrl, err := url.Parse(decryptedUrl)
 	if err != nil {
@@ -217,7 +209,7 @@ func MpdHandler(c *fiber.Ctx) error {
 	c.Request().Header.Set("User-Agent", PLAYER_USER_AGENT)
 	// remove Accept-Encoding header
 	c.Request().Header.Del("Accept-Encoding")
-	if err := proxy.Do(c, requestUrl, TV.Client); err != nil {
+	if err := proxy.Do(c, requestUrl, session.TV().Client); err != nil {
 		return err
 	}
 	c.Response().Header.Del(fiber.HeaderServer)
@@ -268,13 +260,11 @@ func DashHandler(c *fiber.Ctx) error {
 	// decode the URL
 	proxyHost, err := secureurl.DecryptURL(proxyHost)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return err
+		return urlTokenErrorHandler(c, err)
 	}
 	proxyPath, err = secureurl.DecryptURL(proxyPath)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return err
+		return urlTokenErrorHandler(c, err)
 	}
 
 	// remove render.dash from c.Request().URI().RequestURI()
@@ -284,7 +274,7 @@ func DashHandler(c *fiber.Ctx) error {
 
 	c.Request().Header.Set("User-Agent", PLAYER_USER_AGENT)
 
-	if err := proxy.Do(c, proxyUrl, TV.Client); err != nil {
+	if err := proxy.Do(c, proxyUrl, session.TV().Client); err != nil {
 		return err
 	}
 	c.Response().Header.Del(fiber.HeaderServer)
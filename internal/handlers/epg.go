@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/session"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/proxy"
@@ -35,7 +36,7 @@ func WebEPGHandler(c *fiber.Ctx) error {
 	}
 
 	url := fmt.Sprintf(epg.EPG_URL, offset, channelIntID)
-	if err := proxy.Do(c, url, TV.Client); err != nil {
+	if err := proxy.Do(c, url, session.TV().Client); err != nil {
 		return err
 	}
 
@@ -47,7 +48,7 @@ func WebEPGHandler(c *fiber.Ctx) error {
 func PosterHandler(c *fiber.Ctx) error {
 	// catch all params
 	url := EPG_POSTER_URL + c.Params("date") + "/" + c.Params("file")
-	if err := proxy.Do(c, url, TV.Client); err != nil {
+	if err := proxy.Do(c, url, session.TV().Client); err != nil {
 		return err
 	}
 	c.Response().Header.Del(fiber.HeaderServer)
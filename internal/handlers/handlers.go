@@ -1,16 +1,18 @@
 package handlers
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/catalogue"
 	"github.com/Varun03-max/JIO/pkg/secureurl"
+	"github.com/Varun03-max/JIO/pkg/session"
 	"github.com/Varun03-max/JIO/pkg/television"
 	"github.com/Varun03-max/JIO/pkg/utils"
 
@@ -19,7 +21,6 @@ import (
 )
 
 var (
-	TV               *television.Television
 	DisableTSHandler bool
 	isLogoutDisabled bool
 	Title            string
@@ -28,13 +29,12 @@ var (
 )
 
 const (
-	REFRESH_TOKEN_URL     = "https://auth.media.jio.com/tokenservice/apis/v1/refreshtoken?langId=6"
-	REFRESH_SSO_TOKEN_URL = "https://tv.media.jio.com/apis/v2.0/loginotp/refresh?langId=6"
-	PLAYER_USER_AGENT     = "plaYtv/7.0.5 (Linux;Android 8.1.0) ExoPlayerLib/2.11.7"
-	REQUEST_USER_AGENT    = "okhttp/4.2.2"
+	PLAYER_USER_AGENT  = television.PLAYER_USER_AGENT
+	REQUEST_USER_AGENT = "okhttp/4.2.2"
 )
 
 // Init initializes the necessary operations required for the handlers to work.
+// It runs once at startup, logins and logouts only replace the session of their account.
 func Init() {
 	if config.Cfg.Title != "" {
 		Title = config.Cfg.Title
@@ -50,27 +50,23 @@ func Init() {
 	if !EnableDRM {
 		fmt.Println("If you're not using IPTV Client. We strongly recommend enabling DRM for accessing channels without any issues! Either enable by setting environment variable JIOTV_DRM=true or by setting DRM: true in config. For more info Read https://telegram.me/jiotv_go/128")
 	}
+	initSegmentCache()
 	// Generate a new device ID if not present
 	utils.GetDeviceID()
 	// Get credentials from file
 	credentials, err := utils.GetJIOTVCredentials()
-	// Initialize TV object with nil credentials
-	TV = television.New(nil)
 	if err != nil {
 		utils.Log.Println("Login error!", err)
-	} else {
-		// If AccessToken is present, check for its validity and schedule a refresh if required
-		if credentials.AccessToken != "" {
-			// Check validity of credentials
-			go RefreshTokenIfExpired(credentials)
-		}
-		// If SsoToken is present, check for its validity and schedule a refresh if required
-		if credentials.SSOToken != "" {
-			go RefreshSSOTokenIfExpired(credentials)
-		}
-		// Initialize TV object with credentials
-		TV = television.New(credentials)
-	}
+		credentials = nil
+	}
+	initSession()
+	// Notify the token refresher and channel list of the stored login
+	session.Set(utils.DEFAULT_ACCOUNT, credentials)
+	initAccounts()
+	initRecorder()
+	initHDHomeRun()
+	initXtream()
+	initCatalogue()
 }
 
 // ErrorMessageHandler handles error messages
@@ -84,10 +80,51 @@ func ErrorMessageHandler(c *fiber.Ctx, err error) error {
 	return nil
 }
 
+// urlTokenErrorHandler responds to encrypted URLs that could not be decrypted.
+// Tampered and expired URLs are answered with 403 status code.
+func urlTokenErrorHandler(c *fiber.Ctx, err error) error {
+	utils.Log.Println(err)
+	status := fiber.StatusBadRequest
+	if errors.Is(err, secureurl.ErrInvalidToken) || errors.Is(err, secureurl.ErrTokenExpired) {
+		status = fiber.StatusForbidden
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"message": err.Error(),
+	})
+}
+
+// upstreamErrorHandler responds to failed requests to JioTV.
+// Typed television errors are mapped to their status code with a stable error code.
+func upstreamErrorHandler(c *fiber.Ctx, err error) error {
+	utils.Log.Println(err)
+	status, code := fiber.StatusInternalServerError, "internal_error"
+	switch {
+	case errors.Is(err, television.ErrUnauthorized):
+		status, code = fiber.StatusUnauthorized, "unauthorized"
+	case errors.Is(err, television.ErrNotSubscribed):
+		status, code = fiber.StatusForbidden, "not_subscribed"
+	case errors.Is(err, television.ErrChannelNotFound):
+		status, code = fiber.StatusNotFound, "channel_not_found"
+	case errors.Is(err, television.ErrRateLimited):
+		status, code = fiber.StatusTooManyRequests, "rate_limited"
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(television.RETRY_MAX_DELAY.Seconds())))
+	case errors.Is(err, television.ErrUpstreamUnavailable):
+		status, code = fiber.StatusServiceUnavailable, "upstream_unavailable"
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(television.RETRY_MAX_DELAY.Seconds())))
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"message": err.Error(),
+		"code":    code,
+	})
+}
+
 // IndexHandler handles the index page for `/` route
 func IndexHandler(c *fiber.Ctx) error {
 	// Get all channels
-	channels := television.Channels()
+	channels, err := catalogue.Get()
+	if err != nil {
+		return upstreamErrorHandler(c, err)
+	}
 
 	// Get language and category from query params
 	language := c.Query("language")
@@ -118,12 +155,12 @@ func IndexHandler(c *fiber.Ctx) error {
 		if err != nil {
 			return ErrorMessageHandler(c, err)
 		}
-		channels_list := television.FilterChannels(channels.Result, language_int, category_int)
+		channels_list := television.FilterChannels(channels.Channels.Result, language_int, category_int)
 		indexContext["Channels"] = channels_list
 		return c.Render("views/index", indexContext)
 	}
 	// If language and category are not provided, return all channels
-	indexContext["Channels"] = channels.Result
+	indexContext["Channels"] = channels.Channels.Result
 	return c.Render("views/index", indexContext)
 }
 
@@ -144,12 +181,12 @@ func LiveHandler(c *fiber.Ctx) error {
 	id := c.Params("id")
 	// remove suffix .m3u8 if exists
 	id = strings.Replace(id, ".m3u8", "", 1)
-	liveResult, err := TV.Live(id)
+	if directPlaylist(c) {
+		return LivePlaylistHandler(c, id, "auto")
+	}
+	liveResult, err := session.Live(id)
 	if err != nil {
-		utils.Log.Println(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": err,
-		})
+		return upstreamErrorHandler(c, err)
 	}
 
 	// Check if liveResult.Bitrates.Auto is empty
@@ -170,7 +207,7 @@ func LiveHandler(c *fiber.Ctx) error {
 			"message": err,
 		})
 	}
-	return c.Redirect("/render.m3u8?auth="+coded_url+"&channel_key_id="+id, fiber.StatusFound)
+	return c.Redirect("/render.m3u8?auth="+coded_url+"&channel_key_id="+id+variantQuery(c), fiber.StatusFound)
 }
 
 // LiveQualityHandler handles the live channel stream route `/live/:quality/:id.m3u8`.
@@ -179,42 +216,60 @@ func LiveQualityHandler(c *fiber.Ctx) error {
 	id := c.Params("id")
 	// remove suffix .m3u8 if exists
 	id = strings.Replace(id, ".m3u8", "", 1)
-	liveResult, err := TV.Live(id)
+	if directPlaylist(c) {
+		return LivePlaylistHandler(c, id, quality)
+	}
+	liveResult, err := session.Live(id)
 	if err != nil {
-		utils.Log.Println(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": err,
-		})
+		return upstreamErrorHandler(c, err)
 	}
-	Bitrates := liveResult.Bitrates
 	// if id[:2] == "sl" {
 	// 	return sonyLivRedirect(c, liveResult)
 	// }
-	// Channels with following IDs output audio only m3u8 when quality level is enforced
-	if id == "1349" || id == "1322" {
-		quality = "auto"
+	liveURL := qualityURL(id, liveResult.Bitrates, quality)
+	// quote url as it will be passed as a query parameter
+	coded_url, err := secureurl.EncryptURL(liveURL)
+	if err != nil {
+		utils.Log.Println(err)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": err,
+		})
 	}
-	var liveURL string
-	// select quality level based on query parameter
+	return c.Redirect("/render.m3u8?auth="+coded_url+"&channel_key_id="+id+variantQuery(c), fiber.StatusFound)
+}
+
+// qualityLevel returns the name of a quality level given by name or alias, auto for unknown levels
+func qualityLevel(quality string) string {
 	switch quality {
 	case "high", "h":
-		liveURL = Bitrates.High
+		return "high"
 	case "medium", "med", "m":
-		liveURL = Bitrates.Medium
+		return "medium"
 	case "low", "l":
+		return "low"
+	default:
+		return "auto"
+	}
+}
+
+// qualityURL selects the stream URL of a quality level of a channel
+func qualityURL(channelID string, Bitrates television.Bitrates, quality string) string {
+	var liveURL string
+	switch qualityLevel(quality) {
+	case "high":
+		liveURL = Bitrates.High
+	case "medium":
+		liveURL = Bitrates.Medium
+	case "low":
 		liveURL = Bitrates.Low
 	default:
 		liveURL = Bitrates.Auto
 	}
-	// quote url as it will be passed as a query parameter
-	coded_url, err := secureurl.EncryptURL(liveURL)
-	if err != nil {
-		utils.Log.Println(err)
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"message": err,
-		})
+	// Some channels only have audio in the enforced quality levels
+	if liveURL != Bitrates.Auto && audioOnly(channelID, liveURL) {
+		liveURL = Bitrates.Auto
 	}
-	return c.Redirect("/render.m3u8?auth="+coded_url+"&channel_key_id="+id, fiber.StatusFound)
+	return liveURL
 }
 
 // RenderHandler handles M3U8 file for modification
@@ -235,64 +290,53 @@ func RenderHandler(c *fiber.Ctx) error {
 	// decrypt url
 	decoded_url, err := secureurl.DecryptURL(auth)
 	if err != nil {
-		utils.Log.Println(err)
-		return err
-	}
-	renderResult, statusCode := TV.Render(decoded_url)
-	// baseUrl is the part of the url excluding suffix file.m3u8 and params is the part of the url after the suffix
-	split_url_by_params := strings.Split(decoded_url, "?")
-	baseStringUrl := split_url_by_params[0]
-	// Pattern to match file names ending with .m3u8
-	pattern := `[a-z0-9=\_\-A-Z]*\.m3u8`
-	re := regexp.MustCompile(pattern)
-	// Add baseUrl to all the file names ending with .m3u8
-	baseUrl := []byte(re.ReplaceAllString(baseStringUrl, ""))
-	params := split_url_by_params[1]
-
-	// replacer replaces all the file names ending with .m3u8 and .ts with our own server URLs
-	// More info: https://golang.org/pkg/regexp/#Regexp.ReplaceAllFunc
-	replacer := func(match []byte) []byte {
-		switch {
-		case bytes.HasSuffix(match, []byte(".m3u8")):
-			return television.ReplaceM3U8(baseUrl, match, params, channel_id)
-		case bytes.HasSuffix(match, []byte(".ts")):
-			return television.ReplaceTS(baseUrl, match, params)
-		case bytes.HasSuffix(match, []byte(".aac")):
-			return television.ReplaceAAC(baseUrl, match, params)
-		default:
-			return match
-		}
-	}
-
-	// Pattern to match file names ending with .m3u8 and .ts
-	pattern = `[a-z0-9=\_\-A-Z\/]*\.(m3u8|ts|aac)`
-	re = regexp.MustCompile(pattern)
-	// Execute replacer function on renderResult
-	renderResult = re.ReplaceAllFunc(renderResult, replacer)
-
-	// replacer_key replaces all the URLs ending with .key and .pkey with our own server URLs
-	replacer_key := func(match []byte) []byte {
-		switch {
-		case bytes.HasSuffix(match, []byte(".key")) || bytes.HasSuffix(match, []byte(".pkey")):
-			return television.ReplaceKey(match, params, channel_id)
-		default:
-			return match
+		return urlTokenErrorHandler(c, err)
+	}
+	renderResult, statusCode := fetchPlaylist(channel_id, decoded_url)
+	// Long running viewers outlive the token of the URL, continue on a fresh one
+	if upstreamExpired(decoded_url, statusCode) {
+		if freshURL, err := refreshUpstreamURL(channel_id, decoded_url); err == nil {
+			decoded_url = freshURL
+			renderResult, statusCode = fetchPlaylist(channel_id, decoded_url)
+		} else {
+			utils.Log.Println(err)
 		}
 	}
-
-	// Pattern to match URLs ending with .key and .pkey
-	pattern_key := `http[\S]+\.(pkey|key)`
-	re_key := regexp.MustCompile(pattern_key)
-
-	// Execute replacer_key function on renderResult
-	renderResult = re_key.ReplaceAllFunc(renderResult, replacer_key)
-
 	if statusCode != fiber.StatusOK {
 		utils.Log.Println("Error rendering M3U8 file")
 		utils.Log.Println(string(renderResult))
+		return c.Status(statusCode).Send(renderResult)
+	}
+	return sendRenderedPlaylist(c, renderResult, decoded_url, channel_id)
+}
+
+// sendRenderedPlaylist responds with a playlist fetched from upstreamURL.
+// Variants are filtered by the quality query parameters and all URIs are rewritten to point at this server.
+func sendRenderedPlaylist(c *fiber.Ctx, renderResult []byte, upstreamURL, channel_id string) error {
+	filter, err := variantFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid maxres or maxbw: " + err.Error(),
+		})
+	}
+	if pin := c.QueryBool("pin"); !filter.IsZero() || pin {
+		if renderResult, err = selectVariants(renderResult, filter, pin); err != nil {
+			utils.Log.Println(err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		}
+	}
+	// Replace all JioTV server URLs in the playlist with our own server URLs
+	renderResult, err = television.RewritePlaylist(renderResult, upstreamURL, channel_id)
+	if err != nil {
+		utils.Log.Println(err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"message": err.Error(),
+		})
 	}
 	c.Response().Header.Set("Cache-Control", "public, must-revalidate, max-age=3")
-	return c.Status(statusCode).Send(renderResult)
+	return c.Send(renderResult)
 }
 
 // SLHandler proxies requests to SonyLiv CDN
@@ -309,7 +353,7 @@ func SLHandler(c *fiber.Ctx) error {
 	c.Request().Header.Del("Origin")
 	c.Request().Header.Del("Referer")
 	c.Request().Header.Set("User-Agent", PLAYER_USER_AGENT)
-	if err := proxy.Do(c, url, TV.Client); err != nil {
+	if err := proxy.Do(c, url, session.TV().Client); err != nil {
 		return err
 	}
 
@@ -325,29 +369,34 @@ func RenderKeyHandler(c *fiber.Ctx) error {
 	// decode url
 	decoded_url, err := secureurl.DecryptURL(auth)
 	if err != nil {
-		utils.Log.Println(err)
-		return err
+		return urlTokenErrorHandler(c, err)
 	}
 
 	// extract params from url
-	params := strings.Split(decoded_url, "?")[1]
+	_, params, ok := strings.Cut(decoded_url, "?")
+	if !ok {
+		utils.Log.Println("Key URL without auth params:", decoded_url)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Key URL has no auth params",
+		})
+	}
 
 	// set params as cookies as JioTV uses cookies to authenticate
 	for _, param := range strings.Split(params, "&") {
-		key := strings.Split(param, "=")[0]
-		value := strings.Split(param, "=")[1]
+		key, value, _ := strings.Cut(param, "=")
 		c.Request().Header.SetCookie(key, value)
 	}
 
+	tv := session.ForChannel(channel_id)
 	// Copy headers from the Television headers map to the request
-	for key, value := range TV.Headers {
+	for key, value := range tv.Headers {
 		c.Request().Header.Set(key, value) // Assuming only one value for each header
 	}
-	c.Request().Header.Set("srno", "230203144000")
-	c.Request().Header.Set("ssotoken", TV.SsoToken)
+	c.Request().Header.Set("srno", television.KEY_SRNO)
+	c.Request().Header.Set("ssotoken", tv.SsoToken)
 	c.Request().Header.Set("channelId", channel_id)
 	c.Request().Header.Set("User-Agent", PLAYER_USER_AGENT)
-	if err := proxy.Do(c, decoded_url, TV.Client); err != nil {
+	if err := proxy.Do(c, decoded_url, tv.Client); err != nil {
 		return err
 	}
 	c.Response().Header.Del(fiber.HeaderServer)
@@ -360,13 +409,50 @@ func RenderTSHandler(c *fiber.Ctx) error {
 	// decode url
 	decoded_url, err := secureurl.DecryptURL(auth)
 	if err != nil {
-		utils.Log.Panicln(err)
-		return err
+		return urlTokenErrorHandler(c, err)
+	}
+	channel_id := c.Query("channel_key_id")
+	// Byte range requests are passed through as they are
+	if SegmentCache != nil && len(c.Request().Header.Peek(fiber.HeaderRange)) == 0 {
+		entry, hit, err := fetchSegment(channel_id, decoded_url)
+		if err == nil && upstreamExpired(decoded_url, entry.StatusCode) {
+			if freshURL, refreshErr := refreshUpstreamURL(channel_id, decoded_url); refreshErr == nil {
+				entry, hit, err = fetchSegment(channel_id, freshURL)
+			} else {
+				utils.Log.Println(refreshErr)
+			}
+		}
+		if err != nil {
+			utils.Log.Println(err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		}
+		if hit {
+			c.Set("X-Cache", "HIT")
+		} else {
+			c.Set("X-Cache", "MISS")
+		}
+		if entry.ContentType != "" {
+			c.Set(fiber.HeaderContentType, entry.ContentType)
+		}
+		return c.Status(entry.StatusCode).Send(entry.Body)
 	}
 	c.Request().Header.Set("User-Agent", PLAYER_USER_AGENT)
-	if err := proxy.Do(c, decoded_url, TV.Client); err != nil {
+	tv := session.ForChannel(channel_id)
+	if err := proxy.Do(c, decoded_url, tv.Client); err != nil {
 		return err
 	}
+	if upstreamExpired(decoded_url, c.Response().StatusCode()) {
+		if freshURL, err := refreshUpstreamURL(channel_id, decoded_url); err == nil {
+			c.Response().Reset()
+			if err := proxy.Do(c, freshURL, tv.Client); err != nil {
+				return err
+			}
+		} else {
+			utils.Log.Println(err)
+		}
+	}
 	c.Response().Header.Del(fiber.HeaderServer)
 	return nil
 }
@@ -379,15 +465,21 @@ func ChannelsHandler(c *fiber.Ctx) error {
 	splitCategory := strings.TrimSpace(c.Query("c"))
 	languages := strings.TrimSpace(c.Query("l"))
 	skipGenres := strings.TrimSpace(c.Query("sg"))
-	apiResponse := television.Channels()
+	catchup := c.QueryBool("catchup")
+	snapshot, err := catalogue.Get()
+	if err != nil {
+		return upstreamErrorHandler(c, err)
+	}
+	if catalogueNotModified(c, snapshot.ETag, snapshot.ModifiedAt) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	apiResponse := snapshot.Channels
 	// hostUrl should be request URL like http://localhost:5001
 	hostURL := strings.ToLower(c.Protocol()) + "://" + c.Hostname()
 
 	// Check if the query parameter "type" is set to "m3u"
 	if c.Query("type") == "m3u" {
-		// Create an M3U playlist
-		m3uContent := "#EXTM3U x-tvg-url=\"" + hostURL + "/epg.xml.gz\"\n"
-		logoURL := hostURL + "/jtvimage"
+		var channels []television.Channel
 		for _, channel := range apiResponse.Result {
 
 			if languages != "" && !utils.ContainsString(television.LanguageMap[channel.Language], strings.Split(languages, ",")) {
@@ -397,30 +489,15 @@ func ChannelsHandler(c *fiber.Ctx) error {
 			if skipGenres != "" && utils.ContainsString(television.CategoryMap[channel.Category], strings.Split(skipGenres, ",")) {
 				continue
 			}
-
-			var channelURL string
-			if quality != "" {
-				channelURL = fmt.Sprintf("%s/live/%s/%s.m3u8", hostURL, quality, channel.ID)
-			} else {
-				channelURL = fmt.Sprintf("%s/live/%s.m3u8", hostURL, channel.ID)
-			}
-			channelLogoURL := fmt.Sprintf("%s/%s", logoURL, channel.LogoURL)
-			var groupTitle string
-			if splitCategory == "split" {
-				groupTitle = fmt.Sprintf("%s - %s", television.CategoryMap[channel.Category], television.LanguageMap[channel.Language])
-			} else if splitCategory == "language" {
-				groupTitle = television.LanguageMap[channel.Language]
-			} else {
-				groupTitle = television.CategoryMap[channel.Category]
-			}
-			m3uContent += fmt.Sprintf("#EXTINF:-1 tvg-id=%s tvg-name=%q tvg-logo=%q tvg-language=%q tvg-type=%q group-title=%q, %s\n%s\n",
-				channel.ID, channel.Name, channelLogoURL, television.LanguageMap[channel.Language], television.CategoryMap[channel.Category], groupTitle, channel.Name, channelURL)
+			channels = append(channels, channel)
 		}
-
-		// Set the Content-Disposition header for file download
-		c.Set("Content-Disposition", "attachment; filename=jiotv_playlist.m3u")
-		c.Set("Content-Type", "application/vnd.apple.mpegurl") // Set the video M3U MIME type
-		return c.SendStream(strings.NewReader(m3uContent))
+		return sendM3U(c, channels, m3uOptions{
+			Quality:  quality,
+			Grouping: splitCategory,
+			Catchup:  catchup,
+			EPGPath:  "/epg.xml.gz",
+			Filename: "jiotv_playlist.m3u",
+		})
 	}
 
 	for i, channel := range apiResponse.Result {
@@ -430,6 +507,55 @@ func ChannelsHandler(c *fiber.Ctx) error {
 	return c.JSON(apiResponse)
 }
 
+// sendM3U responds with an M3U playlist of channels
+func sendM3U(c *fiber.Ctx, channels []television.Channel, options m3uOptions) error {
+	variantParams := variantQuery(c)
+	// hostUrl should be request URL like http://localhost:5001
+	hostURL := strings.ToLower(c.Protocol()) + "://" + c.Hostname()
+
+	// Create an M3U playlist
+	m3uContent := "#EXTM3U x-tvg-url=\"" + hostURL + options.EPGPath + "\"\n"
+	for _, channel := range channels {
+		var channelURL string
+		if options.Quality != "" {
+			channelURL = fmt.Sprintf("%s/live/%s/%s.m3u8", hostURL, options.Quality, channel.ID)
+		} else {
+			channelURL = fmt.Sprintf("%s/live/%s.m3u8", hostURL, channel.ID)
+		}
+		if variantParams != "" {
+			channelURL += "?" + variantParams[1:]
+		}
+		channelLogoURL := channel.Logo(hostURL)
+		var groupTitle string
+		if channel.Group != "" {
+			groupTitle = channel.Group
+		} else if options.Grouping == "split" {
+			groupTitle = fmt.Sprintf("%s - %s", television.CategoryMap[channel.Category], television.LanguageMap[channel.Language])
+		} else if options.Grouping == "language" {
+			groupTitle = television.LanguageMap[channel.Language]
+		} else {
+			groupTitle = television.CategoryMap[channel.Category]
+		}
+		// Players fill in {utc} and {utcend} with unix timestamps of the programme
+		var catchupAttributes string
+		if options.Catchup && channel.IsCatchupAvailable {
+			catchupSource := fmt.Sprintf("%s/catchup/%s?start={utc}&end={utcend}", hostURL, channel.ID)
+			catchupAttributes = fmt.Sprintf(" catchup=\"default\" catchup-source=%q catchup-days=\"%d\"", catchupSource, CATCHUP_DAYS)
+		}
+		var numberAttribute string
+		if channel.Number > 0 {
+			numberAttribute = fmt.Sprintf(" tvg-chno=\"%d\"", channel.Number)
+		}
+		m3uContent += fmt.Sprintf("#EXTINF:-1 tvg-id=%s tvg-name=%q tvg-logo=%q tvg-language=%q tvg-type=%q%s group-title=%q%s, %s\n%s\n",
+			channel.ID, channel.Name, channelLogoURL, television.LanguageMap[channel.Language], television.CategoryMap[channel.Category], numberAttribute, groupTitle, catchupAttributes, channel.Name, channelURL)
+	}
+
+	// Set the Content-Disposition header for file download
+	c.Set("Content-Disposition", "attachment; filename="+options.Filename)
+	c.Set("Content-Type", "application/vnd.apple.mpegurl") // Set the video M3U MIME type
+	return c.SendStream(strings.NewReader(m3uContent))
+}
+
 // PlayHandler loads HTML Page with video player iframe embedded with video URL
 // URL is generated from the channel ID
 func PlayHandler(c *fiber.Ctx) error {
@@ -442,12 +568,9 @@ func PlayHandler(c *fiber.Ctx) error {
 		// Inorder to check, we need to make additional request to JioTV API
 		// Quick dirty fix, otherise we need to refactor entire LiveTV Handler approach
 		if utils.ContainsString(id, SONY_LIST) {
-			liveResult, err := TV.Live(id)
+			liveResult, err := session.Live(id)
 			if err != nil {
-				utils.Log.Println(err)
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"message": err,
-				})
+				return upstreamErrorHandler(c, err)
 			}
 			// if drm is available, use DRM player
 			if liveResult.IsDRM {
@@ -479,9 +602,15 @@ func PlayerHandler(c *fiber.Ctx) error {
 	} else {
 		play_url = "/live/" + id + ".m3u8"
 	}
+	// Past programmes are played through the catch-up route
+	start, end := c.Query("start"), c.Query("end")
+	if start != "" && end != "" {
+		play_url = "/catchup/" + id + "?start=" + url.QueryEscape(start) + "&end=" + url.QueryEscape(end)
+	}
 	c.Response().Header.Set("Cache-Control", "public, max-age=3600")
 	return c.Render("views/flow_player", fiber.Map{
 		"play_url": play_url,
+		"vod":      start != "" && end != "",
 	})
 }
 
@@ -493,18 +622,26 @@ func FaviconHandler(c *fiber.Ctx) error {
 // PlaylistHandler is the route for generating M3U playlist only
 // For user convenience, redirect to /channels?type=m3u
 func PlaylistHandler(c *fiber.Ctx) error {
+	if profile := c.Query("profile"); profile != "" {
+		target := "/playlist/" + url.PathEscape(profile) + ".m3u"
+		if variantParams := variantQuery(c); variantParams != "" {
+			target += "?" + variantParams[1:]
+		}
+		return c.Redirect(target, fiber.StatusMovedPermanently)
+	}
 	quality := c.Query("q")
 	splitCategory := c.Query("c")
 	languages := c.Query("l")
 	skipGenres := c.Query("sg")
-	return c.Redirect("/channels?type=m3u&q="+quality+"&c="+splitCategory+"&l="+languages+"&sg="+skipGenres, fiber.StatusMovedPermanently)
+	catchup := c.Query("catchup")
+	return c.Redirect("/channels?type=m3u&q="+quality+"&c="+splitCategory+"&l="+languages+"&sg="+skipGenres+"&catchup="+catchup+variantQuery(c), fiber.StatusMovedPermanently)
 }
 
 // ImageHandler loads image from JioTV server
 func ImageHandler(c *fiber.Ctx) error {
 	url := "https://jiotv.catchup.cdn.jio.com/dare_images/images/" + c.Params("file")
 	c.Request().Header.Set("User-Agent", REQUEST_USER_AGENT)
-	if err := proxy.Do(c, url, TV.Client); err != nil {
+	if err := proxy.Do(c, url, session.TV().Client); err != nil {
 		return err
 	}
 	c.Response().Header.Del(fiber.HeaderServer)
@@ -516,6 +653,14 @@ func EPGHandler(c *fiber.Ctx) error {
 	 epgFilePath := utils.GetPathPrefix() + "epg.xml.gz";
 	// if epg.xml.gz exists, return it
 	if _, err := os.Stat(epgFilePath); err == nil {
+		if overrides := catalogue.Overrides(); len(overrides) > 0 {
+			data, err := overriddenEPG(overrides)
+			if err != nil {
+				return ErrorMessageHandler(c, err)
+			}
+			c.Set(fiber.HeaderContentType, "application/gzip")
+			return c.Send(data)
+		}
 		return c.SendFile(epgFilePath, true)
 	} else {
 		err_message := "EPG not found. Please restart the server after setting the environment variable JIOTV_EPG to true."
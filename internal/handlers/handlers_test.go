@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/secureurl"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log = log.New(io.Discard, "", 0)
+	// Init starts goroutines reading the path prefix, so all tests share one
+	dir, err := os.MkdirTemp("", "handlers")
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.Cfg.PathPrefix = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// TestLoginWhileStreaming logs an account in and out while streams request playlists and segments.
+// Run it with -race, logins must not replace what the streams read.
+func TestLoginWhileStreaming(t *testing.T) {
+	store.KVS = store.NewMemoryStore()
+	config.Cfg.SegmentCacheSize = 1
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.m3u8" {
+			io.WriteString(w, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\nsegment1.ts\n")
+			return
+		}
+		w.Write([]byte("segment"))
+	}))
+	defer upstream.Close()
+
+	Init()
+	segmentCache := SegmentCache
+	if segmentCache == nil {
+		t.Fatal("Init() did not create the segment cache")
+	}
+	credentials := &utils.JIOTV_CREDENTIALS{SSOToken: "sso", UniqueID: "unique", CRM: "crm"}
+	if err := utils.WriteAccountCredentials("second", credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, statusCode := fetchPlaylist("143", upstream.URL+"/index.m3u8"); statusCode != http.StatusOK {
+					t.Errorf("fetchPlaylist() status = %d", statusCode)
+				}
+				if _, _, err := fetchSegment("143", upstream.URL+"/segment1.ts"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			if err := loginAccount("second"); err != nil {
+				t.Error(err)
+			}
+			session.Set("second", nil)
+			// The default account has no stored login, it is logged out again
+			loginAccount(utils.DEFAULT_ACCOUNT)
+			session.Set(utils.DEFAULT_ACCOUNT, nil)
+		}
+	}()
+	wg.Wait()
+
+	if SegmentCache != segmentCache {
+		t.Error("logins replaced the segment cache")
+	}
+	if stats := SegmentCache.Stats(); stats.Hits == 0 {
+		t.Errorf("no request was served from the segment cache: %+v", stats)
+	}
+}
+
+func TestLiveSessions(t *testing.T) {
+	store.KVS = store.NewMemoryStore()
+	initSession()
+	clearLiveSessions()
+
+	// Aliases of a quality level share a session, unknown levels are auto
+	if getLiveSession("143", "h") != getLiveSession("143", "high") {
+		t.Error("h and high have different sessions")
+	}
+	if getLiveSession("143", "best") != getLiveSession("143", "auto") {
+		t.Error("unknown quality level is not auto")
+	}
+	if len(liveSessions) != 2 {
+		t.Errorf("sessions = %v, want 2", liveSessions)
+	}
+
+	// Idle sessions are removed
+	liveSessionsMu.Lock()
+	liveSessions["auto/143"].used = time.Now().Add(-2 * LIVE_SESSION_IDLE_TIMEOUT)
+	lastPrune = time.Time{}
+	liveSessionsMu.Unlock()
+	getLiveSession("144", "auto")
+	if _, ok := liveSessions["auto/143"]; ok {
+		t.Error("idle session was not removed")
+	}
+	if _, ok := liveSessions["high/143"]; !ok {
+		t.Error("session in use was removed")
+	}
+
+	// Logins and logouts drop the sessions of the previous login
+	session.Set(utils.DEFAULT_ACCOUNT, nil)
+	if len(liveSessions) != 0 {
+		t.Errorf("sessions after logout = %v", liveSessions)
+	}
+}
+
+func TestRenderKeyWithoutParams(t *testing.T) {
+	store.KVS = store.NewMemoryStore()
+	secureurl.Init()
+	auth, err := secureurl.EncryptURL("https://tv.media.jio.com/streams_live/Colors_HD/key.pkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Get("/render.key", RenderKeyHandler)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/render.key?channel_key_id=143&auth="+url.QueryEscape(auth), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status of a key URL without auth params = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/catalogue"
+	"github.com/Varun03-max/JIO/pkg/hdhomerun"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var hdhomerunOnce sync.Once
+
+// initHDHomeRun sets up the emulated HDHomeRun tuner.
+// The device details are set once, `/discover.json` and SSDP discovery read them without locking.
+func initHDHomeRun() {
+	hdhomerunOnce.Do(func() {
+		hdhomerun.Init(utils.GetDeviceID(), Title, config.Cfg.HDHomeRunTuners)
+	})
+}
+
+// baseURL returns the URL media servers reach this server at
+func baseURL(c *fiber.Ctx) string {
+	return strings.ToLower(c.Protocol()) + "://" + c.Hostname()
+}
+
+// DiscoverHandler responds with the HDHomeRun device details for `/discover.json`
+func DiscoverHandler(c *fiber.Ctx) error {
+	return c.JSON(hdhomerun.NewDiscover(baseURL(c)))
+}
+
+// LineupStatusHandler responds with the HDHomeRun channel scan status for `/lineup_status.json`
+func LineupStatusHandler(c *fiber.Ctx) error {
+	return c.JSON(hdhomerun.NewLineupStatus())
+}
+
+// LineupHandler responds with the HDHomeRun channel lineup for `/lineup.json`
+func LineupHandler(c *fiber.Ctx) error {
+	hostURL := baseURL(c)
+	channels, err := catalogue.Channels()
+	if err != nil {
+		return upstreamErrorHandler(c, err)
+	}
+	lineup := hdhomerun.NewLineup(channels, func(channel television.Channel) string {
+		return fmt.Sprintf("%s/stream/%s.ts", hostURL, channel.ID)
+	})
+	return c.JSON(lineup)
+}
+
+// LineupPostHandler accepts channel scan requests for `/lineup.post`.
+// The lineup is always up to date, so there is nothing to scan.
+func LineupPostHandler(c *fiber.Ctx) error {
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// DeviceXMLHandler responds with the UPnP device description for `/device.xml`
+func DeviceXMLHandler(c *fiber.Ctx) error {
+	body, err := hdhomerun.DeviceXML(baseURL(c))
+	if err != nil {
+		return ErrorMessageHandler(c, err)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	return c.Send(body)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/recorder"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecordingsPageHandler renders the recording library
+func RecordingsPageHandler(c *fiber.Ctx) error {
+	recordings := recorder.List()
+	views := make([]RecordingView, 0, len(recordings))
+	// Newest recordings first
+	for i := len(recordings) - 1; i >= 0; i-- {
+		views = append(views, newRecordingView(recordings[i]))
+	}
+	return c.Render("views/recordings", fiber.Map{
+		"Title":      Title,
+		"Recordings": views,
+	})
+}
+
+// RecordingPlayerHandler loads the web player for a recording
+func RecordingPlayerHandler(c *fiber.Ctx) error {
+	rec, err := recorder.Get(c.Params("id"))
+	if err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	return c.Render("views/flow_player", fiber.Map{
+		"play_url": "/vod/" + rec.ID + "/index.m3u8",
+		"vod":      rec.Status != recorder.StatusRecording,
+	})
+}
+
+// VODPlaylistHandler responds with a playlist over the segments of a recording
+func VODPlaylistHandler(c *fiber.Ctx) error {
+	rec, err := recorder.Get(c.Params("id"))
+	if err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	playlist, err := recorder.VODPlaylist(rec, "video.ts")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Nothing was recorded yet",
+			})
+		}
+		return ErrorMessageHandler(c, err)
+	}
+	c.Set(fiber.HeaderContentType, "application/vnd.apple.mpegurl")
+	if rec.Status == recorder.StatusRecording {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+	}
+	return c.Send(playlist)
+}
+
+// VODFileHandler serves the recorded file, supporting range requests
+func VODFileHandler(c *fiber.Ctx) error {
+	rec, err := recorder.Get(c.Params("id"))
+	if err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	if c.Query("download") != "" {
+		c.Attachment(rec.Title + ".ts")
+	}
+	c.Set(fiber.HeaderContentType, "video/mp2t")
+	return c.SendFile(recorder.FilePath(rec))
+}
+
+// RenameRecordingHandler changes the title of a recording
+func RenameRecordingHandler(c *fiber.Ctx) error {
+	var body RenameRecordingRequestBodyData
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid JSON",
+		})
+	}
+	rec, err := recorder.Rename(c.Params("id"), body.Title)
+	if err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	return c.JSON(rec)
+}
+
+// newRecordingView prepares a recording for the library page
+func newRecordingView(rec recorder.Recording) RecordingView {
+	view := RecordingView{
+		Recording: rec,
+		Playable:  rec.Size > 0,
+		StartText: rec.Start.Local().Format("Mon, 02 Jan 2006 15:04"),
+	}
+	// Posters are proxied through PosterHandler
+	if poster, ok := strings.CutPrefix(rec.Poster, EPG_POSTER_URL); ok && poster != "" {
+		view.PosterURL = "/jtvposter/" + poster
+	}
+	duration := time.Duration(rec.Duration) * time.Second
+	if duration == 0 {
+		duration = rec.End.Sub(rec.Start)
+	}
+	view.DurationText = formatDuration(duration)
+	if rec.Size > 0 {
+		view.SizeText = fmt.Sprintf("%.1f MB", float64(rec.Size)/(1<<20))
+	}
+	return view
+}
+
+// formatDuration formats d as hours and minutes
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
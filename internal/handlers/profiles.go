@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/Varun03-max/JIO/pkg/catalogue"
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/profiles"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// profileChannels returns the profile with the given name and its channels.
+// The ETag of the result changes with the channel list and the profile.
+func profileChannels(name string) (*ProfileChannels, error) {
+	profile, err := profiles.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := catalogue.Get()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	h := fnv.New32a()
+	h.Write(data)
+	result := &ProfileChannels{
+		Profile:    *profile,
+		Channels:   profile.Channels(snapshot.Channels.Result),
+		ETag:       fmt.Sprintf("%s-%x", snapshot.ETag, h.Sum32()),
+		ModifiedAt: snapshot.ModifiedAt,
+	}
+	if modTime := profiles.ModTime(); modTime.After(result.ModifiedAt) {
+		result.ModifiedAt = modTime
+	}
+	return result, nil
+}
+
+// ProfilePlaylistHandler responds with the M3U playlist of a profile
+func ProfilePlaylistHandler(c *fiber.Ctx) error {
+	name := c.Params("profile")
+	result, err := profileChannels(name)
+	if err != nil {
+		return profileErrorHandler(c, err)
+	}
+	if catalogueNotModified(c, result.ETag, result.ModifiedAt) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return sendM3U(c, result.Channels, m3uOptions{
+		Quality:  result.Profile.Quality,
+		Grouping: result.Profile.Grouping,
+		Catchup:  result.Profile.Catchup,
+		EPGPath:  "/epg/" + name + ".xml.gz",
+		Filename: name + ".m3u",
+	})
+}
+
+// ProfileEPGHandler responds with the EPG of the channels of a profile
+func ProfileEPGHandler(c *fiber.Ctx) error {
+	name := c.Params("profile")
+	result, err := profileChannels(name)
+	if err != nil {
+		return profileErrorHandler(c, err)
+	}
+	names := make(map[int]string, len(result.Channels))
+	for _, channel := range result.Channels {
+		id, err := strconv.Atoi(channel.ID)
+		if err == nil {
+			names[id] = channel.Name
+		}
+	}
+	data, err := filteredEPG("profile:"+name, result.ETag, func(channel epg.Channel) (epg.Channel, bool) {
+		name, ok := names[channel.ID]
+		if ok {
+			channel.Display = name
+		}
+		return channel, ok
+	})
+	if err != nil {
+		err_message := "EPG not found. Please restart the server after setting the environment variable JIOTV_EPG to true."
+		utils.Log.Println(err)
+		return c.Status(fiber.StatusNotFound).SendString(err_message)
+	}
+	c.Set(fiber.HeaderContentType, "application/gzip")
+	return c.Send(data)
+}
+
+// ProfilesHandler responds with all playlist profiles
+func ProfilesHandler(c *fiber.Ctx) error {
+	result, err := profiles.List()
+	if err != nil {
+		return profileErrorHandler(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"profiles": result,
+	})
+}
+
+// ProfileHandler responds with a playlist profile
+func ProfileHandler(c *fiber.Ctx) error {
+	profile, err := profiles.Get(c.Params("name"))
+	if err != nil {
+		return profileErrorHandler(c, err)
+	}
+	return c.JSON(profile)
+}
+
+// SaveProfileHandler creates or replaces a playlist profile
+func SaveProfileHandler(c *fiber.Ctx) error {
+	var profile profiles.Profile
+	if err := c.BodyParser(&profile); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid JSON",
+		})
+	}
+	profile.Name = c.Params("name")
+	saved, err := profiles.Save(profile)
+	if err != nil {
+		return profileErrorHandler(c, err)
+	}
+	return c.JSON(saved)
+}
+
+// DeleteProfileHandler removes a playlist profile
+func DeleteProfileHandler(c *fiber.Ctx) error {
+	if err := profiles.Delete(c.Params("name")); err != nil {
+		return profileErrorHandler(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// profileErrorHandler maps errors of playlist profiles to status codes
+func profileErrorHandler(c *fiber.Ctx, err error) error {
+	var status int
+	switch {
+	case errors.Is(err, profiles.ErrProfileNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, profiles.ErrInvalidName), errors.Is(err, profiles.ErrInvalidProfile):
+		status = fiber.StatusBadRequest
+	default:
+		// Errors of the channel list and the profiles file
+		return upstreamErrorHandler(c, err)
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"message": err.Error(),
+	})
+}
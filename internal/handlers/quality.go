@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/Varun03-max/JIO/pkg/hls"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// variantFilter parses the maxres and maxbw query parameters of a request
+func variantFilter(c *fiber.Ctx) (hls.VariantFilter, error) {
+	return hls.ParseVariantFilter(c.Query("maxres"), c.Query("maxbw"))
+}
+
+// variantQuery returns the quality query parameters of a request to pass on to RenderHandler
+func variantQuery(c *fiber.Ctx) string {
+	query := url.Values{}
+	for _, key := range []string{"maxres", "maxbw", "pin"} {
+		if value := c.Query(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "&" + query.Encode()
+}
+
+// selectVariants filters the variants of a master playlist.
+// With pin set, only the best variant within the filter is kept, so players cannot switch quality.
+// Media playlists are returned as they are.
+func selectVariants(data []byte, filter hls.VariantFilter, pin bool) ([]byte, error) {
+	playlist, err := hls.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if playlist.Type != hls.Master {
+		return data, nil
+	}
+	selected := playlist.SelectVariants(filter)
+	if pin {
+		if best := hls.HighestBandwidth(selected); best != nil {
+			selected = []hls.Variant{*best}
+		}
+	}
+	keep := make(map[string]bool, len(selected))
+	for _, variant := range selected {
+		keep[variant.URI] = true
+	}
+	return hls.FilterVariants(data, func(variant hls.Variant) bool {
+		if variant.IFrame {
+			return !pin && filter.Allows(variant)
+		}
+		return keep[variant.URI]
+	})
+}
+
+// audioOnly reports whether the playlist at url of a channel carries no video
+func audioOnly(channelID, url string) bool {
+	body, statusCode := fetchPlaylist(channelID, url)
+	if statusCode != fiber.StatusOK {
+		return false
+	}
+	playlist, err := hls.Parse(body)
+	return err == nil && playlist.AudioOnly()
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/recorder"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var recorderOnce sync.Once
+
+// initRecorder loads saved recordings and schedules the pending ones
+func initRecorder() {
+	recorderOnce.Do(func() {
+		if err := recorder.Init(session.WithChannel); err != nil {
+			utils.Log.Println("Failed to initialize recorder:", err)
+		}
+	})
+}
+
+// RecordingsHandler responds with all recordings
+func RecordingsHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"recordings": recorder.List(),
+	})
+}
+
+// RecordingHandler responds with a single recording
+func RecordingHandler(c *fiber.Ctx) error {
+	rec, err := recorder.Get(c.Params("id"))
+	if err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	return c.JSON(rec)
+}
+
+// ScheduleRecordingHandler schedules a recording either by time or by an EPG programme
+func ScheduleRecordingHandler(c *fiber.Ctx) error {
+	var body RecordingRequestBodyData
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid JSON",
+		})
+	}
+	if body.ChannelID == "" {
+		return recordingErrorHandler(c, recorder.ErrMissingChannel)
+	}
+
+	var rec *recorder.Recording
+	if body.ProgrammeStart != "" {
+		programme, err := findProgramme(body.ChannelID, body.ProgrammeStart)
+		if err != nil {
+			return recordingErrorHandler(c, err)
+		}
+		rec, err = recorder.ScheduleProgramme(*programme)
+		if err != nil {
+			return recordingErrorHandler(c, err)
+		}
+	} else {
+		start, err := parseTime(body.Start)
+		if err != nil {
+			return recordingErrorHandler(c, fmt.Errorf("invalid start time: %w", err))
+		}
+		end, err := parseTime(body.End)
+		if err != nil {
+			return recordingErrorHandler(c, fmt.Errorf("invalid end time: %w", err))
+		}
+		req := recorder.Request{
+			ChannelID: body.ChannelID,
+			Title:     body.Title,
+			Start:     start,
+			End:       end,
+		}
+		// Fill in the details of the programme on air when the recording starts
+		if programme, err := epg.FindProgramme(body.ChannelID, start); err == nil {
+			if req.Title == "" {
+				req.Title = programme.Title.Value
+			}
+			req.Description = programme.Desc.Value
+			req.Category = programme.Category.Value
+			req.Poster = programme.Icon.Src
+		}
+		rec, err = recorder.Schedule(req)
+		if err != nil {
+			return recordingErrorHandler(c, err)
+		}
+	}
+	return c.Status(fiber.StatusCreated).JSON(rec)
+}
+
+// StopRecordingHandler cancels a scheduled recording or stops a running one
+func StopRecordingHandler(c *fiber.Ctx) error {
+	rec, err := recorder.Stop(c.Params("id"))
+	if err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	return c.JSON(rec)
+}
+
+// DeleteRecordingHandler deletes a recording and its files
+func DeleteRecordingHandler(c *fiber.Ctx) error {
+	if err := recorder.Delete(c.Params("id")); err != nil {
+		return recordingErrorHandler(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Recording deleted",
+	})
+}
+
+// recordingErrorHandler responds with the status code matching a recorder error
+func recordingErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, recorder.ErrNotFound), errors.Is(err, epg.ErrProgrammeNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, recorder.ErrAlreadyScheduled):
+		status = fiber.StatusConflict
+	case errors.Is(err, recorder.ErrNotInitialized):
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"message": err.Error(),
+	})
+}
+
+// findProgramme returns the programme of a channel starting at start, given in any format parseTime accepts
+func findProgramme(channelID, start string) (*epg.Programme, error) {
+	startTime, err := parseTime(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid programme start time: %w", err)
+	}
+	programmes, err := epg.ChannelProgrammes(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("EPG is not available: %w", err)
+	}
+	for _, programme := range programmes {
+		if programmeStart, err := programme.StartTime(); err == nil && programmeStart.Equal(startTime) {
+			return &programme, nil
+		}
+	}
+	return nil, epg.ErrProgrammeNotFound
+}
+
+// parseTime parses RFC 3339 times, unix timestamps in seconds and EPG times
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("time is required")
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return epg.ParseTime(value)
+}
+
+// RecordingRulesHandler responds with all recording rules
+func RecordingRulesHandler(c *fiber.Ctx) error {
+	rules, err := recorder.Rules()
+	if err != nil {
+		return ErrorMessageHandler(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"rules": rules,
+	})
+}
+
+// AddRecordingRuleHandler saves a new recording rule and schedules the programmes it matches
+func AddRecordingRuleHandler(c *fiber.Ctx) error {
+	rule, err := parseRecordingRule(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid JSON",
+		})
+	}
+	saved, err := recorder.AddRule(*rule)
+	if err != nil {
+		return recordingRuleErrorHandler(c, err)
+	}
+	go evaluateRecordingRules()
+	return c.Status(fiber.StatusCreated).JSON(saved)
+}
+
+// UpdateRecordingRuleHandler replaces a recording rule
+func UpdateRecordingRuleHandler(c *fiber.Ctx) error {
+	rule, err := parseRecordingRule(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid JSON",
+		})
+	}
+	rule.ID = c.Params("id")
+	saved, err := recorder.UpdateRule(*rule)
+	if err != nil {
+		return recordingRuleErrorHandler(c, err)
+	}
+	go evaluateRecordingRules()
+	return c.JSON(saved)
+}
+
+// DeleteRecordingRuleHandler deletes a recording rule, keeping the recordings it scheduled
+func DeleteRecordingRuleHandler(c *fiber.Ctx) error {
+	if err := recorder.DeleteRule(c.Params("id")); err != nil {
+		return recordingRuleErrorHandler(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Recording rule deleted",
+	})
+}
+
+// parseRecordingRule parses a recording rule from the request body
+func parseRecordingRule(c *fiber.Ctx) (*recorder.Rule, error) {
+	var body RecordingRuleRequestBodyData
+	if err := c.BodyParser(&body); err != nil {
+		return nil, err
+	}
+	return &recorder.Rule{
+		ChannelID: body.ChannelID,
+		Title:     body.Title,
+		Keywords:  body.Keywords,
+		Category:  body.Category,
+		Enabled:   body.Enabled == nil || *body.Enabled,
+	}, nil
+}
+
+// recordingRuleErrorHandler responds with the status code matching a rule error
+func recordingRuleErrorHandler(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, recorder.ErrRuleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	case errors.Is(err, recorder.ErrInvalidRule):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	return ErrorMessageHandler(c, err)
+}
+
+// evaluateRecordingRules schedules the programmes matched by the recording rules
+func evaluateRecordingRules() {
+	if _, err := recorder.EvaluateRules(); err != nil {
+		utils.Log.Println("Failed to evaluate recording rules:", err)
+	}
+}
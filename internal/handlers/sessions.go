@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// LIVE_SESSION_TTL is used for upstream URLs without a token expiry
+	LIVE_SESSION_TTL = 10 * time.Minute
+	// LIVE_SESSION_REFRESH_MARGIN is how long before the token expires a new upstream URL is requested
+	LIVE_SESSION_REFRESH_MARGIN = time.Minute
+	// LIVE_SESSION_MIN_REFRESH_INTERVAL collapses the refreshes of concurrent requests that were all rejected
+	LIVE_SESSION_MIN_REFRESH_INTERVAL = 10 * time.Second
+	// LIVE_SESSION_IDLE_TIMEOUT is how long a session is kept after the channel was last requested
+	LIVE_SESSION_IDLE_TIMEOUT = 5 * time.Minute
+)
+
+var errNoStream = errors.New("no stream found for channel")
+
+// liveSession is the upstream URL a channel is currently played from
+type liveSession struct {
+	mu        sync.Mutex
+	url       string
+	expires   time.Time
+	refreshed time.Time
+	// used is guarded by liveSessionsMu
+	used time.Time
+}
+
+var (
+	liveSessions   = make(map[string]*liveSession)
+	liveSessionsMu sync.Mutex
+	// lastPrune is when idle sessions were last removed
+	lastPrune time.Time
+	// live returns the stream URLs of a channel
+	live = session.Live
+)
+
+// directPlaylist reports whether `/live` routes serve the playlist instead of redirecting
+func directPlaylist(c *fiber.Ctx) bool {
+	return c.QueryBool("direct", config.Cfg.DirectPlaylists)
+}
+
+// getLiveSession returns the session of a channel in a quality level.
+// Sessions of channels that were not requested for LIVE_SESSION_IDLE_TIMEOUT are removed.
+func getLiveSession(id, quality string) *liveSession {
+	liveSessionsMu.Lock()
+	defer liveSessionsMu.Unlock()
+	now := time.Now()
+	if now.Sub(lastPrune) > LIVE_SESSION_IDLE_TIMEOUT {
+		for key, session := range liveSessions {
+			if now.Sub(session.used) > LIVE_SESSION_IDLE_TIMEOUT {
+				delete(liveSessions, key)
+			}
+		}
+		lastPrune = now
+	}
+	key := qualityLevel(quality) + "/" + id
+	session, ok := liveSessions[key]
+	if !ok {
+		session = &liveSession{}
+		liveSessions[key] = session
+	}
+	session.used = now
+	return session
+}
+
+// clearLiveSessions forgets the upstream URLs of all channels, they belong to the previous login
+func clearLiveSessions() {
+	liveSessionsMu.Lock()
+	defer liveSessionsMu.Unlock()
+	clear(liveSessions)
+}
+
+// upstreamURL returns the current upstream URL of the session.
+// A new one is requested from JioTV when the token expires soon, or always with refresh set.
+func (s *liveSession) upstreamURL(id, quality string, refresh bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.url != "" && time.Now().Before(s.expires) &&
+		(!refresh || time.Since(s.refreshed) < LIVE_SESSION_MIN_REFRESH_INTERVAL) {
+		return s.url, nil
+	}
+
+	liveResult, err := live(id)
+	if err != nil {
+		return "", err
+	}
+	liveURL := qualityURL(id, liveResult.Bitrates, quality)
+	if liveURL == "" {
+		return "", errNoStream
+	}
+	s.url = liveURL
+	s.refreshed = time.Now()
+	s.expires = s.refreshed.Add(LIVE_SESSION_TTL)
+	if expiry, ok := television.TokenExpiry(liveURL); ok {
+		s.expires = expiry.Add(-LIVE_SESSION_REFRESH_MARGIN)
+	}
+	return s.url, nil
+}
+
+// LivePlaylistHandler responds to `/live` routes with the rewritten playlist itself, without a redirect.
+// The upstream URL of the channel is kept in a session and replaced once its token expires.
+func LivePlaylistHandler(c *fiber.Ctx, id, quality string) error {
+	session := getLiveSession(id, quality)
+	for attempt := 0; attempt < 2; attempt++ {
+		// The upstream URL is requested again if JioTV rejected the cached one
+		upstreamURL, err := session.upstreamURL(id, quality, attempt > 0)
+		if errors.Is(err, errNoStream) {
+			utils.Log.Println(err)
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": err.Error() + ": " + id,
+			})
+		}
+		if err != nil {
+			return upstreamErrorHandler(c, err)
+		}
+		body, statusCode := fetchPlaylist(id, upstreamURL)
+		if statusCode == fiber.StatusOK {
+			return sendRenderedPlaylist(c, body, upstreamURL, id)
+		}
+		if !upstreamExpired(upstreamURL, statusCode) || attempt > 0 {
+			utils.Log.Println("Error rendering M3U8 file")
+			return c.Status(statusCode).Send(body)
+		}
+	}
+	return nil
+}
+
+// upstreamExpired reports whether a request to an upstream URL failed because its token expired,
+// either because JioTV rejected it with statusCode or by the expiry time of the token
+func upstreamExpired(upstreamURL string, statusCode int) bool {
+	switch statusCode {
+	case fiber.StatusOK, fiber.StatusPartialContent:
+		return false
+	case fiber.StatusForbidden, fiber.StatusGone:
+		return true
+	}
+	expiry, ok := television.TokenExpiry(upstreamURL)
+	return ok && time.Now().After(expiry)
+}
+
+// refreshUpstreamURL maps an upstream URL of a channel whose token expired onto a fresh token.
+// The path is kept, only the token parameters are taken from a new live URL of the channel.
+func refreshUpstreamURL(channelID, upstreamURL string) (string, error) {
+	if channelID == "" {
+		return "", errors.New("channel ID not provided")
+	}
+	liveURL, err := getLiveSession(channelID, "auto").upstreamURL(channelID, "auto", true)
+	if err != nil {
+		return "", err
+	}
+	fresh, err := url.Parse(liveURL)
+	if err != nil {
+		return "", err
+	}
+	expired, err := url.Parse(upstreamURL)
+	if err != nil {
+		return "", err
+	}
+	expired.RawQuery = fresh.RawQuery
+	utils.Log.Println("Refreshed expired upstream URL of channel", channelID)
+	return expired.String(), nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/secureurl"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// hdnea returns a JioTV token parameter expiring at exp
+func hdnea(exp time.Time, hmac string) string {
+	return fmt.Sprintf("__hdnea__=st=%d~exp=%d~acl=/*~hmac=%s", exp.Add(-time.Hour).Unix(), exp.Unix(), hmac)
+}
+
+// stubLive answers live URL requests with liveURL and counts them
+func stubLive(t *testing.T, liveURL func() string, err error) *atomic.Int32 {
+	t.Helper()
+	var requests atomic.Int32
+	live = func(channelID string) (*television.LiveURLOutput, error) {
+		requests.Add(1)
+		if err != nil {
+			return nil, err
+		}
+		return &television.LiveURLOutput{Bitrates: television.Bitrates{Auto: liveURL()}}, nil
+	}
+	t.Cleanup(func() { live = session.Live })
+	clearLiveSessions()
+	return &requests
+}
+
+func TestUpstreamExpired(t *testing.T) {
+	base := "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/index.m3u8"
+	past := base + "?" + hdnea(time.Now().Add(-time.Minute), "old")
+	future := base + "?" + hdnea(time.Now().Add(time.Hour), "new")
+	tests := []struct {
+		name       string
+		url        string
+		statusCode int
+		want       bool
+	}{
+		{"ok", future, fiber.StatusOK, false},
+		{"ok after expiry", past, fiber.StatusOK, false},
+		{"partial content", past, fiber.StatusPartialContent, false},
+		{"forbidden", future, fiber.StatusForbidden, true},
+		{"gone", base, fiber.StatusGone, true},
+		{"not found after expiry", past, fiber.StatusNotFound, true},
+		{"unavailable after expiry", past, fiber.StatusBadGateway, true},
+		{"not found", future, fiber.StatusNotFound, false},
+		{"not found without token", base, fiber.StatusNotFound, false},
+		{"invalid expiry", base + "?__hdnea__=exp=soon~hmac=x", fiber.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := upstreamExpired(tt.url, tt.statusCode); got != tt.want {
+			t.Errorf("upstreamExpired() %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshUpstreamURL(t *testing.T) {
+	fresh := hdnea(time.Now().Add(time.Hour), "fresh")
+	requests := stubLive(t, func() string {
+		return "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/index.m3u8?" + fresh
+	}, nil)
+
+	expired := "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/WEBHD/Colors_HD-audio_98834_hin=94000-video=3050400-1234.ts?" + hdnea(time.Now().Add(-time.Minute), "old")
+	got, err := refreshUpstreamURL("143", expired)
+	want := "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/WEBHD/Colors_HD-audio_98834_hin=94000-video=3050400-1234.ts?" + fresh
+	if err != nil || got != want {
+		t.Errorf("refreshUpstreamURL() = %q, %v, want %q", got, err, want)
+	}
+
+	// Concurrent rejections of the same channel share a refresh
+	if again, err := refreshUpstreamURL("143", expired); err != nil || again != want {
+		t.Errorf("refreshUpstreamURL() again = %q, %v", again, err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("live URL requested %d times, want 1", requests.Load())
+	}
+
+	if _, err := refreshUpstreamURL("", expired); err == nil {
+		t.Error("refreshUpstreamURL() without channel succeeded")
+	}
+	failed := errors.New("upstream down")
+	stubLive(t, nil, failed)
+	if _, err := refreshUpstreamURL("143", expired); !errors.Is(err, failed) {
+		t.Errorf("refreshUpstreamURL() = %v, want %v", err, failed)
+	}
+}
+
+// TestRenderExpired requests a playlist whose token JioTV rejects, it is rendered with a fresh token
+func TestRenderExpired(t *testing.T) {
+	store.KVS = store.NewMemoryStore()
+	secureurl.Init()
+	initSession()
+	session.Set(utils.DEFAULT_ACCOUNT, nil)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.RawQuery, "hmac=fresh") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		io.WriteString(w, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\nsegment1.ts\n")
+	}))
+	defer upstream.Close()
+	fresh := hdnea(time.Now().Add(time.Hour), "fresh")
+	requests := stubLive(t, func() string { return upstream.URL + "/bpk-tv/Colors_HD/index.m3u8?" + fresh }, nil)
+
+	app := fiber.New()
+	app.Get("/render.m3u8", RenderHandler)
+	render := func(upstreamURL string) (*http.Response, string) {
+		t.Helper()
+		auth, err := secureurl.EncryptURL(upstreamURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/render.m3u8?channel_key_id=143&auth="+url.QueryEscape(auth), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := render(upstream.URL + "/bpk-tv/Colors_HD/WEBHD/index.m3u8?" + hdnea(time.Now().Add(-time.Minute), "old"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("render of expired playlist = %d %q", resp.StatusCode, body)
+	}
+	// Segments are requested on the path of the expired playlist with the fresh token
+	_, auth, _ := strings.Cut(body, "auth=")
+	auth, _, _ = strings.Cut(auth, "&")
+	segmentURL, err := secureurl.DecryptURL(auth)
+	if want := upstream.URL + "/bpk-tv/Colors_HD/WEBHD/segment1.ts?" + fresh; err != nil || segmentURL != want {
+		t.Errorf("segment URL = %q, %v, want %q", segmentURL, err, want)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("live URL requested %d times, want 1", requests.Load())
+	}
+
+	// The rejection is passed on when no fresh URL can be requested
+	stubLive(t, nil, errors.New("upstream down"))
+	if resp, _ := render(upstream.URL + "/missing.m3u8"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status of rejected playlist = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
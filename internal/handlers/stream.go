@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/hls"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// STREAM_MAX_FAILURES is the number of failed polls in a row after which a stream is ended
+const STREAM_MAX_FAILURES = 5
+
+var (
+	// mediaPlaylistURL returns the URL of the media playlist a channel is streamed from
+	mediaPlaylistURL = (*television.Television).MediaPlaylistURL
+	// streamRetryInterval is how much longer a stream waits after every failed poll in a row
+	streamRetryInterval = hls.DEFAULT_POLL_INTERVAL
+)
+
+// StreamHandler handles the continuous MPEG-TS route `/stream/:id.ts`.
+// It follows the media playlist of the channel and writes every new segment to one endless response,
+// until the client disconnects.
+func StreamHandler(c *fiber.Ctx) error {
+	id := strings.TrimSuffix(c.Params("id"), ".ts")
+	filter, err := variantFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid maxres or maxbw: " + err.Error(),
+		})
+	}
+	var playlistURL string
+	err = session.WithChannel(id, func(tv *television.Television) error {
+		playlistURL, err = mediaPlaylistURL(tv, id, filter)
+		return err
+	})
+	if err != nil {
+		utils.Log.Println(err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "video/mp2t")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := streamSegments(w, id, playlistURL, filter); err != nil {
+			utils.Log.Printf("Stream of channel %s ended: %v", id, err)
+		}
+	})
+	return nil
+}
+
+// streamSegments writes the segments of the media playlist at playlistURL to w.
+// filter selects the variant again when the playlist URL expires.
+// It returns once writing fails, which happens when the client disconnects.
+func streamSegments(w *bufio.Writer, channelID, playlistURL string, filter hls.VariantFilter) error {
+	// Playlists go through the segment cache so that HLS players of the same channel share them
+	follower := hls.NewFollower(playlistURL, func(url string) ([]byte, error) {
+		body, statusCode := fetchPlaylist(channelID, url)
+		if statusCode != fiber.StatusOK {
+			return nil, &television.StatusError{URL: url, StatusCode: statusCode}
+		}
+		return body, nil
+	})
+	keys := make(map[string][]byte)
+	failures := 0
+
+	for {
+		// Streams outlive tokens, so every poll uses the current login
+		tv := session.ForChannel(channelID)
+		segments, err := follower.Poll()
+		var statusErr *television.StatusError
+		if errors.As(err, &statusErr) {
+			// Tokens of the playlist URL expired, follow a fresh one
+			if playlistURL, err = mediaPlaylistURL(tv, channelID, filter); err == nil {
+				follower.SetURL(playlistURL)
+				segments, err = follower.Poll()
+			}
+		}
+
+		// Segments after a discontinuity are written as they are.
+		// Every segment starts with its own PAT and PMT, so players resync on the new timestamps.
+		for _, segment := range segments {
+			var data []byte
+			if data, err = tv.FetchSegment(segment, channelID, keys); err != nil {
+				// Skip to the next poll, a missing segment is a gap players can cope with
+				break
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		wait := follower.Interval()
+		if err != nil {
+			utils.Log.Printf("Stream of channel %s: %v", channelID, err)
+			failures++
+			if failures >= STREAM_MAX_FAILURES {
+				return err
+			}
+			wait = time.Duration(failures) * streamRetryInterval
+		} else {
+			failures = 0
+		}
+		if playlist := follower.Playlist(); playlist != nil && playlist.EndList {
+			return nil
+		}
+		time.Sleep(wait)
+	}
+}
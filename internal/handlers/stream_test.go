@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/hls"
+	"github.com/Varun03-max/JIO/pkg/session"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// encrypt encrypts a segment with AES-128 and PKCS#7 padding like JioTV does
+func encrypt(t *testing.T, plain, key, iv []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	data := append(append([]byte(nil), plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return data
+}
+
+// setupStream plays channels with the Television of the default account, without the segment cache.
+// Expired playlist URLs are replaced by refreshedURL.
+func setupStream(t *testing.T, refreshedURL func() (string, error)) {
+	t.Helper()
+	store.KVS = store.NewMemoryStore()
+	session.Set(utils.DEFAULT_ACCOUNT, nil)
+	segmentCache := SegmentCache
+	SegmentCache = nil
+	mediaPlaylistURL = func(*television.Television, string, hls.VariantFilter) (string, error) {
+		return refreshedURL()
+	}
+	streamRetryInterval = time.Millisecond
+	t.Cleanup(func() {
+		SegmentCache = segmentCache
+		mediaPlaylistURL = (*television.Television).MediaPlaylistURL
+		streamRetryInterval = hls.DEFAULT_POLL_INTERVAL
+	})
+}
+
+func TestStreamSegments(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+	// The second segment has no IV, it is derived from its sequence number
+	sequenceIV := make([]byte, aes.BlockSize)
+	sequenceIV[aes.BlockSize-1] = 11
+	segments := map[string][]byte{
+		"/segment10.ts": []byte("plain segment"),
+		"/segment11.ts": encrypt(t, []byte("encrypted segment"), key, sequenceIV),
+		"/segment12.ts": encrypt(t, []byte("segment with IV, sixteen bytes+"), key, iv),
+	}
+	var polls, keyRequests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.m3u8":
+			// The playlist grows by a segment on the second poll and ends with the third
+			io.WriteString(w, "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:10\n#EXTINF:1,\nsegment10.ts\n"+
+				"#EXT-X-KEY:METHOD=AES-128,URI=\"key?ck=secret\"\n#EXTINF:1,\nsegment11.ts\n")
+			if polls.Add(1) > 1 {
+				fmt.Fprintf(w, "#EXT-X-KEY:METHOD=AES-128,URI=\"key?ck=secret\",IV=0x%x\n#EXTINF:1,\nsegment12.ts\n", iv)
+				io.WriteString(w, "#EXT-X-ENDLIST\n")
+			}
+		case "/key":
+			keyRequests.Add(1)
+			// JioTV authenticates key requests with the query as cookies
+			if cookie, err := r.Cookie("ck"); err != nil || cookie.Value != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write(key)
+		default:
+			if segment, ok := segments[r.URL.Path]; ok {
+				w.Write(segment)
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+	setupStream(t, func() (string, error) { return "", errors.New("playlist did not expire") })
+
+	var out bytes.Buffer
+	if err := streamSegments(bufio.NewWriter(&out), "143", upstream.URL+"/index.m3u8", hls.VariantFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "plain segmentencrypted segmentsegment with IV, sixteen bytes+"; out.String() != want {
+		t.Errorf("stream = %q, want %q", out.String(), want)
+	}
+	if polls.Load() != 2 {
+		t.Errorf("playlist polled %d times, want 2", polls.Load())
+	}
+	// Keys are kept while they do not rotate
+	if keyRequests.Load() != 1 {
+		t.Errorf("key requested %d times, want 1", keyRequests.Load())
+	}
+
+	// Streams end when the client disconnects
+	polls.Store(0)
+	err := streamSegments(bufio.NewWriterSize(failingWriter{}, 16), "143", upstream.URL+"/index.m3u8", hls.VariantFilter{})
+	if !errors.Is(err, errDisconnected) {
+		t.Errorf("streamSegments() to a disconnected client = %v, want %v", err, errDisconnected)
+	}
+}
+
+var errDisconnected = errors.New("client disconnected")
+
+// failingWriter is a client that disconnected
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errDisconnected
+}
+
+func TestStreamFailures(t *testing.T) {
+	var polls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer upstream.Close()
+	var refreshes atomic.Int32
+	setupStream(t, func() (string, error) {
+		// Every other refresh fails as well
+		if refreshes.Add(1)%2 == 0 {
+			return "", television.ErrUpstreamUnavailable
+		}
+		return upstream.URL + "/index.m3u8", nil
+	})
+
+	var out bytes.Buffer
+	start := time.Now()
+	err := streamSegments(bufio.NewWriter(&out), "143", upstream.URL+"/index.m3u8", hls.VariantFilter{})
+	var statusErr *television.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("streamSegments() of a failing upstream = %v, want status %d", err, http.StatusForbidden)
+	}
+	if refreshes.Load() != STREAM_MAX_FAILURES {
+		t.Errorf("playlist URL refreshed %d times, want %d", refreshes.Load(), STREAM_MAX_FAILURES)
+	}
+	// Polls are retried right after a refresh
+	if want := int32(STREAM_MAX_FAILURES + (STREAM_MAX_FAILURES+1)/2); polls.Load() != want {
+		t.Errorf("playlist polled %d times, want %d", polls.Load(), want)
+	}
+	if out.Len() != 0 {
+		t.Errorf("stream of a failing upstream = %q", out.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stream failed after %v", elapsed)
+	}
+}
@@ -1,9 +1,20 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/catalogue"
+	"github.com/Varun03-max/JIO/pkg/profiles"
+	"github.com/Varun03-max/JIO/pkg/recorder"
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
 // LoginRequestBodyData represents Request body for password based login request
 type LoginRequestBodyData struct {
 	Username string `json:"username"` // Simplified
 	Password string `json:"password"`
+	// Account names the login, the default account if empty
+	Account string `json:"account"`
 }
 
 // LoginSendOTPRequestBodyData represents Request body for OTP based login request
@@ -15,14 +26,8 @@ type LoginSendOTPRequestBodyData struct {
 type LoginVerifyOTPRequestBodyData struct {
 	MobileNumber string `json:"mobileNumber"` // ✅ Fix this tag
 	OTP          string `json:"otp"`
-}
-
-type RefreshTokenResponse struct {
-	AccessToken string `json:"authToken"`
-}
-
-type RefreshSSOTokenResponse struct {
-	SSOToken string `json:"ssoToken"`
+	// Account names the login, the default account if empty
+	Account string `json:"account"`
 }
 
 type DrmMpdOutput struct {
@@ -31,3 +36,67 @@ type DrmMpdOutput struct {
 	Tv_url_host string
 	Tv_url_path string
 }
+
+// RecordingRequestBodyData represents Request body for scheduling a recording
+// Either ProgrammeStart or Start and End must be set
+type RecordingRequestBodyData struct {
+	ChannelID      string `json:"channel_id"`
+	Title          string `json:"title"`
+	Start          string `json:"start"`
+	End            string `json:"end"`
+	ProgrammeStart string `json:"programme_start"`
+}
+
+// RecordingRuleRequestBodyData represents Request body for creating or updating a recording rule
+type RecordingRuleRequestBodyData struct {
+	ChannelID string   `json:"channel_id"`
+	Title     string   `json:"title"`
+	Keywords  []string `json:"keywords"`
+	Category  string   `json:"category"`
+	Enabled   *bool    `json:"enabled"` // Defaults to true
+}
+
+// RenameRecordingRequestBodyData represents Request body for renaming a recording
+type RenameRecordingRequestBodyData struct {
+	Title string `json:"title"`
+}
+
+// RecordingView is a recording with the fields shown in the recording library
+type RecordingView struct {
+	recorder.Recording
+	Playable     bool
+	PosterURL    string
+	StartText    string
+	DurationText string
+	SizeText     string
+}
+
+// ChannelChangeView is a channel change with the fields shown on the changes page
+type ChannelChangeView struct {
+	catalogue.Change
+	ImageURL string
+	TimeText string
+}
+
+// ProfileChannels is a playlist profile with its channels
+type ProfileChannels struct {
+	Profile    profiles.Profile
+	Channels   []television.Channel
+	ETag       string
+	ModifiedAt time.Time
+}
+
+// m3uOptions configures the M3U playlist of sendM3U
+type m3uOptions struct {
+	Quality  string
+	Grouping string
+	Catchup  bool
+	EPGPath  string
+	Filename string
+}
+
+// filteredEPGEntry is a filtered EPG kept until its key changes
+type filteredEPGEntry struct {
+	key  string
+	data []byte
+}
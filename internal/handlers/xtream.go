@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/catalogue"
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/xtream"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// initXtream sets up the Xtream Codes API accounts
+func initXtream() {
+	xtream.Init(config.Cfg.XtreamUsers, CATCHUP_DAYS)
+}
+
+// xtreamAuthenticated checks the username and password of an Xtream Codes request.
+// Credentials are read from the query or a form body.
+func xtreamAuthenticated(c *fiber.Ctx) (string, string, bool) {
+	username := c.Query("username", c.FormValue("username"))
+	password := c.Query("password", c.FormValue("password"))
+	return username, password, xtream.Authenticate(username, password)
+}
+
+// XtreamPlayerAPIHandler handles the Xtream Codes API at `/player_api.php`
+func XtreamPlayerAPIHandler(c *fiber.Ctx) error {
+	username, password, ok := xtreamAuthenticated(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(xtream.Account{})
+	}
+
+	switch c.Query("action", c.FormValue("action")) {
+	case "":
+		return c.JSON(xtream.NewAccount(username, password, xtreamServerInfo(c)))
+	case "get_live_categories":
+		channels, err := catalogue.Channels()
+		if err != nil {
+			return upstreamErrorHandler(c, err)
+		}
+		return c.JSON(xtream.Categories(channels))
+	case "get_live_streams":
+		channels, err := catalogue.Channels()
+		if err != nil {
+			return upstreamErrorHandler(c, err)
+		}
+		categoryID := c.Query("category_id", c.FormValue("category_id"))
+		return c.JSON(xtream.LiveStreams(channels, categoryID, baseURL(c)))
+	case "get_short_epg":
+		streamID := c.Query("stream_id", c.FormValue("stream_id"))
+		limit, _ := strconv.Atoi(c.Query("limit", c.FormValue("limit")))
+		// Without a generated EPG there is nothing to list
+		programmes, _ := epg.ChannelProgrammes(streamID)
+		return c.JSON(xtream.NewShortEPG(programmes, time.Now(), limit))
+	case "get_vod_categories", "get_vod_streams", "get_series_categories", "get_series":
+		// Only live channels are available
+		return c.JSON([]any{})
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Unsupported action",
+		})
+	}
+}
+
+// xtreamServerInfo describes this server to Xtream Codes clients
+func xtreamServerInfo(c *fiber.Ctx) xtream.ServerInfo {
+	protocol := strings.ToLower(c.Protocol())
+	host, port, err := net.SplitHostPort(c.Hostname())
+	if err != nil {
+		host = c.Hostname()
+		port = "80"
+		if protocol == "https" {
+			port = "443"
+		}
+	}
+	now := time.Now()
+	info := xtream.ServerInfo{
+		URL:            host,
+		Port:           port,
+		ServerProtocol: protocol,
+		Timezone:       now.Location().String(),
+		TimestampNow:   now.Unix(),
+		TimeNow:        now.Format(xtream.LISTING_TIME_FORMAT),
+	}
+	if protocol == "https" {
+		info.HTTPSPort = port
+	}
+	return info
+}
+
+// XtreamXMLTVHandler serves the EPG at `/xmltv.php` to Xtream Codes clients
+func XtreamXMLTVHandler(c *fiber.Ctx) error {
+	if _, _, ok := xtreamAuthenticated(c); !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid username or password",
+		})
+	}
+	return EPGHandler(c)
+}
+
+// XtreamLiveHandler handles the Xtream Codes stream routes `/live/:username/:password/:id.m3u8` and `.ts`
+func XtreamLiveHandler(c *fiber.Ctx) error {
+	if !xtream.Authenticate(c.Params("username"), c.Params("password")) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid username or password",
+		})
+	}
+	if strings.HasSuffix(c.Params("id"), ".ts") {
+		return StreamHandler(c)
+	}
+	return LiveHandler(c)
+}
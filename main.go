@@ -5,9 +5,130 @@ import (
 	"os"
 
 	"github.com/Varun03-max/JIO/cmd"
+	"github.com/Varun03-max/JIO/pkg/recorder"
+
+	"github.com/urfave/cli/v2"
 )
 
 func main() {
+	app := &cli.App{
+		Name:   "jiotv_go",
+		Usage:  "Stream JioTV channels with any IPTV player",
+		Action: serve,
+		Commands: []*cli.Command{
+			{
+				Name:  "rules",
+				Usage: "Manage series recording rules",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "Path to the configuration file"},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List recording rules",
+						Action: func(c *cli.Context) error {
+							return cmd.ListRules(c.String("config"))
+						},
+					},
+					{
+						Name:  "add",
+						Usage: "Record every programme on a channel whose title contains the given title",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "channel", Usage: "Channel ID", Required: true},
+							&cli.StringFlag{Name: "title", Usage: "Text the programme title must contain", Required: true},
+							&cli.StringSliceFlag{Name: "keyword", Usage: "Text the title or description must contain, can be repeated"},
+							&cli.StringFlag{Name: "category", Usage: "Category the programme must have"},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.AddRule(c.String("config"), recorder.Rule{
+								ChannelID: c.String("channel"),
+								Title:     c.String("title"),
+								Keywords:  c.StringSlice("keyword"),
+								Category:  c.String("category"),
+							})
+						},
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a recording rule",
+						ArgsUsage: "<rule id>",
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return cli.Exit("expected the ID of the rule to delete", 1)
+							}
+							return cmd.DeleteRule(c.String("config"), c.Args().First())
+						},
+					},
+				},
+			},
+			{
+				Name:  "accounts",
+				Usage: "Manage JioTV accounts",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "Path to the configuration file"},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List logged in accounts",
+						Action: func(c *cli.Context) error {
+							return cmd.ListAccounts(c.String("config"))
+						},
+					},
+					{
+						Name:  "login",
+						Usage: "Log in an account with OTP or password",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "name", Usage: "Name of the account, the default account if empty"},
+							&cli.BoolFlag{Name: "password", Usage: "Log in with password instead of OTP"},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.LoginAccount(c.String("config"), c.String("name"), c.Bool("password"))
+						},
+					},
+					{
+						Name:      "logout",
+						Usage:     "Log out an account",
+						ArgsUsage: "<account name>",
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return cli.Exit("expected the name of the account to log out", 1)
+							}
+							return cmd.LogoutAccount(c.String("config"), c.Args().First())
+						},
+					},
+				},
+			},
+			{
+				Name:  "store",
+				Usage: "Manage the store of login tokens",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "Path to the configuration file"},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "rekey",
+						Usage: "Encrypt the store with a new passphrase",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "new-key-file", Usage: "File to read the new passphrase from, asked for on the terminal if empty"},
+							&cli.BoolFlag{Name: "decrypt", Usage: "Store the values in plaintext again"},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.RekeyStore(c.String("config"), c.String("new-key-file"), c.Bool("decrypt"))
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serve starts the server, the default when no command is given
+func serve(c *cli.Context) error {
 	// Read port from environment or default to 8080
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -19,12 +140,13 @@ func main() {
 	serverConfig := cmd.JioTVServerConfig{
 		Host:       "0.0.0.0",
 		Port:       port,
-		ConfigPath: "",     // Always load from ENV, never file
-		TLS:        false,  // Change to true if using HTTPS
+		ConfigPath: "",    // Always load from ENV, never file
+		TLS:        false, // Change to true if using HTTPS
 	}
 
 	// Start the server
 	if err := cmd.JioTVServer(serverConfig); err != nil {
 		log.Fatalf("Failed to start JioTV server: %v", err)
 	}
+	return nil
 }
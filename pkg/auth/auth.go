@@ -0,0 +1,395 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/Varun03-max/JIO/pkg/scheduler"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	REFRESH_TOKEN_URL     = "https://auth.media.jio.com/tokenservice/apis/v1/refreshtoken?langId=6"
+	REFRESH_SSO_TOKEN_URL = "https://tv.media.jio.com/apis/v2.0/loginotp/refresh?langId=6"
+	// Task IDs of the token refreshes of the default account, other accounts append their name
+	ACCESS_TOKEN_TASK_ID = "jiotv_refresh_token"
+	SSO_TOKEN_TASK_ID    = "jiotv_refresh_sso_token"
+	// ACCESS_TOKEN_LIFETIME is how long an access token without expiry time is valid after it was issued
+	ACCESS_TOKEN_LIFETIME = 2 * time.Hour
+	// SSO_TOKEN_LIFETIME is how long an SSO token is valid after it was issued
+	SSO_TOKEN_LIFETIME = 24 * time.Hour
+	// REFRESH_MARGIN is how long before they expire tokens are refreshed
+	REFRESH_MARGIN = 10 * time.Minute
+	// Failed refreshes are retried after a delay that doubles from RETRY_BASE_DELAY up to RETRY_MAX_DELAY
+	RETRY_BASE_DELAY = 30 * time.Second
+	RETRY_MAX_DELAY  = 30 * time.Minute
+)
+
+// Errors
+var (
+	ErrNotLoggedIn       = errors.New("not logged in")
+	ErrReloginRequired   = errors.New("token can no longer be refreshed, please log in again")
+	ErrNoTokenInResponse = errors.New("no token in refresh response")
+)
+
+var (
+	mu       sync.Mutex
+	statuses = map[string]*Status{}
+	// generations counts how often the refreshes of every account were stopped.
+	// Refreshes scheduled before the last Stop of their account are dropped.
+	generations  = map[string]int{}
+	refreshMu    sync.Mutex
+	refreshHooks []func(string, *utils.JIOTV_CREDENTIALS)
+)
+
+// token describes how one kind of token is refreshed
+type token struct {
+	name     string
+	taskID   string
+	lifetime time.Duration
+	// status returns the status of the token in s
+	status func(s *Status) *TokenStatus
+	// current returns the token and the unix time it was issued at
+	current func(credentials *utils.JIOTV_CREDENTIALS) (string, string)
+	// request asks JioTV for a new token
+	request func(credentials *utils.JIOTV_CREDENTIALS) (string, error)
+	// update stores a new token in credentials
+	update func(credentials *utils.JIOTV_CREDENTIALS, value string)
+}
+
+var accessToken = &token{
+	name:     "AccessToken",
+	taskID:   ACCESS_TOKEN_TASK_ID,
+	lifetime: ACCESS_TOKEN_LIFETIME,
+	status:   func(s *Status) *TokenStatus { return &s.AccessToken },
+	current: func(credentials *utils.JIOTV_CREDENTIALS) (string, string) {
+		return credentials.AccessToken, credentials.LastTokenRefreshTime
+	},
+	request: requestAccessToken,
+	update: func(credentials *utils.JIOTV_CREDENTIALS, value string) {
+		credentials.AccessToken = value
+		credentials.LastTokenRefreshTime = strconv.FormatInt(time.Now().Unix(), 10)
+	},
+}
+
+var ssoToken = &token{
+	name:     "SSOToken",
+	taskID:   SSO_TOKEN_TASK_ID,
+	lifetime: SSO_TOKEN_LIFETIME,
+	status:   func(s *Status) *TokenStatus { return &s.SSOToken },
+	current: func(credentials *utils.JIOTV_CREDENTIALS) (string, string) {
+		return credentials.SSOToken, credentials.LastSSOTokenRefreshTime
+	},
+	request: requestSSOToken,
+	update: func(credentials *utils.JIOTV_CREDENTIALS, value string) {
+		credentials.SSOToken = value
+		credentials.LastSSOTokenRefreshTime = strconv.FormatInt(time.Now().Unix(), 10)
+	},
+}
+
+// OnRefresh registers a function that is called with the account and its credentials after a token was refreshed
+func OnRefresh(fn func(string, *utils.JIOTV_CREDENTIALS)) {
+	mu.Lock()
+	defer mu.Unlock()
+	refreshHooks = append(refreshHooks, fn)
+}
+
+// Start schedules the refresh of the tokens of an account before they expire.
+// Expired tokens are refreshed right away. Nil credentials stop the refreshes.
+func Start(account string, credentials *utils.JIOTV_CREDENTIALS) {
+	Stop(account)
+	if credentials == nil {
+		return
+	}
+	mu.Lock()
+	statusOf(account).LoggedIn = true
+	generation := generations[account]
+	mu.Unlock()
+	for _, t := range []*token{accessToken, ssoToken} {
+		schedule(t, account, credentials, generation)
+	}
+}
+
+// Stop cancels the scheduled refreshes of an account and forgets its status.
+// It waits for a running refresh, so that credentials deleted afterwards are not written again.
+func Stop(account string) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	if scheduler.Scheduler != nil {
+		scheduler.Remove(accessToken.taskIDOf(account))
+		scheduler.Remove(ssoToken.taskIDOf(account))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	generations[account]++
+	delete(statuses, account)
+}
+
+// GetStatus returns the expiry times of the tokens of an account and the results of their refreshes
+func GetStatus(account string) Status {
+	mu.Lock()
+	defer mu.Unlock()
+	if s, ok := statuses[account]; ok {
+		return *s
+	}
+	return Status{Account: account}
+}
+
+// Statuses returns the status of every account with scheduled refreshes, the default account first
+func Statuses() []Status {
+	mu.Lock()
+	defer mu.Unlock()
+	result := make([]Status, 0, len(statuses))
+	for _, s := range statuses {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Account == utils.DEFAULT_ACCOUNT || result[j].Account == utils.DEFAULT_ACCOUNT {
+			return result[i].Account == utils.DEFAULT_ACCOUNT
+		}
+		return result[i].Account < result[j].Account
+	})
+	return result
+}
+
+// RefreshAccessToken refreshes the access token of an account now
+func RefreshAccessToken(account string) error {
+	return refresh(accessToken, account, generationOf(account))
+}
+
+// RefreshSSOToken refreshes the SSO token of an account now
+func RefreshSSOToken(account string) error {
+	return refresh(ssoToken, account, generationOf(account))
+}
+
+// generationOf returns the current generation of the refreshes of an account
+func generationOf(account string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return generations[account]
+}
+
+// taskIDOf returns the ID of the refresh task of the token of an account
+func (t *token) taskIDOf(account string) string {
+	if account == utils.DEFAULT_ACCOUNT {
+		return t.taskID
+	}
+	return t.taskID + "_" + account
+}
+
+// statusOf returns the status of an account, creating it if needed. mu must be held.
+func statusOf(account string) *Status {
+	s, ok := statuses[account]
+	if !ok {
+		s = &Status{Account: account}
+		statuses[account] = s
+	}
+	return s
+}
+
+// schedule sets the expiry time of a token and schedules its refresh.
+// Nothing is scheduled if the refreshes of the account were stopped after generation.
+func schedule(t *token, account string, credentials *utils.JIOTV_CREDENTIALS, generation int) {
+	value, issuedAt := t.current(credentials)
+	if value == "" {
+		// Password logins have no access token
+		return
+	}
+	expiresAt, source := expiry(value, issuedAt, t.lifetime)
+	at := expiresAt.Add(-REFRESH_MARGIN)
+	mu.Lock()
+	if generations[account] != generation {
+		mu.Unlock()
+		return
+	}
+	tokenStatus := t.status(statusOf(account))
+	tokenStatus.ExpiresAt = expiresAt
+	tokenStatus.ExpirySource = source
+	tokenStatus.NextRefresh = at
+	mu.Unlock()
+
+	if scheduler.Scheduler == nil {
+		return
+	}
+	utils.Log.Println("Refreshing", t.name, "of account", account, "after", time.Until(at).Truncate(time.Second))
+	scheduler.AddOnce(t.taskIDOf(account), at, func() error {
+		return refresh(t, account, generation)
+	})
+}
+
+// refresh requests a new token and stores it.
+// Failed refreshes are retried with backoff, unless the token can no longer be refreshed.
+// Refreshes of an account that was stopped after generation are dropped, e.g. after a logout.
+func refresh(t *token, account string, generation int) error {
+	refreshMu.Lock()
+	if generationOf(account) != generation {
+		refreshMu.Unlock()
+		utils.Log.Println("Dropped refresh of", t.name, "of account", account, "after it was stopped")
+		return nil
+	}
+	credentials, err := utils.GetAccountCredentials(account)
+	if err == nil && credentials == nil {
+		err = ErrNotLoggedIn
+	}
+	var value string
+	if err == nil {
+		utils.Log.Println("Refreshing", t.name, "of account", account+"...")
+		value, err = t.request(credentials)
+	}
+	if err == nil {
+		t.update(credentials, value)
+		err = utils.WriteAccountCredentials(account, credentials)
+	}
+	// The next attempt is scheduled before refreshMu is released, Stop removes it then
+	if err != nil {
+		failed(t, account, generation, err)
+		refreshMu.Unlock()
+		return err
+	}
+
+	mu.Lock()
+	status := statusOf(account)
+	tokenStatus := t.status(status)
+	tokenStatus.LastRefresh = time.Now()
+	tokenStatus.LastResult = "success"
+	tokenStatus.LastError = ""
+	tokenStatus.Failures = 0
+	status.LoggedIn = true
+	status.ReloginRequired = false
+	hooks := refreshHooks
+	mu.Unlock()
+	schedule(t, account, credentials, generation)
+	refreshMu.Unlock()
+	utils.Log.Println(t.name, "of account", account, "refreshed")
+
+	for _, hook := range hooks {
+		hook(account, credentials)
+	}
+	return nil
+}
+
+// failed records a failed refresh and schedules the next attempt. refreshMu must be held.
+func failed(t *token, account string, generation int, err error) {
+	utils.Log.Println("Failed to refresh", t.name, "of account", account+":", err)
+	mu.Lock()
+	status := statusOf(account)
+	tokenStatus := t.status(status)
+	tokenStatus.LastRefresh = time.Now()
+	tokenStatus.LastResult = "failed"
+	tokenStatus.LastError = err.Error()
+	tokenStatus.Failures++
+	tokenStatus.NextRefresh = time.Time{}
+	giveUp := errors.Is(err, ErrReloginRequired) || errors.Is(err, ErrNotLoggedIn)
+	if giveUp {
+		status.ReloginRequired = true
+	} else {
+		tokenStatus.NextRefresh = time.Now().Add(utils.Backoff(tokenStatus.Failures, RETRY_BASE_DELAY, RETRY_MAX_DELAY))
+	}
+	next := tokenStatus.NextRefresh
+	mu.Unlock()
+
+	if giveUp || scheduler.Scheduler == nil {
+		return
+	}
+	scheduler.AddOnce(t.taskIDOf(account), next, func() error {
+		return refresh(t, account, generation)
+	})
+}
+
+// requestAccessToken requests a new access token with the refresh token
+func requestAccessToken(credentials *utils.JIOTV_CREDENTIALS) (string, error) {
+	if credentials.RefreshToken == "" {
+		return "", ErrReloginRequired
+	}
+	requestBody, err := json.Marshal(map[string]string{
+		"appName":      "RJIL_JioTV",
+		"deviceId":     utils.GetDeviceID(),
+		"refreshToken": credentials.RefreshToken,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(REFRESH_TOKEN_URL)
+	req.Header.SetMethod("POST")
+	req.Header.Set("devicetype", "phone")
+	req.Header.Set("versionCode", "315")
+	req.Header.Set("os", "android")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", "okhttp/4.2.2")
+	req.Header.Set("accessToken", credentials.AccessToken)
+	req.SetBody(requestBody)
+
+	body, err := send(req)
+	if err != nil {
+		return "", err
+	}
+	var response refreshTokenResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	if response.AccessToken == "" {
+		return "", ErrNoTokenInResponse
+	}
+	return response.AccessToken, nil
+}
+
+// requestSSOToken requests a new SSO token with the current one
+func requestSSOToken(credentials *utils.JIOTV_CREDENTIALS) (string, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(REFRESH_SSO_TOKEN_URL)
+	req.Header.SetMethod("GET")
+	req.Header.Set("devicetype", "phone")
+	req.Header.Set("versionCode", "315")
+	req.Header.Set("os", "android")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", "okhttp/4.2.2")
+	req.Header.Set("ssoToken", credentials.SSOToken)
+	req.Header.Set("uniqueid", credentials.UniqueID)
+	req.Header.Set("deviceid", utils.GetDeviceID())
+
+	body, err := send(req)
+	if err != nil {
+		return "", err
+	}
+	var response refreshSSOTokenResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	if response.SSOToken == "" {
+		return "", ErrNoTokenInResponse
+	}
+	return response.SSOToken, nil
+}
+
+// send sends a refresh request and returns the response body.
+// Rejected tokens are reported as ErrReloginRequired.
+func send(req *fasthttp.Request) ([]byte, error) {
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := utils.GetRequestClient().Do(req, resp); err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode() {
+	case fasthttp.StatusOK:
+	case fasthttp.StatusUnauthorized, fasthttp.StatusForbidden:
+		return nil, fmt.Errorf("%w: status code %d", ErrReloginRequired, resp.StatusCode())
+	default:
+		return nil, fmt.Errorf("request failed with status code: %d", resp.StatusCode())
+	}
+	if string(resp.Header.ContentEncoding()) == "gzip" {
+		return resp.BodyGunzip()
+	}
+	return append([]byte(nil), resp.Body()...), nil
+}
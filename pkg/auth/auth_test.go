@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/scheduler"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log = log.New(io.Discard, "", 0)
+	os.Exit(m.Run())
+}
+
+func jwt(exp int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"user","exp":%d}`, exp)))
+	return "eyJhbGciOiJIUzI1NiJ9." + payload + ".signature"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	at, err := TokenExpiry(jwt(1700000000))
+	if err != nil || !at.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("TokenExpiry() = %v, %v", at, err)
+	}
+	for _, token := range []string{"", "opaque-sso-token", "a.b.c", "a." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user"}`)) + ".c"} {
+		if _, err := TokenExpiry(token); err != ErrNotJWT {
+			t.Errorf("TokenExpiry(%q) = %v, want %v", token, err, ErrNotJWT)
+		}
+	}
+
+	// Tokens that are no JWTs expire their lifetime after they were issued
+	at, source := expiry("opaque-sso-token", "1700000000", time.Hour)
+	if !at.Equal(time.Unix(1700000000, 0).Add(time.Hour)) || source != "age" {
+		t.Errorf("expiry() = %v, %v", at, source)
+	}
+}
+
+// setup stores credentials in a temporary store and replaces the refresh request of the access token
+func setup(t *testing.T, request func(*utils.JIOTV_CREDENTIALS) (string, error)) {
+	t.Helper()
+	store.KVS = store.NewMemoryStore()
+	scheduler.Init()
+	t.Cleanup(scheduler.Stop)
+	original := accessToken.request
+	accessToken.request = request
+	t.Cleanup(func() {
+		accessToken.request = original
+		mu.Lock()
+		refreshHooks = nil
+		mu.Unlock()
+		Stop(utils.DEFAULT_ACCOUNT)
+	})
+	err := utils.WriteJIOTVCredentials(&utils.JIOTV_CREDENTIALS{
+		SSOToken:     "sso",
+		CRM:          "crm",
+		UniqueID:     "unique",
+		AccessToken:  jwt(time.Now().Add(time.Hour).Unix()),
+		RefreshToken: "refresh",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	fresh := jwt(time.Now().Add(2 * time.Hour).Unix())
+	setup(t, func(credentials *utils.JIOTV_CREDENTIALS) (string, error) {
+		if credentials.RefreshToken != "refresh" {
+			return "", ErrReloginRequired
+		}
+		return fresh, nil
+	})
+	refreshed := make(chan *utils.JIOTV_CREDENTIALS, 1)
+	OnRefresh(func(_ string, credentials *utils.JIOTV_CREDENTIALS) { refreshed <- credentials })
+
+	credentials, err := utils.GetJIOTVCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Start(utils.DEFAULT_ACCOUNT, credentials)
+	status := GetStatus(utils.DEFAULT_ACCOUNT)
+	if !status.LoggedIn || status.AccessToken.ExpirySource != "jwt" || status.SSOToken.ExpirySource != "age" {
+		t.Fatalf("GetStatus() after Start() = %+v", status)
+	}
+	if want := status.AccessToken.ExpiresAt.Add(-REFRESH_MARGIN); !status.AccessToken.NextRefresh.Equal(want) {
+		t.Errorf("next refresh = %v, want %v", status.AccessToken.NextRefresh, want)
+	}
+
+	if err := RefreshAccessToken(utils.DEFAULT_ACCOUNT); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-refreshed; got.AccessToken != fresh || got.SSOToken != "sso" {
+		t.Errorf("OnRefresh hook got %+v", got)
+	}
+	stored, err := utils.GetJIOTVCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AccessToken != fresh {
+		t.Errorf("stored access token = %q, want the refreshed one", stored.AccessToken)
+	}
+	status = GetStatus(utils.DEFAULT_ACCOUNT)
+	if status.AccessToken.LastResult != "success" || !status.AccessToken.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("GetStatus() after refresh = %+v", status.AccessToken)
+	}
+}
+
+func TestRefreshFailure(t *testing.T) {
+	var requestErr error
+	setup(t, func(*utils.JIOTV_CREDENTIALS) (string, error) {
+		return "", requestErr
+	})
+
+	// Temporary errors are retried later
+	requestErr = errors.New("connection reset")
+	if err := RefreshAccessToken(utils.DEFAULT_ACCOUNT); err == nil {
+		t.Fatal("RefreshAccessToken() succeeded")
+	}
+	status := GetStatus(utils.DEFAULT_ACCOUNT)
+	if status.AccessToken.LastResult != "failed" || status.AccessToken.Failures != 1 || status.ReloginRequired {
+		t.Errorf("GetStatus() after failure = %+v", status)
+	}
+	if next := status.AccessToken.NextRefresh; next.Before(time.Now()) || next.After(time.Now().Add(RETRY_BASE_DELAY)) {
+		t.Errorf("next refresh = %v, want within %v", next, RETRY_BASE_DELAY)
+	}
+	if err := RefreshAccessToken(utils.DEFAULT_ACCOUNT); err == nil || GetStatus(utils.DEFAULT_ACCOUNT).AccessToken.Failures != 2 {
+		t.Errorf("failures = %d, want 2", GetStatus(utils.DEFAULT_ACCOUNT).AccessToken.Failures)
+	}
+
+	// Rejected refresh tokens need a new login
+	requestErr = ErrReloginRequired
+	if err := RefreshAccessToken(utils.DEFAULT_ACCOUNT); !errors.Is(err, ErrReloginRequired) {
+		t.Errorf("RefreshAccessToken() = %v, want %v", err, ErrReloginRequired)
+	}
+	status = GetStatus(utils.DEFAULT_ACCOUNT)
+	if !status.ReloginRequired || !status.AccessToken.NextRefresh.IsZero() {
+		t.Errorf("GetStatus() after rejected refresh = %+v", status)
+	}
+}
+
+func TestScheduledRefresh(t *testing.T) {
+	calls := make(chan struct{}, 2)
+	var count atomic.Int32
+	setup(t, func(*utils.JIOTV_CREDENTIALS) (string, error) {
+		calls <- struct{}{}
+		if count.Add(1) == 1 {
+			// Expires right away, so the refresh schedules itself again
+			return jwt(time.Now().Unix()), nil
+		}
+		return jwt(time.Now().Add(time.Hour).Unix()), nil
+	})
+	credentials, err := utils.GetJIOTVCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials.AccessToken = jwt(time.Now().Unix())
+	Start(utils.DEFAULT_ACCOUNT, credentials)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-calls:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("refresh %d did not run", i+1)
+		}
+	}
+	// Wait for the next refresh to be scheduled with the token that lasts
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if GetStatus(utils.DEFAULT_ACCOUNT).AccessToken.NextRefresh.After(time.Now().Add(time.Minute)) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GetStatus() = %+v", GetStatus(utils.DEFAULT_ACCOUNT))
+		}
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	setup(t, func(credentials *utils.JIOTV_CREDENTIALS) (string, error) {
+		return jwt(time.Now().Add(2 * time.Hour).Unix()), nil
+	})
+	err := utils.WriteAccountCredentials("family", &utils.JIOTV_CREDENTIALS{
+		SSOToken:     "sso-family",
+		CRM:          "crm-family",
+		UniqueID:     "unique-family",
+		AccessToken:  jwt(time.Now().Add(time.Hour).Unix()),
+		RefreshToken: "refresh-family",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Stop("family") })
+	var refreshed atomic.Value
+	OnRefresh(func(account string, _ *utils.JIOTV_CREDENTIALS) { refreshed.Store(account) })
+
+	for _, account := range []string{utils.DEFAULT_ACCOUNT, "family"} {
+		credentials, err := utils.GetAccountCredentials(account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Start(account, credentials)
+	}
+	if statuses := Statuses(); len(statuses) != 2 || statuses[0].Account != utils.DEFAULT_ACCOUNT || statuses[1].Account != "family" {
+		t.Fatalf("Statuses() = %+v", statuses)
+	}
+
+	// Refreshes store the tokens of their own account
+	if err := RefreshAccessToken("family"); err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Load() != "family" {
+		t.Errorf("OnRefresh hook got account %v", refreshed.Load())
+	}
+	family, _ := utils.GetAccountCredentials("family")
+	defaultAccount, _ := utils.GetJIOTVCredentials()
+	if family.CRM != "crm-family" || GetStatus("family").AccessToken.LastResult != "success" ||
+		GetStatus(utils.DEFAULT_ACCOUNT).AccessToken.LastResult != "" || defaultAccount.CRM != "crm" {
+		t.Errorf("after refresh family = %+v, default = %+v", family, defaultAccount)
+	}
+
+	Stop("family")
+	if statuses := Statuses(); len(statuses) != 1 || statuses[0].Account != utils.DEFAULT_ACCOUNT {
+		t.Errorf("Statuses() after Stop() = %+v", statuses)
+	}
+}
+
+// TestRefreshDuringLogout stops an account while its refresh waits for JioTV, like a logout does
+func TestRefreshDuringLogout(t *testing.T) {
+	var calls atomic.Int32
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	setup(t, func(*utils.JIOTV_CREDENTIALS) (string, error) {
+		if calls.Add(1) == 1 {
+			close(requested)
+			<-release
+		}
+		return jwt(time.Now().Add(2 * time.Hour).Unix()), nil
+	})
+	credentials, err := utils.GetJIOTVCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials.LastSSOTokenRefreshTime = strconv.FormatInt(time.Now().Unix(), 10)
+	Start(utils.DEFAULT_ACCOUNT, credentials)
+	generation := generationOf(utils.DEFAULT_ACCOUNT)
+
+	refreshed := make(chan error)
+	go func() { refreshed <- RefreshAccessToken(utils.DEFAULT_ACCOUNT) }()
+	<-requested
+	stopped := make(chan struct{})
+	go func() {
+		Stop(utils.DEFAULT_ACCOUNT)
+		close(stopped)
+	}()
+	// The credentials are deleted once Stop returns, the refresh must have written them before
+	select {
+	case <-stopped:
+		t.Fatal("Stop() returned while a refresh was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+
+	// Nothing is left to write the credentials again
+	if status := GetStatus(utils.DEFAULT_ACCOUNT); status.LoggedIn || !status.AccessToken.NextRefresh.IsZero() {
+		t.Errorf("GetStatus() after Stop() = %+v", status)
+	}
+	for _, token := range []*token{accessToken, ssoToken} {
+		if _, err := scheduler.Scheduler.Lookup(token.taskIDOf(utils.DEFAULT_ACCOUNT)); err == nil {
+			t.Errorf("refresh of %s is still scheduled after Stop()", token.name)
+		}
+	}
+
+	// Refreshes that were due before the logout are dropped
+	if err := refresh(accessToken, utils.DEFAULT_ACCOUNT, generation); err != nil {
+		t.Errorf("refresh() after Stop() = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("access token requested %d times, want 1", calls.Load())
+	}
+	if status := GetStatus(utils.DEFAULT_ACCOUNT); status.LoggedIn || status.AccessToken.LastResult != "" {
+		t.Errorf("GetStatus() after dropped refresh = %+v", status)
+	}
+}
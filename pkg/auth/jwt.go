@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotJWT is returned for tokens that carry no expiry time
+var ErrNotJWT = errors.New("token is not a JWT with an expiry time")
+
+// TokenExpiry returns the expiry time in the exp claim of a JWT.
+// The signature is not verified, the token is only read to schedule its refresh.
+func TokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, ErrNotJWT
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, ErrNotJWT
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}, ErrNotJWT
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// expiry returns when a token expires and how that was found out.
+// Tokens that are no JWTs expire lifetime after they were issued, as stored in the unix time issuedAt.
+func expiry(token, issuedAt string, lifetime time.Duration) (time.Time, string) {
+	if at, err := TokenExpiry(token); err == nil {
+		return at, "jwt"
+	}
+	issued, err := strconv.ParseInt(issuedAt, 10, 64)
+	if err != nil {
+		// Unknown age, refresh right away
+		return time.Now(), "age"
+	}
+	return time.Unix(issued, 0).Add(lifetime), "age"
+}
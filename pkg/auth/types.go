@@ -0,0 +1,46 @@
+package auth
+
+import "time"
+
+// TokenStatus is the state of the access token or the SSO token
+type TokenStatus struct {
+	// ExpiresAt is when the token expires, zero if there is no token
+	ExpiresAt time.Time `json:"expires_at"`
+	// ExpirySource is "jwt" if ExpiresAt was read from the token and "age" if it was estimated from the time the token was issued
+	ExpirySource string `json:"expiry_source,omitempty"`
+	// NextRefresh is when the token is refreshed next, zero if no refresh is scheduled
+	NextRefresh time.Time `json:"next_refresh"`
+	// LastRefresh is when the token was last refreshed
+	LastRefresh time.Time `json:"last_refresh"`
+	// LastResult is "success" or "failed" after the first refresh attempt
+	LastResult string `json:"last_result,omitempty"`
+	// LastError is the error of the last failed refresh
+	LastError string `json:"last_error,omitempty"`
+	// Failures counts the refresh attempts that failed in a row
+	Failures int `json:"failures"`
+}
+
+// Status is the state of the JioTV login of an account
+type Status struct {
+	Account     string      `json:"account"`
+	LoggedIn    bool        `json:"logged_in"`
+	AccessToken TokenStatus `json:"access_token"`
+	SSOToken    TokenStatus `json:"sso_token"`
+	// ReloginRequired is set when the tokens can no longer be refreshed
+	ReloginRequired bool `json:"relogin_required"`
+}
+
+// refreshTokenResponse is the response of the access token refresh
+type refreshTokenResponse struct {
+	AccessToken string `json:"authToken"`
+}
+
+// refreshSSOTokenResponse is the response of the SSO token refresh
+type refreshSSOTokenResponse struct {
+	SSOToken string `json:"ssoToken"`
+}
+
+// jwtClaims are the claims of the access token used to find out when it expires
+type jwtClaims struct {
+	Exp int64 `json:"exp"`
+}
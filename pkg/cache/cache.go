@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrFetchPanicked is returned to callers that waited for a fetch that panicked
+var ErrFetchPanicked = errors.New("fetch of cache entry panicked")
+
+const (
+	// maxTTLHints bounds the number of playlist directories whose TTL hint is remembered
+	maxTTLHints = 1024
+)
+
+// New creates a new cache holding at most maxBytes of response bodies in memory.
+// If dir is not empty, entries evicted from memory are spilled to files in dir until
+// they take more than maxDiskBytes.
+func New(maxBytes int64, dir string, maxDiskBytes int64) (*Cache, error) {
+	c := &Cache{
+		maxBytes:     maxBytes,
+		maxDiskBytes: maxDiskBytes,
+		memory:       list.New(),
+		memoryItems:  make(map[string]*list.Element),
+		disk:         list.New(),
+		diskItems:    make(map[string]*list.Element),
+		inflight:     make(map[string]*call),
+		hints:        make(map[string]ttlHint),
+	}
+	if dir != "" && maxDiskBytes > 0 {
+		// Files of a previous run are not indexed, start from an empty spill directory
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		c.dir = dir
+	}
+	return c, nil
+}
+
+// Get returns the entry stored for key, looking at the disk spill if it is not in memory
+func (c *Cache) Get(key string) (*Entry, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	if elem, ok := c.memoryItems[key]; ok {
+		it := elem.Value.(*item)
+		if now.Before(it.entry.expires) {
+			c.memory.MoveToFront(elem)
+			c.mu.Unlock()
+			return it.entry, true
+		}
+		c.removeMemory(elem)
+	}
+	elem, ok := c.diskItems[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+	it := elem.Value.(*item)
+	c.removeDisk(elem)
+	c.mu.Unlock()
+
+	// Files are read outside of the lock, the entry is promoted back to memory
+	defer os.Remove(it.path)
+	if !now.Before(it.entry.expires) {
+		return nil, false
+	}
+	body, err := os.ReadFile(it.path)
+	if err != nil {
+		return nil, false
+	}
+	entry := *it.entry
+	entry.Body = body
+	c.store(key, &entry)
+	return &entry, true
+}
+
+// Set stores entry for key. Entries without a positive TTL are not stored.
+func (c *Cache) Set(key string, entry *Entry) {
+	if entry.TTL <= 0 {
+		return
+	}
+	entry.expires = time.Now().Add(entry.TTL)
+	c.store(key, entry)
+}
+
+// Fetch returns the entry stored for key or calls fetch to create it.
+// Concurrent calls for the same key wait for a single fetch instead of fetching on their own.
+// The returned bool reports whether the entry was served without calling fetch.
+func (c *Cache) Fetch(key string, fetch func() (*Entry, error)) (*Entry, bool, error) {
+	if entry, ok := c.Get(key); ok {
+		c.hits.Add(1)
+		return entry, true, nil
+	}
+
+	c.mu.Lock()
+	if cl, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		cl.wg.Wait()
+		c.hits.Add(1)
+		c.collapsed.Add(1)
+		return cl.entry, true, cl.err
+	}
+	cl := new(call)
+	cl.wg.Add(1)
+	c.inflight[key] = cl
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	// Waiters are released even if fetch panics
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		cl.wg.Done()
+	}()
+	// Waiters see ErrFetchPanicked if fetch does not return
+	cl.err = ErrFetchPanicked
+	cl.entry, cl.err = fetch()
+	if cl.err == nil {
+		c.Set(key, cl.entry)
+	}
+	return cl.entry, false, cl.err
+}
+
+// SetTTLHint remembers ttl for all URLs in the same directory as uri.
+// Playlists use it to tell for how long the segments they list stay relevant.
+func (c *Cache) SetTTLHint(uri string, ttl time.Duration) {
+	c.hintsMu.Lock()
+	defer c.hintsMu.Unlock()
+	now := time.Now()
+	if len(c.hints) >= maxTTLHints {
+		for dir, hint := range c.hints {
+			if now.After(hint.expires) {
+				delete(c.hints, dir)
+			}
+		}
+	}
+	if len(c.hints) < maxTTLHints {
+		// Hints are forgotten once the playlist has not been seen for a while
+		c.hints[directory(uri)] = ttlHint{ttl: ttl, expires: now.Add(2 * ttl)}
+	}
+}
+
+// TTLHint returns the TTL hinted for the directory of uri or fallback if there is none
+func (c *Cache) TTLHint(uri string, fallback time.Duration) time.Duration {
+	c.hintsMu.Lock()
+	defer c.hintsMu.Unlock()
+	if hint, ok := c.hints[directory(uri)]; ok && time.Now().Before(hint.expires) {
+		return hint.ttl
+	}
+	return fallback
+}
+
+// Stats returns the counters and the current size of the cache
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Collapsed:    c.collapsed.Load(),
+		Entries:      c.memory.Len(),
+		Bytes:        c.memoryBytes,
+		MaxBytes:     c.maxBytes,
+		DiskEntries:  c.disk.Len(),
+		DiskBytes:    c.diskBytes,
+		MaxDiskBytes: c.maxDiskBytes,
+	}
+}
+
+// store adds entry to memory and spills whatever does not fit anymore
+func (c *Cache) store(key string, entry *Entry) {
+	size := int64(len(entry.Body))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.memoryItems[key]; ok {
+		c.removeMemory(elem)
+	}
+	if elem, ok := c.diskItems[key]; ok {
+		os.Remove(elem.Value.(*item).path)
+		c.removeDisk(elem)
+	}
+	c.memoryItems[key] = c.memory.PushFront(&item{key: key, entry: entry})
+	c.memoryBytes += size
+
+	var evicted []*item
+	for c.memoryBytes > c.maxBytes {
+		elem := c.memory.Back()
+		evicted = append(evicted, elem.Value.(*item))
+		c.removeMemory(elem)
+	}
+	c.mu.Unlock()
+
+	for _, it := range evicted {
+		c.spill(it)
+	}
+}
+
+// spill writes an entry evicted from memory to the disk spill directory
+func (c *Cache) spill(it *item) {
+	size := int64(len(it.entry.Body))
+	if c.dir == "" || size > c.maxDiskBytes || !time.Now().Before(it.entry.expires) {
+		return
+	}
+	sum := sha256.Sum256([]byte(it.key))
+	path := filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+	if err := os.WriteFile(path, it.entry.Body, 0600); err != nil {
+		return
+	}
+
+	// Only metadata is kept in memory
+	entry := *it.entry
+	entry.Body = nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The key may have been stored again while the file was written
+	if _, ok := c.memoryItems[it.key]; ok {
+		os.Remove(path)
+		return
+	}
+	if elem, ok := c.diskItems[it.key]; ok {
+		c.removeDisk(elem)
+	}
+	c.diskItems[it.key] = c.disk.PushFront(&item{key: it.key, entry: &entry, path: path, size: size})
+	c.diskBytes += size
+	for c.diskBytes > c.maxDiskBytes {
+		elem := c.disk.Back()
+		os.Remove(elem.Value.(*item).path)
+		c.removeDisk(elem)
+	}
+}
+
+// removeMemory removes elem from the memory list. c.mu must be held.
+func (c *Cache) removeMemory(elem *list.Element) {
+	it := elem.Value.(*item)
+	c.memory.Remove(elem)
+	delete(c.memoryItems, it.key)
+	c.memoryBytes -= int64(len(it.entry.Body))
+}
+
+// removeDisk removes elem from the disk index, the caller removes the file. c.mu must be held.
+func (c *Cache) removeDisk(elem *list.Element) {
+	it := elem.Value.(*item)
+	c.disk.Remove(elem)
+	delete(c.diskItems, it.key)
+	c.diskBytes -= it.size
+}
+
+// directory returns uri up to the last slash of its path
+func directory(uri string) string {
+	path, _, _ := strings.Cut(uri, "?")
+	if idx := strings.LastIndexByte(path, '/'); idx >= 0 {
+		return path[:idx+1]
+	}
+	return path
+}
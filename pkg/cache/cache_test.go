@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func entry(size int, ttl time.Duration) *Entry {
+	return &Entry{Body: bytes.Repeat([]byte("x"), size), StatusCode: 200, TTL: ttl}
+}
+
+func TestEviction(t *testing.T) {
+	c, err := New(100, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", entry(40, time.Minute))
+	c.Set("b", entry(40, time.Minute))
+	// a is used last, so b is evicted first
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a not cached")
+	}
+	c.Set("c", entry(40, time.Minute))
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if stats := c.Stats(); stats.Entries != 2 || stats.Bytes != 80 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// Entries larger than the cache and entries without TTL are not stored
+	c.Set("large", entry(101, time.Minute))
+	c.Set("uncached", entry(1, 0))
+	for _, key := range []string{"large", "uncached"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%s was stored", key)
+		}
+	}
+}
+
+func TestDiskSpill(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(100, dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := entry(60, time.Minute)
+	first.ContentType = "video/mp2t"
+	c.Set("first", first)
+	c.Set("second", entry(60, time.Minute))
+
+	stats := c.Stats()
+	if stats.Entries != 1 || stats.DiskEntries != 1 || stats.DiskBytes != 60 {
+		t.Fatalf("Stats() after spill = %+v", stats)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("spill files = %v", files)
+	}
+
+	// Reading it back promotes it to memory and removes the file
+	got, ok := c.Get("first")
+	if !ok || len(got.Body) != 60 || got.ContentType != "video/mp2t" {
+		t.Fatalf("Get() from disk = %+v, %v", got, ok)
+	}
+	if stats := c.Stats(); stats.Entries != 1 || stats.DiskEntries != 1 {
+		t.Errorf("Stats() after read back = %+v", stats)
+	}
+	if _, ok := c.Get("second"); !ok {
+		t.Error("second was not spilled when first was read back")
+	}
+
+	// Files beyond the disk size are removed
+	small, err := New(10, t.TempDir(), 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small.Set("a", entry(10, time.Minute))
+	small.Set("b", entry(10, time.Minute))
+	small.Set("c", entry(60, time.Minute))
+	if stats := small.Stats(); stats.DiskEntries != 1 || stats.DiskBytes != 10 {
+		t.Errorf("Stats() with full disk = %+v", stats)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c, err := New(100, t.TempDir(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("short", entry(10, 20*time.Millisecond))
+	c.Set("spilled", entry(60, 20*time.Millisecond))
+	c.Set("evicting", entry(60, time.Minute))
+	if _, ok := c.Get("short"); !ok {
+		t.Fatal("short not cached")
+	}
+	time.Sleep(30 * time.Millisecond)
+	for _, key := range []string{"short", "spilled"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("expired entry %s was returned", key)
+		}
+	}
+
+	c.SetTTLHint("https://cdn/channel/index.m3u8?token=a", 20*time.Millisecond)
+	if ttl := c.TTLHint("https://cdn/channel/segment1.ts", time.Minute); ttl != 20*time.Millisecond {
+		t.Errorf("TTLHint() = %v", ttl)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if ttl := c.TTLHint("https://cdn/channel/segment1.ts", time.Minute); ttl != time.Minute {
+		t.Errorf("TTLHint() after expiry = %v", ttl)
+	}
+}
+
+func TestFetchCollapses(t *testing.T) {
+	c, err := New(100, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func() (*Entry, error) {
+		fetches.Add(1)
+		<-release
+		return entry(10, time.Minute), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, _, err := c.Fetch("segment", fetch); err != nil || len(got.Body) != 10 {
+				t.Errorf("Fetch() = %+v, %v", got, err)
+			}
+		}()
+	}
+	// Let the callers queue up behind the first fetch
+	for deadline := time.Now().Add(time.Second); c.Stats().Misses == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches.Load() != 1 {
+		t.Errorf("fetched %d times, want 1", fetches.Load())
+	}
+	if stats := c.Stats(); stats.Misses != 1 || stats.Hits != 9 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if _, hit, _ := c.Fetch("segment", fetch); !hit {
+		t.Error("Fetch() after the fetch was not served from the cache")
+	}
+
+	// Errors are not cached
+	failed := errors.New("upstream failed")
+	if _, _, err := c.Fetch("failing", func() (*Entry, error) { return nil, failed }); err != failed {
+		t.Errorf("Fetch() = %v, want %v", err, failed)
+	}
+	if _, ok := c.Get("failing"); ok {
+		t.Error("failed fetch was cached")
+	}
+}
+
+func TestFetchPanic(t *testing.T) {
+	c, err := New(100, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	queued := make(chan struct{})
+	waiter := make(chan error)
+	go func() {
+		defer func() { recover() }()
+		c.Fetch("segment", func() (*Entry, error) {
+			close(started)
+			// Give the second caller time to queue up
+			<-queued
+			time.Sleep(20 * time.Millisecond)
+			panic("render failed")
+		})
+	}()
+	<-started
+	go func() {
+		close(queued)
+		_, _, err := c.Fetch("segment", func() (*Entry, error) { return entry(1, time.Minute), nil })
+		waiter <- err
+	}()
+
+	select {
+	case err := <-waiter:
+		if err != ErrFetchPanicked {
+			t.Errorf("waiting Fetch() = %v, want %v", err, ErrFetchPanicked)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting Fetch() blocked after the fetch panicked")
+	}
+	// Later requests fetch again
+	if got, hit, err := c.Fetch("segment", func() (*Entry, error) { return entry(1, time.Minute), nil }); err != nil || hit || got == nil {
+		t.Errorf("Fetch() after panic = %+v, %v, %v", got, hit, err)
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache is a size bounded LRU cache of upstream responses with an optional disk spill
+type Cache struct {
+	mu           sync.Mutex
+	maxBytes     int64
+	memoryBytes  int64
+	memory       *list.List
+	memoryItems  map[string]*list.Element
+	dir          string
+	maxDiskBytes int64
+	diskBytes    int64
+	disk         *list.List
+	diskItems    map[string]*list.Element
+	inflight     map[string]*call
+
+	hintsMu sync.Mutex
+	hints   map[string]ttlHint
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	collapsed atomic.Int64
+}
+
+// Entry represents a cached upstream response
+type Entry struct {
+	Body        []byte
+	ContentType string
+	StatusCode  int
+	// TTL is how long the entry stays valid. Entries without a positive TTL are not cached.
+	TTL     time.Duration
+	expires time.Time
+}
+
+// Stats represents the counters and size of a cache
+type Stats struct {
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"`
+	Collapsed    int64 `json:"collapsed"` // Requests that waited for a concurrent upstream fetch
+	Entries      int   `json:"entries"`
+	Bytes        int64 `json:"bytes"`
+	MaxBytes     int64 `json:"max_bytes"`
+	DiskEntries  int   `json:"disk_entries"`
+	DiskBytes    int64 `json:"disk_bytes"`
+	MaxDiskBytes int64 `json:"max_disk_bytes"`
+}
+
+// item is an element of the memory and disk lists
+type item struct {
+	key   string
+	entry *Entry
+	path  string // Spill file, only set for items on disk
+	size  int64  // Size of the spill file
+}
+
+// ttlHint is a TTL remembered for a playlist directory
+type ttlHint struct {
+	ttl     time.Duration
+	expires time.Time
+}
+
+// call is an in-flight fetch other callers can wait for
+type call struct {
+	wg    sync.WaitGroup
+	entry *Entry
+	err   error
+}
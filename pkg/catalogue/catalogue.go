@@ -0,0 +1,226 @@
+package catalogue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	// CATALOGUE_FILE stores the last good channel list inside the path prefix
+	CATALOGUE_FILE = "channels.json"
+	// TASK_ID is the scheduler task ID of the periodic refresh
+	TASK_ID = "channels_refresh"
+	// DEFAULT_TTL is how long the channel list is served before it is refreshed
+	DEFAULT_TTL = time.Hour
+	// MIN_RETRY_INTERVAL bounds how often a stale channel list is refreshed while JioTV fails
+	MIN_RETRY_INTERVAL = time.Minute
+)
+
+var (
+	mu          sync.RWMutex
+	current     *Snapshot
+	ttl         = DEFAULT_TTL
+	lastAttempt time.Time
+	refreshing  bool
+
+	// refreshMu serialises requests to JioTV
+	refreshMu sync.Mutex
+	// fetch requests the channel list from JioTV
+	fetch = television.Channels
+)
+
+// Init loads the last good channel list from disk.
+// The list is served for ttl before it is refreshed, DEFAULT_TTL if ttl is not positive.
+func Init(refreshAfter time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ttl = DEFAULT_TTL
+	if refreshAfter > 0 {
+		ttl = refreshAfter
+	}
+	current = nil
+	overridesMu.Lock()
+	overrides = nil
+	overridesMu.Unlock()
+	numbersMu.Lock()
+	numbers = nil
+	numbersMu.Unlock()
+	if err := loadChanges(); err != nil {
+		utils.Log.Println("Failed to load channel changes:", err)
+	}
+
+	data, err := os.ReadFile(utils.GetPathPrefix() + CATALOGUE_FILE)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	current = &snapshot
+	utils.Log.Printf("Loaded %d channels fetched at %v", len(snapshot.Channels.Result), snapshot.FetchedAt.Local())
+	return nil
+}
+
+// TTL returns how long the channel list is served before it is refreshed
+func TTL() time.Duration {
+	mu.RLock()
+	defer mu.RUnlock()
+	return ttl
+}
+
+// Get returns the cached channel list with the user overrides applied, ordered by channel number.
+// The list is fetched on first use. A stale list is returned right away and refreshed in the background.
+func Get() (*Snapshot, error) {
+	mu.Lock()
+	snapshot := current
+	stale := snapshot != nil && time.Since(snapshot.FetchedAt) > ttl
+	// Only one background refresh at a time, and not too often while JioTV fails
+	if stale && !refreshing && time.Since(lastAttempt) > MIN_RETRY_INTERVAL {
+		refreshing = true
+		go func() {
+			if err := Refresh(); err != nil {
+				utils.Log.Println("Serving stale channel list:", err)
+			}
+			mu.Lock()
+			refreshing = false
+			mu.Unlock()
+		}()
+	}
+	mu.Unlock()
+
+	if snapshot == nil {
+		if err := fetchFirst(); err != nil {
+			return nil, err
+		}
+		mu.RLock()
+		snapshot = current
+		mu.RUnlock()
+	}
+	snapshot = snapshot.clone()
+	numberChannels(snapshot.Channels.Result, overrideNumbers())
+	applyOverrides(snapshot)
+	// Players and the web UI list channels by number
+	sort.SliceStable(snapshot.Channels.Result, func(i, j int) bool {
+		return snapshot.Channels.Result[i].Number < snapshot.Channels.Result[j].Number
+	})
+	return snapshot, nil
+}
+
+// Channels returns the channels of the cached channel list
+func Channels() ([]television.Channel, error) {
+	snapshot, err := Get()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Channels.Result, nil
+}
+
+// Refresh fetches the channel list from JioTV and saves it to disk.
+// The previous list is kept if the request fails.
+func Refresh() error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	return refresh()
+}
+
+// fetchFirst fetches the channel list unless a concurrent caller fetched it while waiting for refreshMu
+func fetchFirst() error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	mu.RLock()
+	fetched := current != nil
+	mu.RUnlock()
+	if fetched {
+		return nil
+	}
+	return refresh()
+}
+
+// refresh fetches the channel list and saves it. refreshMu must be held.
+func refresh() error {
+	mu.Lock()
+	lastAttempt = time.Now()
+	mu.Unlock()
+
+	response, err := fetch()
+	if err != nil {
+		return err
+	}
+	// An empty list is as good as none, keep the last good one
+	if len(response.Result) == 0 {
+		return &television.UpstreamError{Err: television.ErrUpstreamUnavailable, Detail: "empty channel list"}
+	}
+
+	etag, err := hash(response)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	snapshot := &Snapshot{
+		Channels:   response,
+		FetchedAt:  now,
+		ModifiedAt: now,
+		ETag:       etag,
+	}
+
+	mu.Lock()
+	previous := current
+	if previous != nil && previous.ETag == etag {
+		snapshot.ModifiedAt = previous.ModifiedAt
+	}
+	current = snapshot
+	mu.Unlock()
+
+	err = save(snapshot)
+	if previous != nil && previous.ETag != etag {
+		recordChanges(Diff(previous.Channels.Result, response.Result, now))
+	}
+	return err
+}
+
+// clone copies the snapshot, so callers may modify the channels
+func (s *Snapshot) clone() *Snapshot {
+	clone := *s
+	clone.Channels.Result = append([]television.Channel(nil), s.Channels.Result...)
+	return &clone
+}
+
+// hash returns a short hex digest of the channel list
+func hash(response television.ChannelsResponse) (string, error) {
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(data), nil
+}
+
+// hashBytes returns a short hex digest of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// save writes the snapshot to disk
+func save(snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	filename := utils.GetPathPrefix() + CATALOGUE_FILE
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,308 @@
+package catalogue
+
+import (
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// setup points the path prefix at a temporary folder and replaces the JioTV request with response
+func setup(t *testing.T, response *television.ChannelsResponse, err *error) {
+	t.Helper()
+	utils.Log = log.New(io.Discard, "", 0)
+	config.Cfg.PathPrefix = t.TempDir()
+	fetch = func() (television.ChannelsResponse, error) {
+		return *response, *err
+	}
+	t.Cleanup(func() { fetch = television.Channels })
+	if err := Init(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func channels(names ...string) television.ChannelsResponse {
+	var response television.ChannelsResponse
+	for i, name := range names {
+		response.Result = append(response.Result, television.Channel{ID: string(rune('1' + i)), Name: name, LogoURL: name + ".png"})
+	}
+	return response
+}
+
+func TestDiff(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	previous := []television.Channel{
+		{ID: "1", Name: "News", LogoURL: "news.png"},
+		{ID: "2", Name: "Sports", LogoURL: "sports.png"},
+		{ID: "3", Name: "Movies", LogoURL: "movies.png"},
+	}
+	next := []television.Channel{
+		{ID: "1", Name: "News HD", LogoURL: "news.png"},
+		{ID: "3", Name: "Movies", LogoURL: "movies_new.png"},
+		{ID: "4", Name: "Music", LogoURL: "music.png"},
+	}
+	want := []Change{
+		{Type: ChangeRemoved, ChannelID: "2", Name: "Sports", LogoURL: "sports.png", Time: at},
+		{Type: ChangeRenamed, ChannelID: "1", Name: "News HD", OldName: "News", Time: at},
+		{Type: ChangeLogo, ChannelID: "3", Name: "Movies", LogoURL: "movies_new.png", OldLogoURL: "movies.png", Time: at},
+		{Type: ChangeAdded, ChannelID: "4", Name: "Music", LogoURL: "music.png", Time: at},
+	}
+	if got := Diff(previous, next, at); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if got := Diff(next, next, at); len(got) != 0 {
+		t.Errorf("Diff() of equal lists = %+v, want none", got)
+	}
+}
+
+func TestServeLastGoodList(t *testing.T) {
+	response := channels("News", "Sports")
+	var fetchErr error
+	setup(t, &response, &fetchErr)
+
+	first, err := Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Callers may modify the channels they get
+	first.Channels.Result[0].Name = "Changed"
+
+	fetchErr = television.ErrUpstreamUnavailable
+	if err := Refresh(); err == nil {
+		t.Fatal("Refresh() succeeded while JioTV is unavailable")
+	}
+	got, err := Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Channels.Result[0].Name != "News" || got.ETag != first.ETag {
+		t.Errorf("Get() = %+v, want the last good list", got.Channels.Result)
+	}
+
+	// The last good list is loaded from disk after a restart
+	if err := Init(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	got, err = Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Channels.Result) != 2 || got.Channels.Result[0].ID != "1" || got.ETag != first.ETag {
+		t.Errorf("Get() after restart = %+v", got)
+	}
+}
+
+func TestFetchOnce(t *testing.T) {
+	response := channels("News", "Sports")
+	var fetchErr error
+	setup(t, &response, &fetchErr)
+	var fetches atomic.Int32
+	fetch = func() (television.ChannelsResponse, error) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return response, nil
+	}
+
+	// Callers that wait for the first fetch use its list
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := Get(); err != nil || len(got.Channels.Result) != 2 {
+				t.Errorf("Get() = %+v, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches.Load() != 1 {
+		t.Errorf("fetched %d times on a cold start, want 1", fetches.Load())
+	}
+}
+
+func TestRefreshRecordsChanges(t *testing.T) {
+	response := channels("News", "Sports")
+	var fetchErr error
+	setup(t, &response, &fetchErr)
+	if err := Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	notified := make(chan []Change, 1)
+	OnChange(func(changes []Change) { notified <- changes })
+	t.Cleanup(func() { changeHooks = nil })
+
+	response = channels("News", "Sports", "Music")
+	if err := Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	got := Changes(time.Time{})
+	if len(got) != 1 || got[0].Type != ChangeAdded || got[0].Name != "Music" {
+		t.Fatalf("Changes() = %+v, want Music added", got)
+	}
+	if changes := <-notified; !reflect.DeepEqual(changes, got) {
+		t.Errorf("OnChange hook got %+v, want %+v", changes, got)
+	}
+	if since := Changes(got[0].Time); len(since) != 0 {
+		t.Errorf("Changes(since last change) = %+v, want none", since)
+	}
+
+	// The changelog is kept across restarts
+	if err := Init(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if after := Changes(time.Time{}); len(after) != 1 {
+		t.Errorf("Changes() after restart = %+v", after)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	response := channels("News", "Sports", "Movies")
+	var fetchErr error
+	setup(t, &response, &fetchErr)
+	original, err := Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetOverride("1", Override{Name: "My News", LogoURL: "https://example.com/news.png", Number: 7, Group: "Favourites"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOverride("2", Override{Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOverride("3", Override{Number: -1}); err != ErrInvalidNumber {
+		t.Errorf("SetOverride() with negative number = %v, want %v", err, ErrInvalidNumber)
+	}
+
+	got, err := Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []television.Channel{
+		{ID: "3", Name: "Movies", LogoURL: "Movies.png", Number: 3},
+		{ID: "1", Name: "My News", LogoURL: "https://example.com/news.png", Number: 7, Group: "Favourites"},
+	}
+	if !reflect.DeepEqual(got.Channels.Result, want) {
+		t.Errorf("Get() = %+v, want %+v", got.Channels.Result, want)
+	}
+	if got.ETag == original.ETag {
+		t.Error("ETag did not change with the overrides")
+	}
+
+	// Edits of the file are picked up without a restart
+	edited := `{"1": {"name": "My News"}, "2": {"hidden": true}}`
+	if err := os.WriteFile(utils.GetPathPrefix()+OVERRIDES_FILE, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := Overrides()
+	if len(reloaded) != 2 || reloaded["1"] != (Override{Name: "My News"}) || !reloaded["2"].Hidden {
+		t.Errorf("Overrides() after editing the file = %+v", reloaded)
+	}
+	if err := DeleteOverride("2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteOverride("2"); err != ErrOverrideNotFound {
+		t.Errorf("DeleteOverride() of missing override = %v, want %v", err, ErrOverrideNotFound)
+	}
+	if err := SetOverride("1", Override{}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Channels.Result) != 3 || got.Channels.Result[0].Name != "News" || got.ETag != original.ETag {
+		t.Errorf("Get() without overrides = %+v", got)
+	}
+}
+
+func TestNumbering(t *testing.T) {
+	response := television.ChannelsResponse{Result: []television.Channel{
+		{ID: "20", Name: "Hindi News", Category: 12, Language: 1},
+		{ID: "3", Name: "English Movies", Category: 6, Language: 6},
+		{ID: "10", Name: "English News", Category: 12, Language: 6},
+		{ID: "1", Name: "Hindi Movies", Category: 6, Language: 1},
+		{ID: "2", Name: "Hindi Movies 2", Category: 6, Language: 1},
+	}}
+	var fetchErr error
+	setup(t, &response, &fetchErr)
+
+	numbered := func() map[string]int {
+		t.Helper()
+		snapshot, err := Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[string]int)
+		previous := 0
+		for _, channel := range snapshot.Channels.Result {
+			if channel.Number <= previous {
+				t.Errorf("channels are not ordered by number: %+v", snapshot.Channels.Result)
+			}
+			previous = channel.Number
+			result[channel.ID] = channel.Number
+		}
+		return result
+	}
+
+	// Grouped by category, then language, each category after a round number
+	want := map[string]int{"1": 1, "2": 2, "3": 3, "20": 101, "10": 102}
+	if got := numbered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers = %v, want %v", got, want)
+	}
+
+	// Channels keep their numbers when others come and go
+	response.Result = []television.Channel{
+		{ID: "20", Name: "Hindi News", Category: 12, Language: 1},
+		{ID: "3", Name: "English Movies", Category: 6, Language: 6},
+		{ID: "10", Name: "English News", Category: 12, Language: 6},
+		{ID: "2", Name: "Hindi Movies 2", Category: 6, Language: 1},
+		{ID: "4", Name: "Another Movies", Category: 6, Language: 1},
+		{ID: "30", Name: "Cartoons", Category: 7, Language: 1},
+	}
+	if err := Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]int{"2": 2, "3": 3, "20": 101, "10": 102, "4": 4, "30": 201}
+	if got := numbered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers after update = %v, want %v", got, want)
+	}
+
+	// and across restarts
+	if err := Init(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := numbered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers after restart = %v, want %v", got, want)
+	}
+
+	// Numbers set by overrides are taken from the channels that had them
+	if err := SetOverride("20", Override{Number: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOverride("10", Override{Number: 6}); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]int{"2": 5, "3": 3, "20": 2, "10": 6, "4": 4, "30": 201}
+	if got := numbered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers with overrides = %v, want %v", got, want)
+	}
+
+	// and not given to new channels
+	response.Result = append(response.Result, television.Channel{ID: "5", Name: "More Movies", Category: 6, Language: 1})
+	if err := Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	want["5"] = 7
+	if got := numbered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers of new channels with overrides = %v, want %v", got, want)
+	}
+}
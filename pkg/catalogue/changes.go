@@ -0,0 +1,181 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	// CHANGES_FILE stores the changelog of the channel list inside the path prefix
+	CHANGES_FILE = "channel_changes.json"
+	// MAX_CHANGES is how many changes the changelog keeps
+	MAX_CHANGES = 500
+	// WEBHOOK_TIMEOUT bounds the delivery of changes to a webhook
+	WEBHOOK_TIMEOUT = 10 * time.Second
+)
+
+// Change types
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeRenamed = "renamed"
+	ChangeLogo    = "logo"
+)
+
+var (
+	changesMu sync.Mutex
+	changes   []Change
+
+	changeHooks   []func([]Change)
+	changeHooksMu sync.Mutex
+)
+
+// OnChange registers hook to be called with the changes whenever the channel list changes
+func OnChange(hook func([]Change)) {
+	changeHooksMu.Lock()
+	defer changeHooksMu.Unlock()
+	changeHooks = append(changeHooks, hook)
+}
+
+// runChangeHooks calls the hooks registered with OnChange
+func runChangeHooks(changes []Change) {
+	changeHooksMu.Lock()
+	hooks := append([]func([]Change){}, changeHooks...)
+	changeHooksMu.Unlock()
+	for _, hook := range hooks {
+		hook(changes)
+	}
+}
+
+// Diff compares two channel lists and returns the removed, added, renamed channels and changed logos
+func Diff(previous, next []television.Channel, at time.Time) []Change {
+	var diff []Change
+	seen := make(map[string]television.Channel, len(previous))
+	for _, channel := range previous {
+		seen[channel.ID] = channel
+	}
+	kept := make(map[string]bool, len(next))
+	for _, channel := range next {
+		kept[channel.ID] = true
+	}
+
+	for _, channel := range previous {
+		if !kept[channel.ID] {
+			diff = append(diff, Change{Type: ChangeRemoved, ChannelID: channel.ID, Name: channel.Name, LogoURL: channel.LogoURL, Time: at})
+		}
+	}
+	for _, channel := range next {
+		old, ok := seen[channel.ID]
+		switch {
+		case !ok:
+			diff = append(diff, Change{Type: ChangeAdded, ChannelID: channel.ID, Name: channel.Name, LogoURL: channel.LogoURL, Time: at})
+			continue
+		case old.Name != channel.Name:
+			diff = append(diff, Change{Type: ChangeRenamed, ChannelID: channel.ID, Name: channel.Name, OldName: old.Name, Time: at})
+		}
+		if old.LogoURL != channel.LogoURL {
+			diff = append(diff, Change{Type: ChangeLogo, ChannelID: channel.ID, Name: channel.Name, LogoURL: channel.LogoURL, OldLogoURL: old.LogoURL, Time: at})
+		}
+	}
+	return diff
+}
+
+// Changes returns the recorded changes after since, newest first
+func Changes(since time.Time) []Change {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+	result := []Change{}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if !changes[i].Time.After(since) {
+			break
+		}
+		result = append(result, changes[i])
+	}
+	return result
+}
+
+// recordChanges appends diff to the changelog, saves it and notifies the OnChange hooks
+func recordChanges(diff []Change) {
+	if len(diff) == 0 {
+		return
+	}
+	utils.Log.Printf("Channel list changed: %d changes", len(diff))
+
+	changesMu.Lock()
+	changes = append(changes, diff...)
+	if len(changes) > MAX_CHANGES {
+		changes = append([]Change(nil), changes[len(changes)-MAX_CHANGES:]...)
+	}
+	err := saveChanges()
+	changesMu.Unlock()
+	if err != nil {
+		utils.Log.Println("Failed to save channel changes:", err)
+	}
+
+	runChangeHooks(diff)
+}
+
+// loadChanges reads the changelog from disk
+func loadChanges() error {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+
+	changes = nil
+	data, err := os.ReadFile(utils.GetPathPrefix() + CHANGES_FILE)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &changes)
+}
+
+// saveChanges writes the changelog to disk. changesMu must be held.
+func saveChanges() error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	filename := utils.GetPathPrefix() + CHANGES_FILE
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// Webhook returns an OnChange hook that posts the changes as JSON to url
+func Webhook(url string) func([]Change) {
+	client := &fasthttp.Client{}
+	return func(diff []Change) {
+		body, err := json.Marshal(WebhookPayload{Changes: diff})
+		if err != nil {
+			utils.Log.Println(err)
+			return
+		}
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI(url)
+		req.Header.SetMethod("POST")
+		req.Header.SetContentType("application/json")
+		req.SetBody(body)
+
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+
+		if err := client.DoTimeout(req, resp, WEBHOOK_TIMEOUT); err != nil {
+			utils.Log.Println("Failed to deliver channel changes to webhook:", err)
+			return
+		}
+		if resp.StatusCode() >= fasthttp.StatusBadRequest {
+			utils.Log.Printf("Failed to deliver channel changes to webhook: status code %d", resp.StatusCode())
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	// NUMBERS_FILE stores the assigned channel numbers inside the path prefix
+	NUMBERS_FILE = "channel_numbers.json"
+	// BLOCK_ALIGN aligns the number blocks of categories, so each category starts after a round number
+	BLOCK_ALIGN = 100
+)
+
+var (
+	numbersMu sync.Mutex
+	numbers   *Numbering
+)
+
+// numberChannels sets the number of every channel.
+// Numbers are assigned once and kept, so a channel keeps its number when others are added or removed.
+// New channels get the next free number in the block of their category.
+// Numbers set by overrides, by channel ID, are reserved: other channels holding one of them get a new number.
+func numberChannels(channels []television.Channel, overridden map[string]int) {
+	numbersMu.Lock()
+	defer numbersMu.Unlock()
+	if numbers == nil {
+		numbers = loadNumbers()
+	}
+
+	reserved := make(map[int]bool, len(overridden))
+	for _, number := range overridden {
+		reserved[number] = true
+	}
+	var unnumbered []television.Channel
+	for _, channel := range channels {
+		number, ok := numbers.Numbers[channel.ID]
+		if _, own := overridden[channel.ID]; ok && reserved[number] && !own {
+			delete(numbers.Numbers, channel.ID)
+			ok = false
+		}
+		if !ok {
+			unnumbered = append(unnumbered, channel)
+		}
+	}
+	if len(unnumbered) > 0 {
+		numbers.assign(unnumbered, reserved)
+		if err := saveNumbers(); err != nil {
+			utils.Log.Println("Failed to save channel numbers:", err)
+		}
+	}
+
+	for i := range channels {
+		channels[i].Number = numbers.Numbers[channels[i].ID]
+	}
+}
+
+// assign numbers the channels, grouped by category, then language. Reserved numbers are skipped.
+func (n *Numbering) assign(channels []television.Channel, reserved map[int]bool) {
+	sort.Slice(channels, func(i, j int) bool {
+		a, b := channels[i], channels[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.Language != b.Language {
+			return a.Language < b.Language
+		}
+		return lessID(a.ID, b.ID)
+	})
+
+	// Numbers in use, including those of channels that are gone for now
+	used := make(map[int]bool, len(n.Numbers)+len(reserved))
+	for number := range reserved {
+		used[number] = true
+	}
+	highest := 0
+	for _, number := range n.Numbers {
+		used[number] = true
+		highest = max(highest, number)
+	}
+	for _, block := range n.Blocks {
+		highest = max(highest, block.End)
+	}
+
+	for start := 0; start < len(channels); {
+		category := channels[start].Category
+		end := start
+		for end < len(channels) && channels[end].Category == category {
+			end++
+		}
+
+		key := strconv.Itoa(category)
+		block, ok := n.Blocks[key]
+		if !ok {
+			// New categories get a block with room for half as many channels again
+			count := end - start
+			block.Start = roundUp(highest, BLOCK_ALIGN) + 1
+			block.End = roundUp(block.Start-1+count+count/2, BLOCK_ALIGN)
+			n.Blocks[key] = block
+			highest = block.End
+		}
+
+		next := block.Start
+		for _, channel := range channels[start:end] {
+			for next <= block.End && used[next] {
+				next++
+			}
+			number := next
+			if number > block.End {
+				// The block is full, continue after all other numbers
+				number = highest + 1
+				for used[number] {
+					number++
+				}
+			}
+			n.Numbers[channel.ID] = number
+			used[number] = true
+			highest = max(highest, number)
+		}
+		start = end
+	}
+}
+
+// lessID orders channel IDs numerically
+func lessID(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x < y
+}
+
+// roundUp rounds n up to a multiple of align
+func roundUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
+// loadNumbers reads the assigned channel numbers from disk
+func loadNumbers() *Numbering {
+	result := &Numbering{Numbers: make(map[string]int), Blocks: make(map[string]NumberBlock)}
+	data, err := os.ReadFile(utils.GetPathPrefix() + NUMBERS_FILE)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.Log.Println("Failed to read channel numbers:", err)
+		}
+		return result
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		utils.Log.Println("Failed to read channel numbers:", err)
+		return &Numbering{Numbers: make(map[string]int), Blocks: make(map[string]NumberBlock)}
+	}
+	if result.Numbers == nil {
+		result.Numbers = make(map[string]int)
+	}
+	if result.Blocks == nil {
+		result.Blocks = make(map[string]NumberBlock)
+	}
+	return result
+}
+
+// saveNumbers writes the assigned channel numbers to disk. numbersMu must be held.
+func saveNumbers() error {
+	data, err := json.MarshalIndent(numbers, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := utils.GetPathPrefix() + NUMBERS_FILE
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,199 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// OVERRIDES_FILE stores the user overrides of channels inside the path prefix
+const OVERRIDES_FILE = "channel_overrides.json"
+
+// Errors
+var (
+	ErrOverrideNotFound = errors.New("channel has no override")
+	ErrInvalidNumber    = errors.New("channel number must not be negative")
+)
+
+var (
+	overridesMu      sync.RWMutex
+	overrides        map[string]Override
+	overridesETag    string
+	overridesModTime time.Time
+)
+
+// IsZero reports whether the override changes nothing
+func (o Override) IsZero() bool {
+	return o == Override{}
+}
+
+// apply returns the channel with the override applied
+func (o Override) apply(channel television.Channel) television.Channel {
+	if o.Name != "" {
+		channel.Name = o.Name
+	}
+	if o.LogoURL != "" {
+		channel.LogoURL = o.LogoURL
+	}
+	if o.Number > 0 {
+		channel.Number = o.Number
+	}
+	if o.Group != "" {
+		channel.Group = o.Group
+	}
+	return channel
+}
+
+// Overrides returns the user overrides of channels by channel ID
+func Overrides() map[string]Override {
+	reloadOverrides()
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	result := make(map[string]Override, len(overrides))
+	for id, override := range overrides {
+		result[id] = override
+	}
+	return result
+}
+
+// SetOverride saves the override of a channel. An empty override removes it.
+func SetOverride(channelID string, override Override) error {
+	if override.Number < 0 {
+		return ErrInvalidNumber
+	}
+	reloadOverrides()
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	if override.IsZero() {
+		delete(overrides, channelID)
+	} else {
+		overrides[channelID] = override
+	}
+	return saveOverrides()
+}
+
+// DeleteOverride removes the override of a channel
+func DeleteOverride(channelID string) error {
+	reloadOverrides()
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	if _, ok := overrides[channelID]; !ok {
+		return ErrOverrideNotFound
+	}
+	delete(overrides, channelID)
+	return saveOverrides()
+}
+
+// overrideNumbers returns the channel numbers set by overrides of channels that are not hidden, by channel ID
+func overrideNumbers() map[string]int {
+	reloadOverrides()
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	result := make(map[string]int)
+	for id, override := range overrides {
+		if override.Number > 0 && !override.Hidden {
+			result[id] = override.Number
+		}
+	}
+	return result
+}
+
+// applyOverrides applies the user overrides to the channels of a snapshot and drops hidden channels
+func applyOverrides(snapshot *Snapshot) {
+	reloadOverrides()
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	if len(overrides) == 0 {
+		return
+	}
+
+	channels := snapshot.Channels.Result[:0]
+	for _, channel := range snapshot.Channels.Result {
+		override, ok := overrides[channel.ID]
+		if !ok {
+			channels = append(channels, channel)
+			continue
+		}
+		if !override.Hidden {
+			channels = append(channels, override.apply(channel))
+		}
+	}
+	snapshot.Channels.Result = channels
+	// Responses change with the overrides as well
+	snapshot.ETag += "-" + overridesETag
+	if overridesModTime.After(snapshot.ModifiedAt) {
+		snapshot.ModifiedAt = overridesModTime
+	}
+}
+
+// reloadOverrides reads the overrides file again if it was edited since it was last read
+func reloadOverrides() {
+	filename := utils.GetPathPrefix() + OVERRIDES_FILE
+	var modTime time.Time
+	stat, err := os.Stat(filename)
+	if err == nil {
+		modTime = stat.ModTime()
+	}
+
+	overridesMu.RLock()
+	loaded := overrides != nil && modTime.Equal(overridesModTime)
+	overridesMu.RUnlock()
+	if loaded {
+		return
+	}
+
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	result := make(map[string]Override)
+	if err == nil {
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			err = json.Unmarshal(data, &result)
+		}
+		if err != nil {
+			utils.Log.Println("Failed to read channel overrides:", err)
+			// Keep the overrides read last until the file is fixed
+			if overrides != nil {
+				overridesModTime = modTime
+				return
+			}
+			result = make(map[string]Override)
+		}
+	}
+	setOverrides(result, modTime)
+}
+
+// setOverrides replaces the overrides. overridesMu must be held.
+func setOverrides(result map[string]Override, modTime time.Time) {
+	overrides = result
+	overridesModTime = modTime
+	// Map keys are marshalled in order, so equal overrides hash the same
+	data, _ := json.Marshal(result)
+	overridesETag = hashBytes(data)
+}
+
+// saveOverrides writes the overrides to disk. overridesMu must be held.
+func saveOverrides() error {
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := utils.GetPathPrefix() + OVERRIDES_FILE
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(filename+".tmp", filename); err != nil {
+		return err
+	}
+	modTime := time.Now()
+	if stat, err := os.Stat(filename); err == nil {
+		modTime = stat.ModTime()
+	}
+	setOverrides(overrides, modTime)
+	return nil
+}
@@ -0,0 +1,59 @@
+package catalogue
+
+import (
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
+// Snapshot is a copy of the channel list from JioTV at a point in time
+type Snapshot struct {
+	Channels television.ChannelsResponse `json:"channels"`
+	// FetchedAt is when the channel list was last fetched successfully
+	FetchedAt time.Time `json:"fetchedAt"`
+	// ModifiedAt is when the channel list last changed
+	ModifiedAt time.Time `json:"modifiedAt"`
+	// ETag is a hash of the channel list
+	ETag string `json:"etag"`
+}
+
+// Change describes how a channel changed between two channel lists
+type Change struct {
+	Type      string `json:"type"`
+	ChannelID string `json:"channelId"`
+	Name      string `json:"name"`
+	// OldName is the previous name of a renamed channel
+	OldName string `json:"oldName,omitempty"`
+	LogoURL string `json:"logoUrl,omitempty"`
+	// OldLogoURL is the previous logo of a channel whose logo changed
+	OldLogoURL string    `json:"oldLogoUrl,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// WebhookPayload is the request body posted to the channel changes webhook
+type WebhookPayload struct {
+	Changes []Change `json:"changes"`
+}
+
+// Override is a user edit of a channel. Empty fields keep the values from JioTV.
+type Override struct {
+	Name    string `json:"name,omitempty"`
+	LogoURL string `json:"logoUrl,omitempty"`
+	Number  int    `json:"number,omitempty"`
+	Group   string `json:"group,omitempty"`
+	Hidden  bool   `json:"hidden,omitempty"`
+}
+
+// Numbering holds the assigned channel numbers
+type Numbering struct {
+	// Numbers are the channel numbers by channel ID
+	Numbers map[string]int `json:"numbers"`
+	// Blocks are the number ranges of categories by category ID
+	Blocks map[string]NumberBlock `json:"blocks"`
+}
+
+// NumberBlock is a range of channel numbers reserved for a category
+type NumberBlock struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
@@ -10,6 +10,7 @@ import (
 
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Varun03-max/JIO/pkg/scheduler"
@@ -29,8 +30,35 @@ const (
 	EPG_TASK_ID = "jiotv_epg"
 )
 
+var (
+	generateHooks   []func()
+	generateHooksMu sync.Mutex
+	started         atomic.Bool
+)
+
+// OnGenerate registers hook to be called after every EPG generation
+func OnGenerate(hook func()) {
+	generateHooksMu.Lock()
+	defer generateHooksMu.Unlock()
+	generateHooks = append(generateHooks, hook)
+}
+
+// runGenerateHooks calls the hooks registered with OnGenerate
+func runGenerateHooks() {
+	generateHooksMu.Lock()
+	hooks := append([]func(){}, generateHooks...)
+	generateHooksMu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
 // Init initializes EPG generation and schedules it for the next day.
+// Only the first call does so, later calls return right away.
 func Init() {
+	if !started.CompareAndSwap(false, true) {
+		return
+	}
 	epgFile := utils.GetPathPrefix() + "epg.xml.gz"
 	var lastModTime time.Time
 	flag := false
@@ -58,6 +86,7 @@ func Init() {
 		if err != nil {
 			utils.Log.Fatal(err)
 		}
+		runGenerateHooks()
 		return err
 	}
 
@@ -220,7 +249,7 @@ func genXML() ([]byte, error) {
 
 // formatTime formats the given time to the string representation "20060102150405 -0700".
 func formatTime(t time.Time) string {
-	return t.Format("20060102150405 -0700")
+	return t.Format(TIME_FORMAT)
 }
 
 // GenXMLGz generates XML EPG from JioTV API and writes it to a compressed gzip file.
@@ -231,9 +260,7 @@ func GenXMLGz(filename string) error {
 		return err
 	}
 	// Add XML header
-	xmlHeader := `<?xml version="1.0" encoding="UTF-8"?>
-	<!DOCTYPE tv SYSTEM "http://www.w3.org/2006/05/tv">`
-	xml = append([]byte(xmlHeader), xml...)
+	xml = append([]byte(XML_HEADER), xml...)
 	// write to file
 	f, err := os.Create(filename)
 	if err != nil {
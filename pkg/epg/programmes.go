@@ -0,0 +1,209 @@
+package epg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/utils"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// TIME_FORMAT is the layout of programme start and stop times in the EPG
+	TIME_FORMAT = "20060102150405 -0700"
+	// XML_HEADER starts the EPG files
+	XML_HEADER = `<?xml version="1.0" encoding="UTF-8"?>
+	<!DOCTYPE tv SYSTEM "http://www.w3.org/2006/05/tv">`
+)
+
+// Errors
+var (
+	ErrProgrammeNotFound = errors.New("programme not found in EPG")
+)
+
+var (
+	loaded      *EPG
+	loadedMtime time.Time
+	loadMu      sync.Mutex
+)
+
+// ParseTime parses a programme start or stop time
+func ParseTime(value string) (time.Time, error) {
+	return time.Parse(TIME_FORMAT, value)
+}
+
+// StartTime returns the parsed start time of the programme
+func (p Programme) StartTime() (time.Time, error) {
+	return ParseTime(p.Start)
+}
+
+// StopTime returns the parsed stop time of the programme
+func (p Programme) StopTime() (time.Time, error) {
+	return ParseTime(p.Stop)
+}
+
+// ReadXMLGz reads an EPG written by GenXMLGz
+func ReadXMLGz(filename string) (*EPG, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var epg EPG
+	if err := xml.NewDecoder(gz).Decode(&epg); err != nil {
+		return nil, err
+	}
+	return &epg, nil
+}
+
+// Load returns the generated EPG.
+// The file is only read again after it was regenerated.
+func Load() (*EPG, error) {
+	epgFile := utils.GetPathPrefix() + "epg.xml.gz"
+	stat, err := os.Stat(epgFile)
+	if err != nil {
+		return nil, err
+	}
+
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	if loaded != nil && stat.ModTime().Equal(loadedMtime) {
+		return loaded, nil
+	}
+	epg, err := ReadXMLGz(epgFile)
+	if err != nil {
+		return nil, err
+	}
+	loaded = epg
+	loadedMtime = stat.ModTime()
+	return loaded, nil
+}
+
+// ChannelProgrammes returns the programmes of a channel in the generated EPG
+func ChannelProgrammes(channelID string) ([]Programme, error) {
+	epg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var programmes []Programme
+	for _, programme := range epg.Programme {
+		if programme.Channel == channelID {
+			programmes = append(programmes, programme)
+		}
+	}
+	return programmes, nil
+}
+
+// FindProgramme returns the programme of a channel that is on air at the given time
+func FindProgramme(channelID string, at time.Time) (*Programme, error) {
+	programmes, err := ChannelProgrammes(channelID)
+	if err != nil {
+		return nil, err
+	}
+	for _, programme := range programmes {
+		start, err := programme.StartTime()
+		if err != nil {
+			continue
+		}
+		stop, err := programme.StopTime()
+		if err != nil {
+			continue
+		}
+		if !at.Before(start) && at.Before(stop) {
+			return &programme, nil
+		}
+	}
+	return nil, ErrProgrammeNotFound
+}
+
+// Filter returns a copy of the EPG with every channel passed through fn.
+// Channels for which fn returns false are dropped together with their programmes.
+func (e *EPG) Filter(fn func(channel Channel) (Channel, bool)) *EPG {
+	filtered := &EPG{XMLVersion: e.XMLVersion, XMLEncoding: e.XMLEncoding}
+	kept := make(map[string]bool, len(e.Channel))
+	for _, channel := range e.Channel {
+		if channel, ok := fn(channel); ok {
+			filtered.Channel = append(filtered.Channel, channel)
+			kept[strconv.Itoa(channel.ID)] = true
+		}
+	}
+	for _, programme := range e.Programme {
+		if kept[programme.Channel] {
+			filtered.Programme = append(filtered.Programme, programme)
+		}
+	}
+	return filtered
+}
+
+// Gzip returns the EPG as a gzip compressed XML document, like the files written by GenXMLGz
+func (e *EPG) Gzip() ([]byte, error) {
+	data, err := xml.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(XML_HEADER)); err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ChannelName returns the display name of a channel in the generated EPG
+func ChannelName(channelID string) string {
+	epg, err := Load()
+	if err != nil {
+		return ""
+	}
+	for _, channel := range epg.Channel {
+		if strconv.Itoa(channel.ID) == channelID {
+			return channel.Display
+		}
+	}
+	return ""
+}
+
+// FetchChannelEPG fetches the EPG of a channel from JioTV API.
+// offset selects the day relative to today, negative offsets return past days.
+func FetchChannelEPG(client *fasthttp.Client, channelID, offset int) (*EPGResponse, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(fmt.Sprintf(EPG_URL, offset, channelID))
+	req.Header.SetUserAgent("okhttp/4.2.2")
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := client.Do(req, resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, fmt.Errorf("fetching EPG of channel %d failed with status code: %d", channelID, resp.StatusCode())
+	}
+
+	var epgResponse EPGResponse
+	if err := json.Unmarshal(resp.Body(), &epgResponse); err != nil {
+		return nil, err
+	}
+	return &epgResponse, nil
+}
@@ -0,0 +1,134 @@
+package hdhomerun
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
+const (
+	MANUFACTURER        = "Silicondust"
+	MODEL_NUMBER        = "HDTC-2US"
+	FIRMWARE_NAME       = "hdhomeruntc_atsc"
+	FIRMWARE_VERSION    = "20150826"
+	DEVICE_TYPE         = "urn:schemas-upnp-org:device:MediaServer:1"
+	DEFAULT_TUNER_COUNT = 4
+)
+
+var (
+	// DeviceID is the HDHomeRun device ID of the emulated tuner
+	DeviceID string
+	// FriendlyName is the name media servers show for the tuner
+	FriendlyName string
+	// TunerCount is the number of streams media servers may open at once
+	TunerCount int
+)
+
+// checksumTable is used by HDHomeRun clients to validate device IDs
+var checksumTable = [16]uint32{0xA, 0x5, 0xF, 0x6, 0x7, 0xC, 0x1, 0xB, 0x9, 0x2, 0x8, 0xD, 0x4, 0x3, 0xE, 0x0}
+
+// Init sets up the emulated tuner. It is called once at startup, before the tuner is served or discovered.
+// seed is hashed into the device ID so that it stays the same across restarts.
+func Init(seed, friendlyName string, tunerCount int) {
+	if tunerCount <= 0 {
+		tunerCount = DEFAULT_TUNER_COUNT
+	}
+	DeviceID = NewDeviceID(seed)
+	FriendlyName = friendlyName
+	TunerCount = tunerCount
+}
+
+// NewDeviceID derives a device ID from seed, with a valid HDHomeRun checksum
+func NewDeviceID(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	// The last digit is the checksum
+	id := binary.BigEndian.Uint32(sum[:4]) &^ 0xF
+	id |= checksum(id)
+	return fmt.Sprintf("%08X", id)
+}
+
+// checksum returns the digit that makes the checksum of id zero, ignoring the last digit of id
+func checksum(id uint32) uint32 {
+	var sum uint32
+	sum ^= checksumTable[(id>>28)&0xF]
+	sum ^= (id >> 24) & 0xF
+	sum ^= checksumTable[(id>>20)&0xF]
+	sum ^= (id >> 16) & 0xF
+	sum ^= checksumTable[(id>>12)&0xF]
+	sum ^= (id >> 8) & 0xF
+	sum ^= checksumTable[(id>>4)&0xF]
+	return sum
+}
+
+// NewDiscover returns the device details for a tuner reachable at baseURL
+func NewDiscover(baseURL string) Discover {
+	return Discover{
+		FriendlyName:    FriendlyName,
+		Manufacturer:    MANUFACTURER,
+		ModelNumber:     MODEL_NUMBER,
+		FirmwareName:    FIRMWARE_NAME,
+		FirmwareVersion: FIRMWARE_VERSION,
+		DeviceID:        DeviceID,
+		DeviceAuth:      DeviceID,
+		BaseURL:         baseURL,
+		LineupURL:       baseURL + "/lineup.json",
+		TunerCount:      TunerCount,
+	}
+}
+
+// NewLineupStatus returns a lineup status with no channel scan running
+func NewLineupStatus() LineupStatus {
+	return LineupStatus{
+		ScanInProgress: 0,
+		ScanPossible:   1,
+		Source:         "Cable",
+		SourceList:     []string{"Cable"},
+	}
+}
+
+// NewLineup returns the lineup of channels.
+// streamURL returns the stream URL of a channel.
+func NewLineup(channels []television.Channel, streamURL func(channel television.Channel) string) []LineupItem {
+	lineup := make([]LineupItem, 0, len(channels))
+	for _, channel := range channels {
+		item := LineupItem{
+			GuideNumber: channel.ID,
+			GuideName:   channel.Name,
+			URL:         streamURL(channel),
+		}
+		if channel.Number > 0 {
+			item.GuideNumber = strconv.Itoa(channel.Number)
+		}
+		if channel.IsHD {
+			item.HD = 1
+		}
+		lineup = append(lineup, item)
+	}
+	return lineup
+}
+
+// DeviceXML returns the UPnP device description of a tuner reachable at baseURL
+func DeviceXML(baseURL string) ([]byte, error) {
+	root := Root{
+		SpecVersion: SpecVersion{Major: 1, Minor: 0},
+		URLBase:     baseURL,
+		Device: Device{
+			DeviceType:   DEVICE_TYPE,
+			FriendlyName: FriendlyName,
+			Manufacturer: MANUFACTURER,
+			ModelName:    MODEL_NUMBER,
+			ModelNumber:  MODEL_NUMBER,
+			SerialNumber: DeviceID,
+			UDN:          "uuid:" + DeviceID,
+		},
+	}
+	body, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,105 @@
+package hdhomerun
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
+// validDeviceID checks a device ID the way HDHomeRun clients do, every digit is folded into the checksum
+func validDeviceID(t *testing.T, deviceID string) bool {
+	t.Helper()
+	id, err := strconv.ParseUint(deviceID, 16, 32)
+	if err != nil || len(deviceID) != 8 {
+		t.Fatalf("device ID %q is not 8 hex digits", deviceID)
+	}
+	var sum uint64
+	for shift := 28; shift >= 0; shift -= 4 {
+		digit := (id >> shift) & 0xF
+		if shift%8 == 4 {
+			digit = uint64(checksumTable[digit])
+		}
+		sum ^= digit
+	}
+	return sum == 0
+}
+
+func TestDeviceID(t *testing.T) {
+	for _, id := range []string{"1000000F", "00000000"} {
+		if !validDeviceID(t, id) {
+			t.Errorf("reference device ID %s is invalid", id)
+		}
+	}
+	if validDeviceID(t, "10000000") {
+		t.Error("device ID 10000000 with wrong checksum is valid")
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seed := "device" + strconv.Itoa(i)
+		id := NewDeviceID(seed)
+		if !validDeviceID(t, id) {
+			t.Errorf("NewDeviceID(%q) = %s, invalid checksum", seed, id)
+		}
+		if NewDeviceID(seed) != id {
+			t.Errorf("NewDeviceID(%q) changed", seed)
+		}
+		seen[id] = true
+	}
+	if len(seen) < 99 {
+		t.Errorf("%d distinct device IDs for 100 seeds", len(seen))
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	Init("seed", "JioTV Go", 0)
+	if TunerCount != DEFAULT_TUNER_COUNT {
+		t.Errorf("TunerCount = %d, want %d", TunerCount, DEFAULT_TUNER_COUNT)
+	}
+	Init("seed", "JioTV Go", 2)
+
+	body, err := json.Marshal(NewDiscover("http://192.168.1.10:5001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"FriendlyName":"JioTV Go","Manufacturer":"Silicondust","ModelNumber":"HDTC-2US",` +
+		`"FirmwareName":"hdhomeruntc_atsc","FirmwareVersion":"20150826","DeviceID":"` + DeviceID + `","DeviceAuth":"` + DeviceID + `",` +
+		`"BaseURL":"http://192.168.1.10:5001","LineupURL":"http://192.168.1.10:5001/lineup.json","TunerCount":2}`
+	if string(body) != want {
+		t.Errorf("discover.json =\n%s\nwant\n%s", body, want)
+	}
+
+	body, err = json.Marshal(NewLineupStatus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"ScanInProgress":0,"ScanPossible":1,"Source":"Cable","SourceList":["Cable"]}`; string(body) != want {
+		t.Errorf("lineup_status.json = %s, want %s", body, want)
+	}
+}
+
+func TestLineup(t *testing.T) {
+	channels := []television.Channel{
+		{ID: "143", Name: "Colors HD", IsHD: true, Number: 101},
+		{ID: "144", Name: "Colors"},
+	}
+	lineup := NewLineup(channels, func(channel television.Channel) string {
+		return "http://192.168.1.10:5001/stream/" + channel.ID + ".ts"
+	})
+	body, err := json.Marshal(lineup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Channels without a number are listed under their ID
+	want := `[{"GuideNumber":"101","GuideName":"Colors HD","HD":1,"URL":"http://192.168.1.10:5001/stream/143.ts"},` +
+		`{"GuideNumber":"144","GuideName":"Colors","URL":"http://192.168.1.10:5001/stream/144.ts"}]`
+	if string(body) != want {
+		t.Errorf("lineup.json =\n%s\nwant\n%s", body, want)
+	}
+
+	if body, _ := json.Marshal(NewLineup(nil, nil)); string(body) != "[]" {
+		t.Errorf("empty lineup.json = %s, want []", body)
+	}
+}
@@ -0,0 +1,102 @@
+package hdhomerun
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	SSDP_ADDRESS = "239.255.255.250:1900"
+	SSDP_MAX_AGE = 1800
+)
+
+// searchTargets are the SSDP search targets the tuner answers to
+func searchTargets() []string {
+	return []string{"ssdp:all", "upnp:rootdevice", DEVICE_TYPE, "uuid:" + DeviceID}
+}
+
+// ListenSSDP answers SSDP discovery requests on the LAN.
+// port is the port of the HTTP server serving `/device.xml`. It blocks until the socket fails.
+func ListenSSDP(port string) error {
+	addr, err := net.ResolveUDPAddr("udp4", SSDP_ADDRESS)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	utils.Log.Println("Answering HDHomeRun SSDP discovery on", SSDP_ADDRESS)
+
+	buf := make([]byte, 2048)
+	for {
+		n, remote, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return err
+		}
+		target, ok := parseSearch(buf[:n])
+		if !ok {
+			continue
+		}
+		response, err := searchResponse(target, remote, port)
+		if err != nil {
+			utils.Log.Println("SSDP:", err)
+			continue
+		}
+		if _, err := conn.WriteToUDP(response, remote); err != nil {
+			utils.Log.Println("SSDP:", err)
+		}
+	}
+}
+
+// parseSearch returns the search target of an M-SEARCH request the tuner should answer
+func parseSearch(packet []byte) (string, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(packet)))
+	if err != nil || req.Method != "M-SEARCH" {
+		return "", false
+	}
+	target := req.Header.Get("ST")
+	for _, st := range searchTargets() {
+		if strings.EqualFold(target, st) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// searchResponse builds the reply to a search from remote
+func searchResponse(target string, remote *net.UDPAddr, port string) ([]byte, error) {
+	ip, err := localIP(remote)
+	if err != nil {
+		return nil, err
+	}
+	usn := "uuid:" + DeviceID
+	if target != usn {
+		usn += "::" + target
+	}
+	return []byte(fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+		"CACHE-CONTROL: max-age=%d\r\n"+
+		"EXT:\r\n"+
+		"LOCATION: http://%s/device.xml\r\n"+
+		"SERVER: JioTV Go UPnP/1.0\r\n"+
+		"ST: %s\r\n"+
+		"USN: %s\r\n"+
+		"\r\n", SSDP_MAX_AGE, net.JoinHostPort(ip.String(), port), target, usn)), nil
+}
+
+// localIP returns the address of the interface that reaches remote
+func localIP(remote *net.UDPAddr) (net.IP, error) {
+	conn, err := net.DialUDP("udp4", nil, remote)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
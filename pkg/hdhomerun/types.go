@@ -0,0 +1,58 @@
+package hdhomerun
+
+import "encoding/xml"
+
+// Discover is the response body of `/discover.json`
+type Discover struct {
+	FriendlyName    string `json:"FriendlyName"`
+	Manufacturer    string `json:"Manufacturer"`
+	ModelNumber     string `json:"ModelNumber"`
+	FirmwareName    string `json:"FirmwareName"`
+	FirmwareVersion string `json:"FirmwareVersion"`
+	DeviceID        string `json:"DeviceID"`
+	DeviceAuth      string `json:"DeviceAuth"`
+	BaseURL         string `json:"BaseURL"`
+	LineupURL       string `json:"LineupURL"`
+	TunerCount      int    `json:"TunerCount"`
+}
+
+// LineupStatus is the response body of `/lineup_status.json`
+type LineupStatus struct {
+	ScanInProgress int      `json:"ScanInProgress"`
+	ScanPossible   int      `json:"ScanPossible"`
+	Source         string   `json:"Source"`
+	SourceList     []string `json:"SourceList"`
+}
+
+// LineupItem is a single channel of `/lineup.json`
+type LineupItem struct {
+	GuideNumber string `json:"GuideNumber"`
+	GuideName   string `json:"GuideName"`
+	HD          int    `json:"HD,omitempty"`
+	URL         string `json:"URL"`
+}
+
+// Root is the UPnP device description served at `/device.xml`
+type Root struct {
+	XMLName     xml.Name    `xml:"urn:schemas-upnp-org:device-1-0 root"`
+	SpecVersion SpecVersion `xml:"specVersion"`
+	URLBase     string      `xml:"URLBase"`
+	Device      Device      `xml:"device"`
+}
+
+// SpecVersion is the UPnP version the device description follows
+type SpecVersion struct {
+	Major int `xml:"major"`
+	Minor int `xml:"minor"`
+}
+
+// Device describes the emulated tuner in the UPnP device description
+type Device struct {
+	DeviceType   string `xml:"deviceType"`
+	FriendlyName string `xml:"friendlyName"`
+	Manufacturer string `xml:"manufacturer"`
+	ModelName    string `xml:"modelName"`
+	ModelNumber  string `xml:"modelNumber"`
+	SerialNumber string `xml:"serialNumber"`
+	UDN          string `xml:"UDN"`
+}
@@ -0,0 +1,182 @@
+package hls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// LIVE_EDGE_SEGMENTS is the number of segments handed out on the first poll of a live playlist
+	LIVE_EDGE_SEGMENTS = 3
+	// DEFAULT_POLL_INTERVAL is used while the target duration of the playlist is unknown
+	DEFAULT_POLL_INTERVAL = 2 * time.Second
+)
+
+// Errors
+var (
+	ErrNotMediaPlaylist = errors.New("not a media playlist")
+)
+
+// Follower polls a live media playlist and returns every segment exactly once
+type Follower struct {
+	url          string
+	fetch        func(url string) ([]byte, error)
+	lastSequence int64
+	started      bool
+	playlist     *Playlist
+}
+
+// NewFollower creates a Follower for the media playlist at playlistURL.
+// fetch is used to download the playlist on every poll.
+func NewFollower(playlistURL string, fetch func(url string) ([]byte, error)) *Follower {
+	return &Follower{
+		url:   playlistURL,
+		fetch: fetch,
+	}
+}
+
+// SetURL replaces the playlist URL, for example after its tokens expired.
+// Segments already returned are not returned again.
+func (f *Follower) SetURL(playlistURL string) {
+	f.url = playlistURL
+}
+
+// URL returns the playlist URL being followed
+func (f *Follower) URL() string {
+	return f.url
+}
+
+// Playlist returns the playlist fetched by the last successful poll
+func (f *Follower) Playlist() *Playlist {
+	return f.playlist
+}
+
+// Interval returns how long to wait before the next poll
+func (f *Follower) Interval() time.Duration {
+	if f.playlist == nil || f.playlist.TargetDuration <= 0 {
+		return DEFAULT_POLL_INTERVAL
+	}
+	return time.Duration(f.playlist.TargetDuration * float64(time.Second) / 2)
+}
+
+// Poll fetches the playlist and returns the segments that were not returned before.
+// The first poll of a live playlist starts close to the live edge.
+// URIs of the returned segments, their keys and maps are absolute.
+func (f *Follower) Poll() ([]Segment, error) {
+	data, err := f.fetch(f.url)
+	if err != nil {
+		return nil, err
+	}
+	playlist, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if playlist.Type != Media {
+		return nil, ErrNotMediaPlaylist
+	}
+	f.playlist = playlist
+	if len(playlist.Segments) == 0 {
+		return nil, nil
+	}
+
+	segments := playlist.Segments
+	lastSequence := segments[len(segments)-1].Sequence
+	switch {
+	case !f.started:
+		if !playlist.EndList && len(segments) > LIVE_EDGE_SEGMENTS {
+			segments = segments[len(segments)-LIVE_EDGE_SEGMENTS:]
+		}
+	case lastSequence < f.lastSequence:
+		// The stream restarted with new sequence numbers
+		if len(segments) > LIVE_EDGE_SEGMENTS {
+			segments = segments[len(segments)-LIVE_EDGE_SEGMENTS:]
+		}
+		segments[0].Discontinuity = true
+	default:
+		var newSegments []Segment
+		for _, segment := range segments {
+			if segment.Sequence > f.lastSequence {
+				newSegments = append(newSegments, segment)
+			}
+		}
+		segments = newSegments
+	}
+	f.started = true
+	f.lastSequence = lastSequence
+
+	base, err := url.Parse(f.url)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]Segment, 0, len(segments))
+	for _, segment := range segments {
+		if segment.URI, err = ResolveURI(base, segment.URI); err != nil {
+			return nil, err
+		}
+		if segment.Key != nil && segment.Key.URI != "" {
+			key := *segment.Key
+			if key.URI, err = ResolveURI(base, key.URI); err != nil {
+				return nil, err
+			}
+			segment.Key = &key
+		}
+		if segment.Map != nil {
+			initMap := *segment.Map
+			if initMap.URI, err = ResolveURI(base, initMap.URI); err != nil {
+				return nil, err
+			}
+			segment.Map = &initMap
+		}
+		resolved = append(resolved, segment)
+	}
+	return resolved, nil
+}
+
+// Decrypt decrypts an AES-128 encrypted segment with the given key.
+// The IV is taken from the key tag or derived from the media sequence number as the HLS spec requires.
+func Decrypt(data, key []byte, segment Segment) ([]byte, error) {
+	if segment.Key == nil || segment.Key.Method == "" || segment.Key.Method == "NONE" {
+		return data, nil
+	}
+	if segment.Key.Method != "AES-128" {
+		return nil, fmt.Errorf("unsupported encryption method %s", segment.Key.Method)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted segment is not a multiple of the block size")
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if segment.Key.IV != "" {
+		ivHex := strings.TrimPrefix(strings.TrimPrefix(segment.Key.IV, "0x"), "0X")
+		decoded, err := hex.DecodeString(fmt.Sprintf("%032s", ivHex))
+		if err != nil || len(decoded) != aes.BlockSize {
+			return nil, fmt.Errorf("invalid IV %s", segment.Key.IV)
+		}
+		iv = decoded
+	} else {
+		for i := 0; i < 8; i++ {
+			iv[aes.BlockSize-1-i] = byte(segment.Sequence >> (8 * i))
+		}
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	// Remove PKCS#7 padding
+	if len(plain) > 0 {
+		padding := int(plain[len(plain)-1])
+		if padding > 0 && padding <= aes.BlockSize && padding <= len(plain) {
+			plain = plain[:len(plain)-padding]
+		}
+	}
+	return plain, nil
+}
@@ -0,0 +1,134 @@
+package hls
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// livePlaylist builds a live media playlist with count segments starting at sequence
+func livePlaylist(sequence, count int) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:%d\n", sequence)
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, "#EXTINF:6.000,\nseg-%d.ts\n", sequence+i)
+	}
+	return []byte(b.String())
+}
+
+func TestFollowerPoll(t *testing.T) {
+	var playlist []byte
+	follower := NewFollower(testBaseURL, func(string) ([]byte, error) {
+		return playlist, nil
+	})
+
+	tests := []struct {
+		name          string
+		playlist      []byte
+		want          []int64
+		discontinuity bool
+	}{
+		{name: "starts at live edge", playlist: livePlaylist(100, 6), want: []int64{103, 104, 105}},
+		{name: "no new segments", playlist: livePlaylist(100, 6), want: nil},
+		{name: "window moved", playlist: livePlaylist(102, 6), want: []int64{106, 107}},
+		{name: "stream restarted", playlist: livePlaylist(1, 5), want: []int64{3, 4, 5}, discontinuity: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playlist = tt.playlist
+			segments, err := follower.Poll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int64
+			for _, segment := range segments {
+				got = append(got, segment.Sequence)
+				if !strings.HasPrefix(segment.URI, "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/output/seg-") ||
+					!strings.Contains(segment.URI, "hdnea=") {
+					t.Errorf("segment URI %q is not resolved against the playlist", segment.URI)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("sequences = %v, want %v", got, tt.want)
+			}
+			if tt.discontinuity && !segments[0].Discontinuity {
+				t.Error("first segment after a restart is not marked as discontinuity")
+			}
+		})
+	}
+
+	if got := follower.Interval().Seconds(); got != 3 {
+		t.Errorf("Interval() = %vs, want 3s", got)
+	}
+}
+
+func TestFollowerMasterPlaylist(t *testing.T) {
+	follower := NewFollower(testBaseURL, func(string) ([]byte, error) {
+		return readFixture(t, "master.m3u8"), nil
+	})
+	if _, err := follower.Poll(); err != ErrNotMediaPlaylist {
+		t.Errorf("Poll() error = %v, want %v", err, ErrNotMediaPlaylist)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	plain := []byte("a transport stream segment")
+
+	encrypt := func(iv []byte) []byte {
+		padding := aes.BlockSize - len(plain)%aes.BlockSize
+		padded := append(append([]byte(nil), plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+		return out
+	}
+
+	sequenceIV := make([]byte, aes.BlockSize)
+	sequenceIV[aes.BlockSize-1] = 0x3c
+	sequenceIV[aes.BlockSize-2] = 0x58
+	sequenceIV[aes.BlockSize-3] = 0x04
+
+	tests := []struct {
+		name    string
+		segment Segment
+		data    []byte
+	}{
+		{
+			name:    "explicit IV",
+			segment: Segment{Key: &Key{Method: "AES-128", IV: "0x0000000000000000000000000004583C"}},
+			data:    encrypt(sequenceIV),
+		},
+		{
+			name:    "IV from sequence",
+			segment: Segment{Sequence: 284732, Key: &Key{Method: "AES-128"}},
+			data:    encrypt(sequenceIV),
+		},
+		{
+			name:    "not encrypted",
+			segment: Segment{Key: &Key{Method: "NONE"}},
+			data:    plain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.data, key, tt.segment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("Decrypt() = %q, want %q", got, plain)
+			}
+		})
+	}
+
+	if _, err := Decrypt([]byte("short"), key, Segment{Key: &Key{Method: "AES-128"}}); err == nil {
+		t.Error("Decrypt() of data not aligned to the block size succeeded")
+	}
+}
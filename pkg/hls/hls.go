@@ -0,0 +1,373 @@
+package hls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Header is the mandatory first line of every HLS playlist
+	Header = "#EXTM3U"
+)
+
+// Errors
+var (
+	ErrInvalidPlaylist = errors.New("invalid playlist: missing " + Header + " header")
+)
+
+// uriTags maps tags carrying a URI attribute to the kind of resource the URI points to
+var uriTags = map[string]URIKind{
+	"#EXT-X-KEY":                KindKey,
+	"#EXT-X-SESSION-KEY":        KindKey,
+	"#EXT-X-MAP":                KindInit,
+	"#EXT-X-MEDIA":              KindPlaylist,
+	"#EXT-X-I-FRAME-STREAM-INF": KindPlaylist,
+	"#EXT-X-RENDITION-REPORT":   KindPlaylist,
+	"#EXT-X-PART":               KindSegment,
+	"#EXT-X-PRELOAD-HINT":       KindSegment,
+}
+
+// Parse parses a master or media playlist
+func Parse(data []byte) (*Playlist, error) {
+	lines := splitLines(data)
+	if len(lines) == 0 || lines[0] != Header {
+		return nil, ErrInvalidPlaylist
+	}
+
+	playlist := &Playlist{Type: Media}
+	var (
+		segment   Segment
+		streamInf *Variant
+		key       *Key
+		initMap   *Map
+	)
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			// A URI line belongs either to the preceding EXT-X-STREAM-INF or to the preceding EXTINF
+			if streamInf != nil {
+				streamInf.URI = line
+				playlist.Variants = append(playlist.Variants, *streamInf)
+				streamInf = nil
+				continue
+			}
+			segment.URI = line
+			segment.Key = key
+			segment.Map = initMap
+			playlist.Segments = append(playlist.Segments, segment)
+			segment = Segment{}
+			continue
+		}
+
+		tag, value := splitTag(line)
+		var err error
+		switch tag {
+		case "#EXT-X-VERSION":
+			playlist.Version, err = strconv.Atoi(value)
+		case "#EXT-X-TARGETDURATION":
+			playlist.TargetDuration, err = strconv.ParseFloat(value, 64)
+		case "#EXT-X-MEDIA-SEQUENCE":
+			playlist.MediaSequence, err = strconv.ParseInt(value, 10, 64)
+		case "#EXT-X-DISCONTINUITY-SEQUENCE":
+			playlist.DiscontinuitySequence, err = strconv.ParseInt(value, 10, 64)
+		case "#EXT-X-PLAYLIST-TYPE":
+			playlist.PlaylistType = value
+		case "#EXT-X-ENDLIST":
+			playlist.EndList = true
+		case "#EXTINF":
+			duration, title, _ := strings.Cut(value, ",")
+			segment.Title = title
+			segment.Duration, err = strconv.ParseFloat(strings.TrimSpace(duration), 64)
+		case "#EXT-X-BYTERANGE":
+			segment.ByteRange, err = parseByteRange(value)
+		case "#EXT-X-DISCONTINUITY":
+			segment.Discontinuity = true
+		case "#EXT-X-PROGRAM-DATE-TIME":
+			segment.ProgramDateTime = value
+		case "#EXT-X-KEY":
+			attrs := ParseAttributes(value)
+			if attrs["METHOD"] == "NONE" {
+				key = nil
+			} else {
+				key = &Key{
+					Method:    attrs["METHOD"],
+					URI:       attrs["URI"],
+					IV:        attrs["IV"],
+					KeyFormat: attrs["KEYFORMAT"],
+				}
+			}
+		case "#EXT-X-MAP":
+			attrs := ParseAttributes(value)
+			initMap = &Map{URI: attrs["URI"]}
+			if byteRange, ok := attrs["BYTERANGE"]; ok {
+				initMap.ByteRange, err = parseByteRange(byteRange)
+			}
+		case "#EXT-X-STREAM-INF":
+			playlist.Type = Master
+			var variant Variant
+			variant, err = parseVariant(value)
+			streamInf = &variant
+		case "#EXT-X-I-FRAME-STREAM-INF":
+			playlist.Type = Master
+			var variant Variant
+			variant, err = parseVariant(value)
+			variant.IFrame = true
+			variant.URI = ParseAttributes(value)["URI"]
+			playlist.Variants = append(playlist.Variants, variant)
+		case "#EXT-X-MEDIA":
+			playlist.Type = Master
+			attrs := ParseAttributes(value)
+			playlist.Renditions = append(playlist.Renditions, Rendition{
+				Type:     attrs["TYPE"],
+				GroupID:  attrs["GROUP-ID"],
+				Name:     attrs["NAME"],
+				Language: attrs["LANGUAGE"],
+				URI:      attrs["URI"],
+				Default:  attrs["DEFAULT"] == "YES",
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag %q: %w", tag, line, err)
+		}
+	}
+
+	// Number segments and fill implied byte range offsets
+	var rangeEnd int64
+	for i := range playlist.Segments {
+		segment := &playlist.Segments[i]
+		segment.Sequence = playlist.MediaSequence + int64(i)
+		if segment.ByteRange != nil {
+			if !segment.ByteRange.HasOffset {
+				segment.ByteRange.Offset = rangeEnd
+			}
+			rangeEnd = segment.ByteRange.Offset + segment.ByteRange.Length
+		}
+	}
+	return playlist, nil
+}
+
+// Rewrite replaces every URI of the playlist with the value returned by rewrite.
+// URIs are resolved against baseURL before rewrite is called, both in URI lines and in
+// URI attributes of tags such as EXT-X-KEY, EXT-X-MAP and EXT-X-MEDIA.
+// All other lines are kept as they are.
+func Rewrite(data []byte, baseURL string, rewrite func(uri string, kind URIKind) string) ([]byte, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(data)
+	if len(lines) == 0 || lines[0] != Header {
+		return nil, ErrInvalidPlaylist
+	}
+
+	resolveAndRewrite := func(ref string, kind URIKind) string {
+		uri, err := ResolveURI(base, ref)
+		// Keys like skd:// are handled by the player itself
+		if err != nil || !(strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")) {
+			return ref
+		}
+		return rewrite(uri, kind)
+	}
+
+	var out bytes.Buffer
+	// URI lines are segments unless they follow EXT-X-STREAM-INF
+	nextKind := KindSegment
+	for i, line := range lines {
+		switch {
+		case line == "":
+		case !strings.HasPrefix(line, "#"):
+			line = resolveAndRewrite(line, nextKind)
+			nextKind = KindSegment
+		default:
+			tag, value := splitTag(line)
+			if tag == "#EXT-X-STREAM-INF" {
+				nextKind = KindPlaylist
+			}
+			if kind, ok := uriTags[tag]; ok {
+				if tag == "#EXT-X-PRELOAD-HINT" && ParseAttributes(value)["TYPE"] == "MAP" {
+					kind = KindInit
+				}
+				line = rewriteURIAttribute(line, func(ref string) string {
+					return resolveAndRewrite(ref, kind)
+				})
+			}
+		}
+		out.WriteString(line)
+		if i < len(lines)-1 || bytes.HasSuffix(data, []byte("\n")) {
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// ResolveURI resolves ref against the URL of the playlist it was found in.
+// Query parameters of base are carried over to ref when it has none of its own and is on the same domain,
+// as JioTV authorises child requests with the token parameters of the parent playlist.
+// Keys are served from another host than the playlists, tv.media.jio.com, but tokens are never sent to other domains.
+// References that do not resolve to an HTTP(S) URL, like skd:// keys, are returned as they are.
+func ResolveURI(base *url.URL, ref string) (string, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	resolved := base.ResolveReference(refURL)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ref, nil
+	}
+	if resolved.RawQuery == "" && sameDomain(resolved.Hostname(), base.Hostname()) {
+		resolved.RawQuery = base.RawQuery
+	}
+	return resolved.String(), nil
+}
+
+// sameDomain reports whether two hosts share their last two labels, like cdn.jio.com and tv.media.jio.com
+func sameDomain(a, b string) bool {
+	return a == b || domain(a) != "" && domain(a) == domain(b)
+}
+
+// domain returns the last two labels of host, empty for hosts with fewer labels
+func domain(host string) string {
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+// ParseAttributes parses an attribute list like `BANDWIDTH=1000,CODECS="avc1,mp4a"`.
+// Quotes are removed from quoted string values.
+func ParseAttributes(value string) map[string]string {
+	attrs := make(map[string]string)
+	for value != "" {
+		name, rest, found := strings.Cut(value, "=")
+		if !found {
+			break
+		}
+		name = strings.TrimSpace(name)
+		var attrValue string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				attrValue, rest = rest[1:], ""
+			} else {
+				attrValue, rest = rest[1:end+1], rest[end+2:]
+			}
+			_, rest, _ = strings.Cut(rest, ",")
+		} else {
+			attrValue, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[name] = attrValue
+		value = rest
+	}
+	return attrs
+}
+
+// rewriteURIAttribute replaces the value of the URI attribute of a tag line
+func rewriteURIAttribute(line string, rewrite func(ref string) string) string {
+	const attr = `URI="`
+	var out strings.Builder
+	for {
+		idx := strings.Index(line, attr)
+		if idx < 0 {
+			break
+		}
+		start := idx + len(attr)
+		end := strings.IndexByte(line[start:], '"')
+		if end < 0 {
+			break
+		}
+		end += start
+		out.WriteString(line[:start])
+		// Only a standalone URI attribute, not a suffix of another attribute name
+		if idx > 0 && (line[idx-1] == ':' || line[idx-1] == ',') {
+			out.WriteString(rewrite(line[start:end]))
+		} else {
+			out.WriteString(line[start:end])
+		}
+		out.WriteByte('"')
+		line = line[end+1:]
+	}
+	out.WriteString(line)
+	return out.String()
+}
+
+// parseVariant parses the attribute list of EXT-X-STREAM-INF and EXT-X-I-FRAME-STREAM-INF tags
+func parseVariant(value string) (Variant, error) {
+	attrs := ParseAttributes(value)
+	variant := Variant{
+		Codecs: attrs["CODECS"],
+		Audio:  attrs["AUDIO"],
+	}
+	var err error
+	if bandwidth, ok := attrs["BANDWIDTH"]; ok {
+		if variant.Bandwidth, err = strconv.ParseInt(bandwidth, 10, 64); err != nil {
+			return variant, err
+		}
+	}
+	if bandwidth, ok := attrs["AVERAGE-BANDWIDTH"]; ok {
+		if variant.AverageBandwidth, err = strconv.ParseInt(bandwidth, 10, 64); err != nil {
+			return variant, err
+		}
+	}
+	if resolution, ok := attrs["RESOLUTION"]; ok {
+		width, height, found := strings.Cut(strings.ToLower(resolution), "x")
+		if !found {
+			return variant, fmt.Errorf("invalid resolution %q", resolution)
+		}
+		if variant.Width, err = strconv.Atoi(width); err != nil {
+			return variant, err
+		}
+		if variant.Height, err = strconv.Atoi(height); err != nil {
+			return variant, err
+		}
+	}
+	if frameRate, ok := attrs["FRAME-RATE"]; ok {
+		if variant.FrameRate, err = strconv.ParseFloat(frameRate, 64); err != nil {
+			return variant, err
+		}
+	}
+	return variant, nil
+}
+
+// parseByteRange parses a byte range of the form <length>[@<offset>]
+func parseByteRange(value string) (*ByteRange, error) {
+	length, offset, hasOffset := strings.Cut(value, "@")
+	byteRange := &ByteRange{HasOffset: hasOffset}
+	var err error
+	if byteRange.Length, err = strconv.ParseInt(length, 10, 64); err != nil {
+		return nil, err
+	}
+	if hasOffset {
+		if byteRange.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return byteRange, nil
+}
+
+// splitTag splits a tag line into the tag name and its value
+func splitTag(line string) (string, string) {
+	tag, value, _ := strings.Cut(line, ":")
+	return tag, value
+}
+
+// splitLines splits a playlist into trimmed lines, dropping a leading byte order mark
+func splitLines(data []byte) []string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return lines
+}
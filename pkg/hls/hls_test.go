@@ -0,0 +1,278 @@
+package hls
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBaseURL = "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/output/index.m3u8?hdnea=st=1709290000~exp=1709300000~acl=/*~hmac=abc123"
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		fixture string
+		check   func(t *testing.T, p *Playlist)
+	}{
+		{
+			fixture: "master.m3u8",
+			check: func(t *testing.T, p *Playlist) {
+				if p.Type != Master {
+					t.Fatalf("Type = %v, want Master", p.Type)
+				}
+				if len(p.Variants) != 4 {
+					t.Fatalf("len(Variants) = %d, want 4", len(p.Variants))
+				}
+				want := Variant{
+					URI:              "Colors_HD_1200.m3u8",
+					Bandwidth:        4628000,
+					AverageBandwidth: 4206000,
+					Width:            1920,
+					Height:           1080,
+					Codecs:           "avc1.640028,mp4a.40.2",
+					FrameRate:        25,
+				}
+				if !reflect.DeepEqual(p.Variants[0], want) {
+					t.Errorf("Variants[0] = %+v, want %+v", p.Variants[0], want)
+				}
+				if audio := p.Variants[3]; audio.Width != 0 || audio.Codecs != "mp4a.40.2" {
+					t.Errorf("Variants[3] = %+v, want audio only variant", audio)
+				}
+			},
+		},
+		{
+			fixture: "master_renditions.m3u8",
+			check: func(t *testing.T, p *Playlist) {
+				if len(p.Renditions) != 2 {
+					t.Fatalf("len(Renditions) = %d, want 2", len(p.Renditions))
+				}
+				if r := p.Renditions[0]; r.URI != "audio/hin/index.m3u8" || !r.Default || r.Language != "hin" {
+					t.Errorf("Renditions[0] = %+v", r)
+				}
+				if len(p.Variants) != 3 {
+					t.Fatalf("len(Variants) = %d, want 3", len(p.Variants))
+				}
+				if v := p.Variants[2]; !v.IFrame || v.URI != "video/1080/iframes.m3u8" {
+					t.Errorf("Variants[2] = %+v, want I-frame variant", v)
+				}
+				if v := p.Variants[0]; v.Audio != "aac" {
+					t.Errorf("Variants[0].Audio = %q, want aac", v.Audio)
+				}
+			},
+		},
+		{
+			fixture: "media_ts.m3u8",
+			check: func(t *testing.T, p *Playlist) {
+				if p.Type != Media || p.TargetDuration != 6 || p.MediaSequence != 284712 {
+					t.Fatalf("unexpected header %+v", p)
+				}
+				if len(p.Segments) != 3 {
+					t.Fatalf("len(Segments) = %d, want 3", len(p.Segments))
+				}
+				first, last := p.Segments[0], p.Segments[2]
+				if first.Key == nil || first.Key.Method != "AES-128" {
+					t.Errorf("Segments[0].Key = %+v, want AES-128 key", first.Key)
+				}
+				if first.ProgramDateTime == "" {
+					t.Error("Segments[0].ProgramDateTime is empty")
+				}
+				if last.Sequence != 284714 || !last.Discontinuity || last.Key != nil || last.Title != "Promo" {
+					t.Errorf("Segments[2] = %+v", last)
+				}
+			},
+		},
+		{
+			fixture: "media_fmp4.m3u8",
+			check: func(t *testing.T, p *Playlist) {
+				if !p.EndList || p.PlaylistType != "EVENT" || p.Version != 7 {
+					t.Fatalf("unexpected header %+v", p)
+				}
+				if len(p.Segments) != 3 {
+					t.Fatalf("len(Segments) = %d, want 3", len(p.Segments))
+				}
+				initMap := p.Segments[0].Map
+				if initMap == nil || initMap.URI != "init.mp4" || initMap.ByteRange.Length != 812 {
+					t.Errorf("Segments[0].Map = %+v", initMap)
+				}
+				if br := p.Segments[1].ByteRange; br == nil || br.Offset != 204800+812 || br.Length != 198400 {
+					t.Errorf("Segments[1].ByteRange = %+v, want implied offset", br)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			p, err := Parse(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, p)
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "",
+		"no header":      "#EXTINF:6,\nsegment.ts\n",
+		"html error":     "<html><body>403 Forbidden</body></html>",
+		"bad extinf":     "#EXTM3U\n#EXTINF:abc,\nsegment.ts\n",
+		"bad resolution": "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1,RESOLUTION=big\nvariant.m3u8\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// markRewrite tags every rewritten URI with its kind so that tests can assert both
+func markRewrite(uri string, kind URIKind) string {
+	names := map[URIKind]string{KindPlaylist: "playlist", KindSegment: "segment", KindInit: "init", KindKey: "key"}
+	return "/" + names[kind] + "?u=" + uri
+}
+
+func TestRewrite(t *testing.T) {
+	const query = "?hdnea=st=1709290000~exp=1709300000~acl=/*~hmac=abc123"
+	const dir = "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/output/"
+	tests := []struct {
+		fixture string
+		want    []string
+	}{
+		{
+			fixture: "master.m3u8",
+			want: []string{
+				"/playlist?u=" + dir + "Colors_HD_1200.m3u8" + query + "\n",
+				"/playlist?u=" + dir + "Colors_HD_64.m3u8" + query + "\n",
+				`CODECS="avc1.640028,mp4a.40.2"`,
+			},
+		},
+		{
+			fixture: "master_renditions.m3u8",
+			want: []string{
+				`URI="/playlist?u=` + dir + "audio/hin/index.m3u8" + query + `"`,
+				`URI="/playlist?u=https://cdn2.example.jio.com/bpk-tv/Star_Sports_1_HD/audio/eng/index.m3u8` + query + `"`,
+				"/playlist?u=" + dir + "video/480/index.m3u8" + query,
+				`URI="/playlist?u=` + dir + "video/1080/iframes.m3u8" + query + `"`,
+			},
+		},
+		{
+			fixture: "media_ts.m3u8",
+			want: []string{
+				// keys on another JioTV host keep the token they are authorised with
+				`URI="/key?u=https://tv.media.jio.com/streams_live/Colors_HD/Colors_HD_1200-284712.pkey` + query + `"`,
+				"/segment?u=" + dir + "Colors_HD_1200-284713.ts" + query,
+				"/segment?u=https://jiotvmblive.cdn.jio.com/ads/promo/segment_01.ts" + query,
+				"#EXT-X-KEY:METHOD=NONE\n",
+				"#EXTINF:5.960,Promo\n",
+			},
+		},
+		{
+			fixture: "media_aac.m3u8",
+			want: []string{
+				"/segment?u=" + dir + "Radio_Mirchi_64-95031.aac" + query,
+			},
+		},
+		{
+			fixture: "media_fmp4.m3u8",
+			want: []string{
+				`#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://jiotv/Sony_HD",KEYFORMAT="com.apple.streamingkeydelivery"`,
+				`#EXT-X-MAP:URI="/init?u=` + dir + "init.mp4" + query + `",BYTERANGE="812@0"`,
+				"#EXT-X-BYTERANGE:204800@812\n/segment?u=" + dir + "chunk.m4s" + query,
+				// own query parameters take precedence over the inherited ones
+				"/segment?u=https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/1021/segment_1023.m4s?hdnea=exp=1709300000~hmac=feedface\n",
+				"#EXT-X-ENDLIST\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data := readFixture(t, tt.fixture)
+			out, err := Rewrite(data, testBaseURL, markRewrite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(out)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output does not contain %q\n%s", want, got)
+				}
+			}
+			if strings.Count(got, "\n") != strings.Count(string(data), "\n") {
+				t.Errorf("line count changed from %d to %d", strings.Count(string(data), "\n"), strings.Count(got, "\n"))
+			}
+			// every URI line must have been rewritten
+			for _, line := range strings.Split(got, "\n") {
+				if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "/") {
+					t.Errorf("URI line %q was not rewritten", line)
+				}
+			}
+			// the rewritten playlist must still parse
+			if _, err := Parse(out); err != nil {
+				t.Errorf("rewritten playlist does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveURI(t *testing.T) {
+	base, err := url.Parse(testBaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const query = "?hdnea=st=1709290000~exp=1709300000~acl=/*~hmac=abc123"
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"seg.ts", "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/output/seg.ts" + query},
+		{"/other/seg.ts", "https://jiotvmblive.cdn.jio.com/other/seg.ts" + query},
+		{"../seg.ts?x=1", "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/seg.ts?x=1"},
+		{"https://a.example/k.key", "https://a.example/k.key"},
+		{"https://jiotvmblive.cdn.jio.com/keys/k.key", "https://jiotvmblive.cdn.jio.com/keys/k.key" + query},
+		{"https://tv.media.jio.com/streams_live/k.pkey", "https://tv.media.jio.com/streams_live/k.pkey" + query},
+		{"https://jio.com.example/k.key", "https://jio.com.example/k.key"},
+		{"//a.example/seg.ts", "https://a.example/seg.ts"},
+		{"skd://jiotv/key", "skd://jiotv/key"},
+		{"data:text/plain;base64,AAAA", "data:text/plain;base64,AAAA"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveURI(base, tt.ref)
+		if err != nil {
+			t.Errorf("ResolveURI(%q): %v", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveURI(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	got := ParseAttributes(`TYPE=AUDIO,GROUP-ID="aac",NAME="Hindi, Stereo",DEFAULT=YES,URI="a/b.m3u8"`)
+	want := map[string]string{
+		"TYPE":     "AUDIO",
+		"GROUP-ID": "aac",
+		"NAME":     "Hindi, Stereo",
+		"DEFAULT":  "YES",
+		"URI":      "a/b.m3u8",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAttributes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package hls
+
+// PlaylistType tells master playlists apart from media playlists
+type PlaylistType int
+
+const (
+	// Media playlist lists the segments of a single rendition
+	Media PlaylistType = iota
+	// Master playlist lists the variant streams and renditions of a channel
+	Master
+)
+
+// URIKind describes what a URI found in a playlist points to
+type URIKind int
+
+const (
+	// KindPlaylist is a variant, rendition, I-frame or rendition report playlist
+	KindPlaylist URIKind = iota
+	// KindSegment is a media segment or a partial segment
+	KindSegment
+	// KindInit is an EXT-X-MAP initialisation segment
+	KindInit
+	// KindKey is an EXT-X-KEY or EXT-X-SESSION-KEY decryption key
+	KindKey
+)
+
+// ByteRange represents the EXT-X-BYTERANGE (or BYTERANGE attribute) of a resource
+type ByteRange struct {
+	Length int64
+	Offset int64
+	// HasOffset is false when the offset is implied by the previous range
+	HasOffset bool
+}
+
+// Key represents an EXT-X-KEY tag
+type Key struct {
+	Method    string
+	URI       string
+	IV        string
+	KeyFormat string
+}
+
+// Map represents an EXT-X-MAP tag
+type Map struct {
+	URI       string
+	ByteRange *ByteRange
+}
+
+// Segment represents a single media segment of a media playlist
+type Segment struct {
+	URI             string
+	Duration        float64
+	Title           string
+	Sequence        int64
+	ByteRange       *ByteRange
+	Discontinuity   bool
+	ProgramDateTime string
+	Key             *Key
+	Map             *Map
+}
+
+// Variant represents an EXT-X-STREAM-INF or EXT-X-I-FRAME-STREAM-INF entry of a master playlist
+type Variant struct {
+	URI              string
+	Bandwidth        int64
+	AverageBandwidth int64
+	Width            int
+	Height           int
+	Codecs           string
+	FrameRate        float64
+	Audio            string
+	IFrame           bool
+}
+
+// Rendition represents an EXT-X-MEDIA tag of a master playlist
+type Rendition struct {
+	Type     string
+	GroupID  string
+	Name     string
+	Language string
+	URI      string
+	Default  bool
+}
+
+// Playlist is a parsed HLS playlist.
+// Variants and Renditions are only filled for master playlists, Segments only for media playlists.
+type Playlist struct {
+	Type                  PlaylistType
+	Version               int
+	TargetDuration        float64
+	MediaSequence         int64
+	DiscontinuitySequence int64
+	PlaylistType          string
+	EndList               bool
+	Variants              []Variant
+	Renditions            []Rendition
+	Segments              []Segment
+}
@@ -0,0 +1,188 @@
+package hls
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// videoCodecs are the CODECS prefixes of video streams
+var videoCodecs = []string{"avc1", "avc3", "hvc1", "hev1", "dvh1", "dvhe", "vp09", "vp8", "av01", "mp4v"}
+
+// VariantFilter limits the variants of a master playlist.
+// Zero fields do not limit anything.
+type VariantFilter struct {
+	// MaxHeight is the highest allowed vertical resolution, like 720
+	MaxHeight int
+	// MaxBandwidth is the highest allowed peak bandwidth in bits per second
+	MaxBandwidth int64
+}
+
+// ParseVariantFilter parses the maxres and maxbw query values.
+// maxres may be a height like `720` or `720p`, or a resolution like `1280x720`.
+func ParseVariantFilter(maxres, maxbw string) (VariantFilter, error) {
+	var filter VariantFilter
+	var err error
+	if maxres != "" {
+		height := strings.TrimSuffix(strings.ToLower(maxres), "p")
+		if _, h, found := strings.Cut(height, "x"); found {
+			height = h
+		}
+		if filter.MaxHeight, err = strconv.Atoi(height); err != nil {
+			return filter, err
+		}
+	}
+	if maxbw != "" {
+		if filter.MaxBandwidth, err = strconv.ParseInt(maxbw, 10, 64); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// IsZero reports whether the filter allows every variant
+func (f VariantFilter) IsZero() bool {
+	return f.MaxHeight <= 0 && f.MaxBandwidth <= 0
+}
+
+// Allows reports whether the variant fits within the limits of the filter.
+// Variants without a resolution pass any resolution limit.
+func (f VariantFilter) Allows(v Variant) bool {
+	if f.MaxHeight > 0 && v.Height > f.MaxHeight {
+		return false
+	}
+	if f.MaxBandwidth > 0 && v.Bandwidth > f.MaxBandwidth {
+		return false
+	}
+	return true
+}
+
+// HasVideo reports whether the variant carries video.
+// Variants that announce neither a resolution nor codecs are assumed to have video.
+func (v Variant) HasVideo() bool {
+	if v.Width > 0 || v.Height > 0 || v.Codecs == "" {
+		return true
+	}
+	for _, codec := range strings.Split(v.Codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		for _, prefix := range videoCodecs {
+			if strings.HasPrefix(codec, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AudioOnly reports whether the playlist carries no video.
+// A master playlist is audio only if none of its variants has video,
+// a media playlist if all of its segments are AAC.
+func (p *Playlist) AudioOnly() bool {
+	if p.Type == Master {
+		for _, variant := range p.Variants {
+			if !variant.IFrame && variant.HasVideo() {
+				return false
+			}
+		}
+		return len(p.Variants) > 0
+	}
+	for _, segment := range p.Segments {
+		if path, _, _ := strings.Cut(segment.URI, "?"); !strings.HasSuffix(path, ".aac") {
+			return false
+		}
+	}
+	return len(p.Segments) > 0
+}
+
+// SelectVariants returns the variants allowed by filter, without I-frame variants.
+// Variants without video are left out unless the playlist is audio only.
+// If no variant fits the filter, the one with the lowest bandwidth is returned,
+// so that a channel always stays playable.
+func (p *Playlist) SelectVariants(filter VariantFilter) []Variant {
+	audioOnly := p.AudioOnly()
+	var candidates, selected []Variant
+	for _, variant := range p.Variants {
+		if variant.IFrame || (!audioOnly && !variant.HasVideo()) {
+			continue
+		}
+		candidates = append(candidates, variant)
+		if filter.Allows(variant) {
+			selected = append(selected, variant)
+		}
+	}
+	if len(selected) > 0 || len(candidates) == 0 {
+		return selected
+	}
+	lowest := candidates[0]
+	for _, variant := range candidates[1:] {
+		if variant.Bandwidth < lowest.Bandwidth {
+			lowest = variant
+		}
+	}
+	return []Variant{lowest}
+}
+
+// HighestBandwidth returns the variant with the highest bandwidth, or nil if there are no variants
+func HighestBandwidth(variants []Variant) *Variant {
+	var best *Variant
+	for i := range variants {
+		if best == nil || variants[i].Bandwidth > best.Bandwidth {
+			best = &variants[i]
+		}
+	}
+	return best
+}
+
+// FilterVariants removes the variants of a master playlist for which keep returns false.
+// The EXT-X-STREAM-INF tag is removed along with its URI line, all other lines are kept.
+func FilterVariants(data []byte, keep func(variant Variant) bool) ([]byte, error) {
+	lines := splitLines(data)
+	if len(lines) == 0 || lines[0] != Header {
+		return nil, ErrInvalidPlaylist
+	}
+
+	var out bytes.Buffer
+	var streamInf *Variant
+	streamInfLine := ""
+	write := func(line string) {
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	for _, line := range lines {
+		switch {
+		case line == "":
+			continue
+		case streamInf != nil && !strings.HasPrefix(line, "#"):
+			streamInf.URI = line
+			if keep(*streamInf) {
+				write(streamInfLine)
+				write(line)
+			}
+			streamInf = nil
+			continue
+		case strings.HasPrefix(line, "#"):
+			tag, value := splitTag(line)
+			switch tag {
+			case "#EXT-X-STREAM-INF":
+				variant, err := parseVariant(value)
+				if err != nil {
+					return nil, err
+				}
+				streamInf, streamInfLine = &variant, line
+				continue
+			case "#EXT-X-I-FRAME-STREAM-INF":
+				variant, err := parseVariant(value)
+				if err != nil {
+					return nil, err
+				}
+				variant.IFrame = true
+				variant.URI = ParseAttributes(value)["URI"]
+				if !keep(variant) {
+					continue
+				}
+			}
+		}
+		write(line)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package hls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func variantURIs(variants []Variant) []string {
+	uris := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		uris = append(uris, variant.URI)
+	}
+	return uris
+}
+
+func TestParseVariantFilter(t *testing.T) {
+	tests := []struct {
+		maxres, maxbw string
+		want          VariantFilter
+		wantErr       bool
+	}{
+		{"", "", VariantFilter{}, false},
+		{"720", "", VariantFilter{MaxHeight: 720}, false},
+		{"720p", "3000000", VariantFilter{MaxHeight: 720, MaxBandwidth: 3000000}, false},
+		{"1280x720", "", VariantFilter{MaxHeight: 720}, false},
+		{"hd", "", VariantFilter{}, true},
+		{"", "3M", VariantFilter{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVariantFilter(tt.maxres, tt.maxbw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVariantFilter(%q, %q) error = %v, wantErr %v", tt.maxres, tt.maxbw, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseVariantFilter(%q, %q) = %+v, want %+v", tt.maxres, tt.maxbw, got, tt.want)
+		}
+	}
+}
+
+func TestSelectVariants(t *testing.T) {
+	master, err := Parse(readFixture(t, "master.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Parse(readFixture(t, "master_audio.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		playlist *Playlist
+		filter   VariantFilter
+		want     []string
+	}{
+		{"no limit drops audio only variant", master, VariantFilter{}, []string{"Colors_HD_1200.m3u8", "Colors_HD_800.m3u8", "Colors_HD_400.m3u8"}},
+		{"max resolution", master, VariantFilter{MaxHeight: 720}, []string{"Colors_HD_800.m3u8", "Colors_HD_400.m3u8"}},
+		{"max bandwidth", master, VariantFilter{MaxBandwidth: 1000000}, []string{"Colors_HD_400.m3u8"}},
+		{"nothing fits falls back to lowest", master, VariantFilter{MaxHeight: 240}, []string{"Colors_HD_400.m3u8"}},
+		{"audio only channel", audio, VariantFilter{MaxHeight: 720}, []string{"Radio_128.m3u8", "Radio_64.m3u8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := variantURIs(tt.playlist.SelectVariants(tt.filter))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectVariants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudioOnly(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    bool
+	}{
+		{"master.m3u8", false},
+		{"master_audio.m3u8", true},
+		{"media_ts.m3u8", false},
+		{"media_aac.m3u8", true},
+	}
+	for _, tt := range tests {
+		playlist, err := Parse(readFixture(t, tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := playlist.AudioOnly(); got != tt.want {
+			t.Errorf("%s: AudioOnly() = %v, want %v", tt.fixture, got, tt.want)
+		}
+	}
+}
+
+func TestFilterVariants(t *testing.T) {
+	data := readFixture(t, "master_renditions.m3u8")
+	filtered, err := FilterVariants(data, func(variant Variant) bool {
+		return variant.Height <= 480
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlist, err := Parse(filtered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := variantURIs(playlist.Variants), []string{"video/480/index.m3u8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("variants after filtering = %v, want %v", got, want)
+	}
+	if len(playlist.Renditions) != 2 {
+		t.Errorf("filtering dropped renditions, %d left", len(playlist.Renditions))
+	}
+
+	if _, err := FilterVariants([]byte("not a playlist"), func(Variant) bool { return true }); err != ErrInvalidPlaylist {
+		t.Errorf("FilterVariants() on invalid playlist error = %v, want %v", err, ErrInvalidPlaylist)
+	}
+}
@@ -0,0 +1,243 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// PROFILES_FILE stores the playlist profiles inside the path prefix
+const PROFILES_FILE = "profiles.toml"
+
+// Errors
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrInvalidName     = errors.New("profile name may only contain lowercase letters, digits, - and _")
+	ErrInvalidProfile  = errors.New("invalid profile")
+)
+
+var (
+	mu        sync.Mutex
+	validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// List returns all profiles ordered by name
+func List() ([]Profile, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+// Get returns the profile with the given name
+func Get(name string) (*Profile, error) {
+	profiles, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+	return nil, ErrProfileNotFound
+}
+
+// Save creates the profile or replaces the profile with the same name
+func Save(profile Profile) (*Profile, error) {
+	if err := normalize(&profile); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	profiles, err := load()
+	if err != nil {
+		return nil, err
+	}
+	replaced := false
+	for i := range profiles {
+		if profiles[i].Name == profile.Name {
+			profiles[i] = profile
+			replaced = true
+		}
+	}
+	if !replaced {
+		profiles = append(profiles, profile)
+	}
+	return &profile, save(profiles)
+}
+
+// Delete removes a profile
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	profiles, err := load()
+	if err != nil {
+		return err
+	}
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return save(append(profiles[:i], profiles[i+1:]...))
+		}
+	}
+	return ErrProfileNotFound
+}
+
+// ModTime returns when the profiles were last changed
+func ModTime() time.Time {
+	stat, err := os.Stat(profilesPath())
+	if err != nil {
+		return time.Time{}
+	}
+	return stat.ModTime()
+}
+
+// hasFilters reports whether the profile selects channels by language, category or HD
+func (p Profile) hasFilters() bool {
+	return len(p.Languages) > 0 || len(p.Categories) > 0 || p.HDOnly
+}
+
+// Matches reports whether a channel is part of the playlist of the profile.
+// Favourites are always part of it. Without any filters, a profile with favourites holds only them
+// and an empty profile holds all channels.
+func (p Profile) Matches(channel television.Channel) bool {
+	if utils.ContainsString(channel.ID, p.Exclude) {
+		return false
+	}
+	if utils.ContainsString(channel.ID, p.Favourites) {
+		return true
+	}
+	if !p.hasFilters() {
+		return len(p.Favourites) == 0
+	}
+	if len(p.Languages) > 0 && !containsFold(p.Languages, television.LanguageMap[channel.Language]) {
+		return false
+	}
+	if len(p.Categories) > 0 && !containsFold(p.Categories, television.CategoryMap[channel.Category]) {
+		return false
+	}
+	return !p.HDOnly || channel.IsHD
+}
+
+// Channels returns the channels of the profile, keeping their order
+func (p Profile) Channels(channels []television.Channel) []television.Channel {
+	result := []television.Channel{}
+	for _, channel := range channels {
+		if p.Matches(channel) {
+			result = append(result, channel)
+		}
+	}
+	return result
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize validates the profile and drops empty entries
+func normalize(profile *Profile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if !validName.MatchString(profile.Name) {
+		return ErrInvalidName
+	}
+	profile.Favourites = trim(profile.Favourites)
+	profile.Exclude = trim(profile.Exclude)
+	profile.Languages = trim(profile.Languages)
+	for _, language := range profile.Languages {
+		if !known(television.LanguageMap, language) {
+			return fmt.Errorf("%w: unknown language %q", ErrInvalidProfile, language)
+		}
+	}
+	profile.Categories = trim(profile.Categories)
+	for _, category := range profile.Categories {
+		if !known(television.CategoryMap, category) {
+			return fmt.Errorf("%w: unknown category %q", ErrInvalidProfile, category)
+		}
+	}
+	switch profile.Quality {
+	case "", "auto", "high", "h", "medium", "med", "m", "low", "l":
+	default:
+		return fmt.Errorf("%w: unknown quality %q", ErrInvalidProfile, profile.Quality)
+	}
+	switch profile.Grouping {
+	case "", "split", "language":
+	default:
+		return fmt.Errorf("%w: grouping must be empty, split or language", ErrInvalidProfile)
+	}
+	return nil
+}
+
+// trim drops empty and surrounding whitespace of values
+func trim(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// known reports whether name is one of the names of a language or category map
+func known(names map[int]string, name string) bool {
+	for _, v := range names {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// profilesPath returns the path of the profiles file
+func profilesPath() string {
+	return filepath.Join(utils.GetPathPrefix(), PROFILES_FILE)
+}
+
+// load reads the profiles file. mu must be held.
+func load() ([]Profile, error) {
+	var file profilesFile
+	if _, err := toml.DecodeFile(profilesPath(), &file); err != nil {
+		if os.IsNotExist(err) {
+			return []Profile{}, nil
+		}
+		return nil, err
+	}
+	sort.Slice(file.Profiles, func(i, j int) bool {
+		return file.Profiles[i].Name < file.Profiles[j].Name
+	})
+	return file.Profiles, nil
+}
+
+// save writes the profiles file. mu must be held.
+func save(profiles []Profile) error {
+	filename := profilesPath()
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(file).Encode(profilesFile{Profiles: profiles}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
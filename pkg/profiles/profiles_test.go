@@ -0,0 +1,100 @@
+package profiles
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
+func ids(channels []television.Channel) []string {
+	result := []string{}
+	for _, channel := range channels {
+		result = append(result, channel.ID)
+	}
+	return result
+}
+
+func TestChannels(t *testing.T) {
+	// Language 1 is Hindi, 6 is English. Category 8 is Sports, 12 is News.
+	channels := []television.Channel{
+		{ID: "1", Category: 8, Language: 1, IsHD: true},
+		{ID: "2", Category: 8, Language: 6},
+		{ID: "3", Category: 12, Language: 6, IsHD: true},
+		{ID: "4", Category: 8, Language: 6, IsHD: true},
+		{ID: "5", Category: 12, Language: 1},
+	}
+	tests := []struct {
+		name    string
+		profile Profile
+		want    []string
+	}{
+		{"empty profile holds all channels", Profile{}, []string{"1", "2", "3", "4", "5"}},
+		{"favourites only", Profile{Favourites: []string{"5", "2"}}, []string{"2", "5"}},
+		{"filters", Profile{Categories: []string{"sports"}, HDOnly: true}, []string{"1", "4"}},
+		{"filters and favourites", Profile{Languages: []string{"English"}, Categories: []string{"Sports"}, Favourites: []string{"5"}}, []string{"2", "4", "5"}},
+		{"exclude wins", Profile{Favourites: []string{"3"}, Exclude: []string{"3", "1"}, Languages: []string{"Hindi"}}, []string{"5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.profile.Channels(channels)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Channels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveAndDelete(t *testing.T) {
+	config.Cfg.PathPrefix = t.TempDir()
+
+	if _, err := Save(Profile{Name: "Sports HD"}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Save() with invalid name = %v, want %v", err, ErrInvalidName)
+	}
+	if _, err := Save(Profile{Name: "kids", Languages: []string{"Klingon"}}); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("Save() with unknown language = %v, want %v", err, ErrInvalidProfile)
+	}
+
+	sports := Profile{Name: "sports-hd", Categories: []string{"Sports"}, HDOnly: true, Quality: "high"}
+	if _, err := Save(sports); err != nil {
+		t.Fatal(err)
+	}
+	kids := Profile{Name: "kids", Favourites: []string{" 154 ", ""}, Catchup: true}
+	if _, err := Save(kids); err != nil {
+		t.Fatal(err)
+	}
+	// Saving a profile with the same name replaces it
+	sports.Exclude = []string{"143"}
+	if _, err := Save(sports); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "kids" || list[1].Name != "sports-hd" {
+		t.Fatalf("List() = %+v", list)
+	}
+	if !reflect.DeepEqual(list[0].Favourites, []string{"154"}) {
+		t.Errorf("favourites = %q, want them trimmed", list[0].Favourites)
+	}
+	got, err := Get("sports-hd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Exclude, []string{"143"}) || !got.HDOnly || got.Quality != "high" {
+		t.Errorf("Get() = %+v", got)
+	}
+
+	if err := Delete("kids"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get("kids"); err != ErrProfileNotFound {
+		t.Errorf("Get() of deleted profile = %v, want %v", err, ErrProfileNotFound)
+	}
+	if err := Delete("kids"); err != ErrProfileNotFound {
+		t.Errorf("Delete() of missing profile = %v, want %v", err, ErrProfileNotFound)
+	}
+}
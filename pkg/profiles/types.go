@@ -0,0 +1,24 @@
+package profiles
+
+// Profile is a named playlist of favourite channels and channel filters
+type Profile struct {
+	Name string `json:"name" toml:"name"`
+	// Favourites are channel IDs that are always part of the playlist
+	Favourites []string `json:"favourites" toml:"favourites"`
+	// Languages and Categories select channels by their language and category names
+	Languages  []string `json:"languages" toml:"languages"`
+	Categories []string `json:"categories" toml:"categories"`
+	// HDOnly drops SD channels selected by the filters
+	HDOnly bool `json:"hd_only" toml:"hd_only"`
+	// Exclude are channel IDs that are never part of the playlist
+	Exclude []string `json:"exclude" toml:"exclude"`
+	// Quality, Grouping and Catchup match the q, c and catchup parameters of /playlist.m3u
+	Quality  string `json:"quality" toml:"quality"`
+	Grouping string `json:"grouping" toml:"grouping"`
+	Catchup  bool   `json:"catchup" toml:"catchup"`
+}
+
+// profilesFile is the structure of the profiles TOML file
+type profilesFile struct {
+	Profiles []Profile `toml:"profiles"`
+}
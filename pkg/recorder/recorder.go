@@ -0,0 +1,559 @@
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/hls"
+	"github.com/Varun03-max/JIO/pkg/scheduler"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	// RECORDINGS_DIR is the folder inside the path prefix where recordings are written
+	RECORDINGS_DIR = "recordings"
+	// RECORDINGS_FILE stores the list of recordings inside the path prefix
+	RECORDINGS_FILE = "recordings.json"
+	// TASK_PREFIX prefixes the scheduler task IDs of recordings
+	TASK_PREFIX = "recording_"
+	// SAVE_INTERVAL bounds how often progress of running recordings is written to disk
+	SAVE_INTERVAL = 30 * time.Second
+	// MAX_RETRY_DELAY bounds the wait between attempts after upstream errors
+	MAX_RETRY_DELAY = 30 * time.Second
+)
+
+// Errors
+var (
+	ErrNotFound         = errors.New("recording not found")
+	ErrInvalidTime      = errors.New("recording must end after it starts and in the future")
+	ErrMissingChannel   = errors.New("channel ID is required")
+	ErrAlreadyScheduled = errors.New("recording is already scheduled")
+	ErrNotInitialized   = errors.New("recorder is not initialized")
+	ErrMissingTitle     = errors.New("title is required")
+)
+
+var (
+	mu         sync.Mutex
+	recordings map[string]*Recording
+	cancels    map[string]context.CancelFunc
+	// withChannel calls a function with the Television of the account that is entitled to a channel
+	withChannel func(channelID string, fn func(*television.Television) error) error
+	// mediaPlaylistURL returns the URL of the media playlist a channel is recorded from
+	mediaPlaylistURL = (*television.Television).MediaPlaylistURL
+)
+
+// rulesOnce registers the evaluation of the recording rules with the EPG generation once
+var rulesOnce sync.Once
+
+// Init loads saved recordings and schedules the pending ones.
+// channel calls its function with the Television used to fetch the streams of a channel when a recording starts.
+// Recordings are loaded only once, later calls only replace channel,
+// so recordings running in this process are neither scheduled again nor lose their cancel function.
+func Init(channel func(channelID string, fn func(*television.Television) error) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	withChannel = channel
+	if recordings != nil {
+		return nil
+	}
+	recordings = make(map[string]*Recording)
+	cancels = make(map[string]context.CancelFunc)
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(utils.GetPathPrefix() + RECORDINGS_FILE)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		var saved []*Recording
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return err
+		}
+		for _, rec := range saved {
+			recordings[rec.ID] = rec
+		}
+	}
+
+	now := time.Now()
+	for _, rec := range recordings {
+		// Recordings running when the server stopped continue where they left off
+		if rec.Status == StatusRecording {
+			if rec.End.After(now) {
+				rec.Status = StatusScheduled
+			} else {
+				finish(rec, errors.New("interrupted by a restart"))
+			}
+		}
+		if rec.Status != StatusScheduled {
+			continue
+		}
+		if !rec.End.After(now) {
+			rec.Status = StatusFailed
+			rec.Error = "missed while the server was not running"
+			continue
+		}
+		schedule(rec)
+	}
+	utils.Log.Println("Loaded", len(recordings), "recordings")
+	if err := save(); err != nil {
+		return err
+	}
+
+	// New episodes show up whenever the EPG is generated
+	rulesOnce.Do(func() {
+		epg.OnGenerate(evaluateRules)
+		go evaluateRules()
+	})
+	return nil
+}
+
+// Dir returns the folder recordings are written to
+func Dir() string {
+	return utils.GetPathPrefix() + RECORDINGS_DIR
+}
+
+// Schedule schedules a new recording
+func Schedule(req Request) (*Recording, error) {
+	if req.ChannelID == "" {
+		return nil, ErrMissingChannel
+	}
+	if !req.End.After(req.Start) || !req.End.After(time.Now()) {
+		return nil, ErrInvalidTime
+	}
+	if req.ChannelName == "" {
+		req.ChannelName = epg.ChannelName(req.ChannelID)
+	}
+	if req.Title == "" {
+		req.Title = fmt.Sprintf("%s %s", req.ChannelName, req.Start.Local().Format("2006-01-02 15:04"))
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if recordings == nil {
+		return nil, ErrNotInitialized
+	}
+	for _, rec := range recordings {
+		// Titles are not compared, recordings may have been renamed
+		if rec.ChannelID != req.ChannelID || !rec.Start.Equal(req.Start) {
+			continue
+		}
+		// Rules must not bring back recordings that were cancelled
+		if req.RuleID != "" || rec.Status == StatusScheduled || rec.Status == StatusRecording {
+			return nil, ErrAlreadyScheduled
+		}
+	}
+
+	rec := &Recording{
+		ID:          id,
+		ChannelID:   req.ChannelID,
+		ChannelName: req.ChannelName,
+		Title:       req.Title,
+		Description: req.Description,
+		Category:    req.Category,
+		Poster:      req.Poster,
+		Start:       req.Start,
+		End:         req.End,
+		Status:      StatusScheduled,
+		File:        id + ".ts",
+		RuleID:      req.RuleID,
+		CreatedAt:   time.Now(),
+	}
+	recordings[id] = rec
+	if err := save(); err != nil {
+		delete(recordings, id)
+		return nil, err
+	}
+	schedule(rec)
+	utils.Log.Printf("Scheduled recording %s of channel %s from %v to %v", rec.ID, rec.ChannelID, rec.Start.Local(), rec.End.Local())
+	copied := *rec
+	return &copied, nil
+}
+
+// ScheduleProgramme schedules a recording of a programme from the generated EPG
+func ScheduleProgramme(programme epg.Programme) (*Recording, error) {
+	return scheduleProgramme(programme, "")
+}
+
+// scheduleProgramme schedules a recording of a programme on behalf of a rule
+func scheduleProgramme(programme epg.Programme, ruleID string) (*Recording, error) {
+	start, err := programme.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	end, err := programme.StopTime()
+	if err != nil {
+		return nil, err
+	}
+	return Schedule(Request{
+		ChannelID:   programme.Channel,
+		Title:       programme.Title.Value,
+		Description: programme.Desc.Value,
+		Category:    programme.Category.Value,
+		Poster:      programme.Icon.Src,
+		Start:       start,
+		End:         end,
+		RuleID:      ruleID,
+	})
+}
+
+// List returns all recordings sorted by start time
+func List() []Recording {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]Recording, 0, len(recordings))
+	for _, rec := range recordings {
+		list = append(list, *rec)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+	return list
+}
+
+// Get returns the recording with the given ID
+func Get(id string) (*Recording, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	rec, ok := recordings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+// Stop cancels a scheduled recording or stops a running one.
+// What was recorded so far is kept.
+func Stop(id string) (*Recording, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	rec, ok := recordings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	switch rec.Status {
+	case StatusScheduled:
+		scheduler.Remove(TASK_PREFIX + id)
+		rec.Status = StatusCancelled
+	case StatusRecording:
+		if cancel, ok := cancels[id]; ok {
+			cancel()
+		}
+	}
+	if err := save(); err != nil {
+		return nil, err
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+// Rename changes the title of a recording
+func Rename(id, title string) (*Recording, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, ErrMissingTitle
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rec, ok := recordings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rec.Title = title
+	if err := save(); err != nil {
+		return nil, err
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+// Delete stops a recording and removes it along with its files
+func Delete(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	rec, ok := recordings[id]
+	if !ok {
+		return ErrNotFound
+	}
+	scheduler.Remove(TASK_PREFIX + id)
+	if cancel, ok := cancels[id]; ok {
+		cancel()
+	}
+	delete(recordings, id)
+	if rec.File != "" {
+		for _, file := range []string{FilePath(rec), indexPath(rec)} {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				utils.Log.Println("Failed to remove recording file:", err)
+			}
+		}
+	}
+	return save()
+}
+
+// FilePath returns the path of the recorded file
+func FilePath(rec *Recording) string {
+	return filepath.Join(Dir(), rec.File)
+}
+
+// ReadIndex returns the position of every segment inside the recorded file
+func ReadIndex(rec *Recording) ([]IndexEntry, error) {
+	f, err := os.Open(indexPath(rec))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var index []IndexEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		offset, err1 := strconv.ParseInt(fields[0], 10, 64)
+		length, err2 := strconv.ParseInt(fields[1], 10, 64)
+		duration, err3 := strconv.ParseFloat(fields[2], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			// An interrupted write leaves a partial last line
+			continue
+		}
+		index = append(index, IndexEntry{
+			Offset:        offset,
+			Length:        length,
+			Duration:      duration,
+			Discontinuity: fields[3] == "1",
+		})
+	}
+	return index, scanner.Err()
+}
+
+// indexPath returns the path of the segment index of a recording
+func indexPath(rec *Recording) string {
+	return strings.TrimSuffix(FilePath(rec), filepath.Ext(rec.File)) + ".idx"
+}
+
+// schedule adds the scheduler task starting rec. mu must be held.
+func schedule(rec *Recording) {
+	id := rec.ID
+	scheduler.AddOnce(TASK_PREFIX+id, rec.Start, func() error {
+		return run(id)
+	})
+}
+
+// run records until the recording ends or is stopped
+func run(id string) (err error) {
+	mu.Lock()
+	rec, ok := recordings[id]
+	if !ok || rec.Status != StatusScheduled {
+		mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), rec.End)
+	defer cancel()
+	cancels[id] = cancel
+	rec.Status = StatusRecording
+	rec.Error = ""
+	snapshot := *rec
+	if err := save(); err != nil {
+		utils.Log.Println("Failed to save recordings:", err)
+	}
+	mu.Unlock()
+
+	utils.Log.Printf("Recording %s of channel %s started", id, snapshot.ChannelID)
+	defer func() {
+		// Television methods panic on some upstream errors
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recording %s: %v", id, r)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		delete(cancels, id)
+		if rec, ok := recordings[id]; ok {
+			finish(rec, err)
+			if err := save(); err != nil {
+				utils.Log.Println("Failed to save recordings:", err)
+			}
+		}
+		utils.Log.Printf("Recording %s finished", id)
+	}()
+
+	return record(ctx, &snapshot)
+}
+
+// finish sets the final status of rec. mu must be held.
+func finish(rec *Recording, err error) {
+	switch {
+	case err == nil:
+		rec.Status = StatusCompleted
+	case rec.Size > 0:
+		// Keep what was recorded and tell why it is incomplete
+		rec.Status = StatusCompleted
+		rec.Error = err.Error()
+	default:
+		rec.Status = StatusFailed
+		rec.Error = err.Error()
+	}
+}
+
+// record follows the live playlist of the channel and appends every new segment to the recording file
+func record(ctx context.Context, rec *Recording) error {
+	file, err := os.OpenFile(FilePath(rec), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	index, err := os.OpenFile(indexPath(rec), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := stat.Size()
+	// A resumed recording continues after a gap
+	discontinuity := offset > 0
+
+	var tv *television.Television
+	keys := make(map[string][]byte)
+	var follower *hls.Follower
+	var lastErr error
+	failures := 0
+	lastSave := time.Now()
+
+	for {
+		var segments []hls.Segment
+		if follower == nil {
+			err = withChannel(rec.ChannelID, func(channelTV *television.Television) error {
+				streamURL, err := mediaPlaylistURL(channelTV, rec.ChannelID, hls.VariantFilter{})
+				if err == nil {
+					tv = channelTV
+					follower = hls.NewFollower(streamURL, tv.Fetch)
+				}
+				return err
+			})
+		}
+		if follower != nil {
+			if segments, err = follower.Poll(); err != nil {
+				var statusErr *television.StatusError
+				if errors.As(err, &statusErr) {
+					// Tokens of the stream URL expired, get a new one with the current login
+					follower = nil
+				}
+			}
+		}
+
+		for _, segment := range segments {
+			var data []byte
+			if data, err = tv.FetchSegment(segment, rec.ChannelID, keys); err != nil {
+				break
+			}
+			if _, err = file.Write(data); err != nil {
+				return err
+			}
+			flag := "0"
+			if segment.Discontinuity || discontinuity {
+				flag = "1"
+			}
+			discontinuity = false
+			if _, err = fmt.Fprintf(index, "%d %d %.3f %s\n", offset, len(data), segment.Duration, flag); err != nil {
+				return err
+			}
+			offset += int64(len(data))
+
+			mu.Lock()
+			if stored, ok := recordings[rec.ID]; ok {
+				stored.Size = offset
+				stored.Duration += segment.Duration
+				rec.Size, rec.Duration = stored.Size, stored.Duration
+				if time.Since(lastSave) > SAVE_INTERVAL {
+					if err := save(); err != nil {
+						utils.Log.Println("Failed to save recordings:", err)
+					}
+					lastSave = time.Now()
+				}
+			}
+			mu.Unlock()
+		}
+
+		wait := hls.DEFAULT_POLL_INTERVAL
+		if follower != nil {
+			wait = follower.Interval()
+		}
+		if err != nil {
+			utils.Log.Printf("Recording %s: %v", rec.ID, err)
+			lastErr = err
+			failures++
+			wait = min(time.Duration(failures)*hls.DEFAULT_POLL_INTERVAL, MAX_RETRY_DELAY)
+			// Segments after the failed one are skipped, mark the gap
+			discontinuity = true
+		} else {
+			failures = 0
+		}
+		if follower != nil && follower.Playlist() != nil && follower.Playlist().EndList {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if offset == 0 && lastErr != nil {
+				return lastErr
+			}
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// save writes the list of recordings to disk. mu must be held.
+func save() error {
+	list := make([]*Recording, 0, len(recordings))
+	for _, rec := range recordings {
+		list = append(list, rec)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := utils.GetPathPrefix() + RECORDINGS_FILE
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// newID returns a random recording ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
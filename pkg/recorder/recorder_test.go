@@ -0,0 +1,326 @@
+package recorder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/hls"
+	"github.com/Varun03-max/JIO/pkg/scheduler"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log = log.New(io.Discard, "", 0)
+	// Rules are evaluated by the tests themselves, not in the background
+	rulesOnce.Do(func() {})
+	os.Exit(m.Run())
+}
+
+// upstream serves a finished media playlist of segments with the given bodies
+func upstream(t *testing.T, segments ...string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.m3u8" {
+			io.WriteString(w, "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:0\n")
+			for i := range segments {
+				fmt.Fprintf(w, "#EXTINF:2.000,\nsegment%d.ts\n", i)
+			}
+			io.WriteString(w, "#EXT-X-ENDLIST\n")
+			return
+		}
+		var i int
+		if _, err := fmt.Sscanf(r.URL.Path, "/segment%d.ts", &i); err != nil || i >= len(segments) {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, segments[i])
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/index.m3u8"
+}
+
+// setup points the path prefix at a temporary folder and records channels from playlistURL.
+// The recordings of earlier tests are forgotten, saved recordings are loaded by Init.
+func setup(t *testing.T, playlistURL string) {
+	t.Helper()
+	config.Cfg.PathPrefix = t.TempDir()
+	store.KVS = store.NewMemoryStore()
+	scheduler.Init()
+	t.Cleanup(scheduler.Stop)
+	mu.Lock()
+	recordings, cancels = nil, nil
+	mu.Unlock()
+	mediaPlaylistURL = func(*television.Television, string, hls.VariantFilter) (string, error) {
+		return playlistURL, nil
+	}
+	t.Cleanup(func() { mediaPlaylistURL = (*television.Television).MediaPlaylistURL })
+}
+
+// initRecorder loads the saved recordings with every channel played by a Television without login
+func initRecorder(t *testing.T) {
+	t.Helper()
+	err := Init(func(channelID string, fn func(*television.Television) error) error {
+		return fn(television.New(nil))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor waits until the recording has reached status
+func waitFor(t *testing.T, id string, status Status) *Recording {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		rec, err := Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Status == status {
+			return rec
+		}
+	}
+	rec, _ := Get(id)
+	t.Fatalf("recording %s has status %s, want %s", id, rec.Status, status)
+	return nil
+}
+
+// saved returns the recordings in recordings.json by ID
+func saved(t *testing.T) map[string]Recording {
+	t.Helper()
+	data, err := os.ReadFile(utils.GetPathPrefix() + RECORDINGS_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []Recording
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]Recording)
+	for _, rec := range list {
+		result[rec.ID] = rec
+	}
+	return result
+}
+
+func TestRecord(t *testing.T) {
+	setup(t, upstream(t, "first", "second segment"))
+	initRecorder(t)
+
+	rec, err := Schedule(Request{ChannelID: "143", ChannelName: "Colors HD", Start: time.Now(), End: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rec.Title, "Colors HD ") || rec.Status != StatusScheduled {
+		t.Errorf("Schedule() = %+v", rec)
+	}
+	rec = waitFor(t, rec.ID, StatusCompleted)
+	if rec.Error != "" || rec.Size != 19 || rec.Duration != 4 {
+		t.Errorf("completed recording = %+v", rec)
+	}
+
+	data, err := os.ReadFile(FilePath(rec))
+	if err != nil || string(data) != "firstsecond segment" {
+		t.Errorf("recorded file = %q, %v", data, err)
+	}
+	index, err := ReadIndex(rec)
+	want := []IndexEntry{{Offset: 0, Length: 5, Duration: 2}, {Offset: 5, Length: 14, Duration: 2}}
+	if err != nil || fmt.Sprint(index) != fmt.Sprint(want) {
+		t.Errorf("ReadIndex() = %v, %v, want %v", index, err, want)
+	}
+	if got := saved(t)[rec.ID]; got.Status != StatusCompleted || got.Size != 19 {
+		t.Errorf("saved recording = %+v", got)
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	setup(t, "")
+	mediaPlaylistURL = func(*television.Television, string, hls.VariantFilter) (string, error) {
+		return "", television.ErrNotSubscribed
+	}
+	initRecorder(t)
+
+	rec, err := Schedule(Request{ChannelID: "143", Title: "News", Start: time.Now(), End: time.Now().Add(300 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing was recorded, so the recording failed
+	rec = waitFor(t, rec.ID, StatusFailed)
+	if !strings.Contains(rec.Error, television.ErrNotSubscribed.Error()) {
+		t.Errorf("failed recording error = %q", rec.Error)
+	}
+}
+
+func TestScheduleStopDelete(t *testing.T) {
+	setup(t, "")
+	initRecorder(t)
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	req := Request{ChannelID: "143", Title: "News", Start: start, End: start.Add(30 * time.Minute)}
+
+	for _, tt := range []struct {
+		name string
+		req  Request
+		want error
+	}{
+		{"no channel", Request{Title: "News", Start: start, End: start.Add(time.Minute)}, ErrMissingChannel},
+		{"ends before start", Request{ChannelID: "143", Start: start, End: start.Add(-time.Minute)}, ErrInvalidTime},
+		{"ended", Request{ChannelID: "143", Start: time.Now().Add(-time.Hour), End: time.Now().Add(-time.Minute)}, ErrInvalidTime},
+	} {
+		if _, err := Schedule(tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("Schedule() %s = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	rec, err := Schedule(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Schedule(req); !errors.Is(err, ErrAlreadyScheduled) {
+		t.Errorf("Schedule() again = %v, want %v", err, ErrAlreadyScheduled)
+	}
+	if got := saved(t)[rec.ID]; got.Status != StatusScheduled || !got.Start.Equal(start) {
+		t.Errorf("saved recording = %+v", got)
+	}
+
+	stopped, err := Stop(rec.ID)
+	if err != nil || stopped.Status != StatusCancelled {
+		t.Errorf("Stop() = %+v, %v", stopped, err)
+	}
+	if got := saved(t)[rec.ID]; got.Status != StatusCancelled {
+		t.Errorf("saved recording after Stop() = %+v", got)
+	}
+	// Users may schedule a cancelled programme again, rules may not
+	ruled := req
+	ruled.RuleID = "rule"
+	if _, err := Schedule(ruled); !errors.Is(err, ErrAlreadyScheduled) {
+		t.Errorf("Schedule() of a cancelled programme by a rule = %v, want %v", err, ErrAlreadyScheduled)
+	}
+	again, err := Schedule(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renamed, err := Rename(again.ID, "  Evening News ")
+	if err != nil || renamed.Title != "Evening News" {
+		t.Errorf("Rename() = %+v, %v", renamed, err)
+	}
+	if _, err := Rename(again.ID, " "); !errors.Is(err, ErrMissingTitle) {
+		t.Errorf("Rename() to empty title = %v, want %v", err, ErrMissingTitle)
+	}
+
+	// Deleting removes the recording with its files
+	for _, file := range []string{FilePath(again), indexPath(again)} {
+		if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Delete(again.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{FilePath(again), indexPath(again)} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", file, err)
+		}
+	}
+	if _, err := Get(again.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() = %v, want %v", err, ErrNotFound)
+	}
+	if _, ok := saved(t)[again.ID]; ok {
+		t.Error("deleted recording is still saved")
+	}
+	for name, err := range map[string]error{"Delete": Delete("missing"), "Stop": second(Stop("missing")), "Rename": second(Rename("missing", "title"))} {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s() of a missing recording = %v, want %v", name, err, ErrNotFound)
+		}
+	}
+}
+
+func second(_ *Recording, err error) error {
+	return err
+}
+
+func TestRestart(t *testing.T) {
+	setup(t, upstream(t, "resumed"))
+	now := time.Now()
+	saveFixture := []*Recording{
+		{ID: "running", ChannelID: "143", Status: StatusRecording, Start: now.Add(-time.Minute), End: now.Add(time.Minute), File: "running.ts", Size: 3},
+		{ID: "interrupted", ChannelID: "143", Status: StatusRecording, Start: now.Add(-time.Hour), End: now.Add(-time.Minute), File: "interrupted.ts", Size: 3},
+		{ID: "empty", ChannelID: "143", Status: StatusRecording, Start: now.Add(-time.Hour), End: now.Add(-time.Minute), File: "empty.ts"},
+		{ID: "missed", ChannelID: "143", Status: StatusScheduled, Start: now.Add(-time.Hour), End: now.Add(-time.Minute), File: "missed.ts"},
+		{ID: "pending", ChannelID: "143", Status: StatusScheduled, Start: now.Add(time.Hour), End: now.Add(2 * time.Hour), File: "pending.ts"},
+		{ID: "done", ChannelID: "143", Status: StatusCompleted, Start: now.Add(-time.Hour), End: now.Add(-time.Minute), File: "done.ts", Size: 10},
+		{ID: "cancelled", ChannelID: "143", Status: StatusCancelled, Start: now.Add(time.Hour), End: now.Add(2 * time.Hour), File: "cancelled.ts"},
+	}
+	data, err := json.Marshal(saveFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(utils.GetPathPrefix()+RECORDINGS_FILE, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// What the running recording wrote before the restart
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	running := saveFixture[0]
+	if err := os.WriteFile(FilePath(running), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(indexPath(running), []byte("0 3 2.000 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	initRecorder(t)
+	for _, tt := range []struct {
+		id     string
+		status Status
+		err    string
+	}{
+		{"interrupted", StatusCompleted, "interrupted by a restart"},
+		{"empty", StatusFailed, "interrupted by a restart"},
+		{"missed", StatusFailed, "missed while the server was not running"},
+		{"pending", StatusScheduled, ""},
+		{"done", StatusCompleted, ""},
+		{"cancelled", StatusCancelled, ""},
+	} {
+		rec, err := Get(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Status != tt.status || rec.Error != tt.err {
+			t.Errorf("recording %s after restart = %s %q, want %s %q", tt.id, rec.Status, rec.Error, tt.status, tt.err)
+		}
+		if got := saved(t)[tt.id]; got.Status != tt.status {
+			t.Errorf("saved recording %s = %s, want %s", tt.id, got.Status, tt.status)
+		}
+	}
+
+	// The running recording continues after a gap
+	rec := waitFor(t, "running", StatusCompleted)
+	if content, err := os.ReadFile(FilePath(rec)); err != nil || string(content) != "oldresumed" {
+		t.Errorf("resumed file = %q, %v", content, err)
+	}
+	index, err := ReadIndex(rec)
+	want := []IndexEntry{{Offset: 0, Length: 3, Duration: 2}, {Offset: 3, Length: 7, Duration: 2, Discontinuity: true}}
+	if err != nil || fmt.Sprint(index) != fmt.Sprint(want) {
+		t.Errorf("ReadIndex() of resumed recording = %v, %v, want %v", index, err, want)
+	}
+
+	// Later calls of Init keep the recordings of this process
+	Stop("pending")
+	initRecorder(t)
+	if rec, _ := Get("pending"); rec.Status != StatusCancelled {
+		t.Errorf("second Init() reloaded the recordings: %+v", rec)
+	}
+}
@@ -0,0 +1,231 @@
+package recorder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// RULES_FILE stores the recording rules next to the TOML store
+const RULES_FILE = "recording_rules.toml"
+
+// Errors
+var (
+	ErrRuleNotFound = errors.New("recording rule not found")
+	ErrInvalidRule  = errors.New("recording rule needs a channel ID and a title")
+)
+
+var rulesMu sync.Mutex
+
+// Rules returns all recording rules
+func Rules() ([]Rule, error) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	return loadRules()
+}
+
+// GetRule returns the rule with the given ID
+func GetRule(id string) (*Rule, error) {
+	rules, err := Rules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.ID == id {
+			return &rule, nil
+		}
+	}
+	return nil, ErrRuleNotFound
+}
+
+// AddRule saves a new recording rule
+func AddRule(rule Rule) (*Rule, error) {
+	if err := normalizeRule(&rule); err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = time.Now()
+
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules, err := loadRules()
+	if err != nil {
+		return nil, err
+	}
+	if err := saveRules(append(rules, rule)); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule replaces the rule with the ID of rule
+func UpdateRule(rule Rule) (*Rule, error) {
+	if err := normalizeRule(&rule); err != nil {
+		return nil, err
+	}
+
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules, err := loadRules()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].ID == rule.ID {
+			rule.CreatedAt = rules[i].CreatedAt
+			rules[i] = rule
+			return &rule, saveRules(rules)
+		}
+	}
+	return nil, ErrRuleNotFound
+}
+
+// DeleteRule removes a recording rule. Recordings it scheduled are kept.
+func DeleteRule(id string) error {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules, err := loadRules()
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].ID == id {
+			return saveRules(append(rules[:i], rules[i+1:]...))
+		}
+	}
+	return ErrRuleNotFound
+}
+
+// Matches reports whether a programme should be recorded by the rule
+func (r Rule) Matches(programme epg.Programme) bool {
+	if !r.Enabled || programme.Channel != r.ChannelID {
+		return false
+	}
+	title := strings.ToLower(programme.Title.Value)
+	if !strings.Contains(title, strings.ToLower(r.Title)) {
+		return false
+	}
+	if r.Category != "" && !strings.EqualFold(programme.Category.Value, r.Category) {
+		return false
+	}
+	text := title + " " + strings.ToLower(programme.Desc.Value)
+	for _, keyword := range r.Keywords {
+		if !strings.Contains(text, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	return true
+}
+
+// EvaluateRules schedules recordings for every upcoming programme in the EPG matching an enabled rule.
+// Programmes that were recorded or scheduled before, even if cancelled, are skipped.
+// It returns the number of recordings scheduled.
+func EvaluateRules() (int, error) {
+	rules, err := Rules()
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+	guide, err := epg.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	scheduled := 0
+	for _, programme := range guide.Programme {
+		for _, rule := range rules {
+			if !rule.Matches(programme) {
+				continue
+			}
+			if stop, err := programme.StopTime(); err != nil || !stop.After(now) {
+				break
+			}
+			rec, err := scheduleProgramme(programme, rule.ID)
+			if err == nil {
+				utils.Log.Printf("Rule %s scheduled recording %s of %q", rule.ID, rec.ID, rec.Title)
+				scheduled++
+			} else if !errors.Is(err, ErrAlreadyScheduled) {
+				utils.Log.Printf("Rule %s failed to schedule %q: %v", rule.ID, programme.Title.Value, err)
+			}
+			break
+		}
+	}
+	return scheduled, nil
+}
+
+// evaluateRules runs EvaluateRules and logs the outcome
+func evaluateRules() {
+	scheduled, err := EvaluateRules()
+	if err != nil {
+		utils.Log.Println("Failed to evaluate recording rules:", err)
+		return
+	}
+	utils.Log.Println("Recording rules scheduled", scheduled, "recordings")
+}
+
+// normalizeRule validates rule and drops empty keywords
+func normalizeRule(rule *Rule) error {
+	rule.ChannelID = strings.TrimSpace(rule.ChannelID)
+	rule.Title = strings.TrimSpace(rule.Title)
+	if rule.ChannelID == "" || rule.Title == "" {
+		return ErrInvalidRule
+	}
+	keywords := rule.Keywords[:0]
+	for _, keyword := range rule.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	rule.Keywords = keywords
+	rule.Category = strings.TrimSpace(rule.Category)
+	return nil
+}
+
+// rulesPath returns the path of the rules file
+func rulesPath() string {
+	return filepath.Join(utils.GetPathPrefix(), RULES_FILE)
+}
+
+// loadRules reads the rules file. rulesMu must be held.
+func loadRules() ([]Rule, error) {
+	var file rulesFile
+	if _, err := toml.DecodeFile(rulesPath(), &file); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file.Rules, nil
+}
+
+// saveRules writes the rules file. rulesMu must be held.
+func saveRules(rules []Rule) error {
+	filename := rulesPath()
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(file).Encode(rulesFile{Rules: rules}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,103 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+// programme returns an EPG programme of channel 143 starting in start
+func programme(title, desc, category string, start time.Duration) epg.Programme {
+	now := time.Now().Truncate(time.Second)
+	return epg.Programme{
+		Channel:  "143",
+		Start:    now.Add(start).Format(epg.TIME_FORMAT),
+		Stop:     now.Add(start + 30*time.Minute).Format(epg.TIME_FORMAT),
+		Title:    epg.Title{Value: title},
+		Desc:     epg.Desc{Value: desc},
+		Category: epg.Category{Value: category},
+	}
+}
+
+func TestMatches(t *testing.T) {
+	news := programme("Evening News", "Headlines from Mumbai and Delhi", "News", time.Hour)
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"title", Rule{ChannelID: "143", Title: "news", Enabled: true}, true},
+		{"other title", Rule{ChannelID: "143", Title: "Cricket", Enabled: true}, false},
+		{"disabled", Rule{ChannelID: "143", Title: "News"}, false},
+		{"other channel", Rule{ChannelID: "144", Title: "News", Enabled: true}, false},
+		{"category", Rule{ChannelID: "143", Title: "News", Category: "news", Enabled: true}, true},
+		{"other category", Rule{ChannelID: "143", Title: "News", Category: "Sports", Enabled: true}, false},
+		{"keywords in description", Rule{ChannelID: "143", Title: "News", Keywords: []string{"mumbai", "DELHI"}, Enabled: true}, true},
+		{"keyword in title", Rule{ChannelID: "143", Title: "News", Keywords: []string{"evening"}, Enabled: true}, true},
+		{"missing keyword", Rule{ChannelID: "143", Title: "News", Keywords: []string{"Mumbai", "Chennai"}, Enabled: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Matches(news); got != tt.want {
+			t.Errorf("Matches() with %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// writeEPG writes the programmes as the generated EPG
+func writeEPG(t *testing.T, programmes ...epg.Programme) {
+	t.Helper()
+	file, err := os.Create(utils.GetPathPrefix() + "epg.xml.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	if err := xml.NewEncoder(gz).Encode(epg.EPG{Programme: programmes}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	setup(t, "")
+	initRecorder(t)
+	writeEPG(t,
+		programme("Evening News", "", "News", time.Hour),
+		programme("Late News", "", "News", 2*time.Hour),
+		programme("Morning News", "", "News", -time.Hour),
+		programme("Cricket Live", "", "Sports", 3*time.Hour),
+	)
+	if _, err := AddRule(Rule{ChannelID: "143", Title: "news", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Programmes that have ended are skipped
+	if scheduled, err := EvaluateRules(); err != nil || scheduled != 2 {
+		t.Fatalf("EvaluateRules() = %d, %v, want 2", scheduled, err)
+	}
+	list := List()
+	if len(list) != 2 || list[0].Title != "Evening News" || list[1].Title != "Late News" {
+		t.Fatalf("recordings = %+v", list)
+	}
+
+	// Renamed and cancelled recordings are not scheduled again
+	if _, err := Rename(list[0].ID, "News at Seven"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Stop(list[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if scheduled, err := EvaluateRules(); err != nil || scheduled != 0 {
+		t.Errorf("EvaluateRules() again = %d, %v, want 0", scheduled, err)
+	}
+	if list := List(); len(list) != 2 {
+		t.Errorf("recordings after evaluating again = %+v", list)
+	}
+}
@@ -0,0 +1,72 @@
+package recorder
+
+import "time"
+
+// Status is the state of a recording
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusRecording Status = "recording"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Recording is a scheduled, running or finished recording of a channel
+type Recording struct {
+	ID          string    `json:"id"`
+	ChannelID   string    `json:"channel_id"`
+	ChannelName string    `json:"channel_name,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Poster      string    `json:"poster,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Status      Status    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	File        string    `json:"file,omitempty"`    // File name inside the recordings folder
+	Size        int64     `json:"size"`              // Size of the recorded file in bytes
+	Duration    float64   `json:"duration"`          // Recorded duration in seconds
+	RuleID      string    `json:"rule_id,omitempty"` // Rule that scheduled the recording
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Request describes a recording to schedule
+type Request struct {
+	ChannelID   string
+	ChannelName string
+	Title       string
+	Description string
+	Category    string
+	Poster      string
+	Start       time.Time
+	End         time.Time
+	RuleID      string
+}
+
+// IndexEntry locates a recorded segment inside the recording file
+type IndexEntry struct {
+	Offset        int64
+	Length        int64
+	Duration      float64
+	Discontinuity bool
+}
+
+// Rule schedules recordings of every programme on a channel whose title contains Title.
+// Optional keywords must all appear in the title or description, and the category must match if set.
+type Rule struct {
+	ID        string    `json:"id" toml:"id"`
+	ChannelID string    `json:"channel_id" toml:"channel_id"`
+	Title     string    `json:"title" toml:"title"`
+	Keywords  []string  `json:"keywords" toml:"keywords"`
+	Category  string    `json:"category" toml:"category"`
+	Enabled   bool      `json:"enabled" toml:"enabled"`
+	CreatedAt time.Time `json:"created_at" toml:"created_at"`
+}
+
+// rulesFile is the structure of the rules TOML file
+type rulesFile struct {
+	Rules []Rule `toml:"rules"`
+}
@@ -0,0 +1,46 @@
+package recorder
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// VODPlaylist builds a media playlist addressing the segments of the recorded file with byte ranges.
+// segmentURI is used as the URI of every segment. Recordings still running get an EVENT playlist
+// that players reload for new segments.
+func VODPlaylist(rec *Recording, segmentURI string) ([]byte, error) {
+	index, err := ReadIndex(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	targetDuration := 1.0
+	for _, entry := range index {
+		targetDuration = math.Max(targetDuration, math.Ceil(entry.Duration))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	// Byte ranges need version 4
+	b.WriteString("#EXT-X-VERSION:4\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(targetDuration))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	if rec.Status == StatusRecording {
+		b.WriteString("#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	} else {
+		b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	}
+	for i, entry := range index {
+		if entry.Discontinuity && i > 0 {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", entry.Duration)
+		fmt.Fprintf(&b, "#EXT-X-BYTERANGE:%d@%d\n", entry.Length, entry.Offset)
+		b.WriteString(segmentURI + "\n")
+	}
+	if rec.Status != StatusRecording {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.Bytes(), nil
+}
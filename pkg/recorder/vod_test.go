@@ -0,0 +1,55 @@
+package recorder
+
+import (
+	"os"
+	"testing"
+)
+
+func TestVODPlaylist(t *testing.T) {
+	setup(t, "")
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rec := &Recording{ID: "news", File: "news.ts"}
+	// Three segments, the recording was resumed before the third. The last line was cut by a crash.
+	index := "0 188 4.000 0\n188 376 4.000 0\n564 188 6.480 1\n752 18"
+	if err := os.WriteFile(indexPath(rec), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	segments := "#EXTINF:4.000,\n#EXT-X-BYTERANGE:188@0\nnews.ts\n" +
+		"#EXTINF:4.000,\n#EXT-X-BYTERANGE:376@188\nnews.ts\n" +
+		"#EXT-X-DISCONTINUITY\n#EXTINF:6.480,\n#EXT-X-BYTERANGE:188@564\nnews.ts\n"
+	header := "#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-TARGETDURATION:7\n#EXT-X-MEDIA-SEQUENCE:0\n"
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{StatusRecording, header + "#EXT-X-PLAYLIST-TYPE:EVENT\n" + segments},
+		{StatusCompleted, header + "#EXT-X-PLAYLIST-TYPE:VOD\n" + segments + "#EXT-X-ENDLIST\n"},
+		{StatusFailed, header + "#EXT-X-PLAYLIST-TYPE:VOD\n" + segments + "#EXT-X-ENDLIST\n"},
+	}
+	for _, tt := range tests {
+		rec.Status = tt.status
+		got, err := VODPlaylist(rec, "news.ts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("VODPlaylist() of %s recording =\n%s\nwant\n%s", tt.status, got, tt.want)
+		}
+	}
+
+	// A recording that has not written a segment yet has an empty playlist
+	empty := &Recording{ID: "empty", File: "empty.ts", Status: StatusRecording}
+	if err := os.WriteFile(indexPath(empty), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:EVENT\n"
+	if got, err := VODPlaylist(empty, "empty.ts"); err != nil || string(got) != want {
+		t.Errorf("VODPlaylist() without segments = %q, %v, want %q", got, err, want)
+	}
+	if _, err := VODPlaylist(&Recording{ID: "missing", File: "missing.ts"}, "missing.ts"); !os.IsNotExist(err) {
+		t.Errorf("VODPlaylist() without index = %v, want a missing file", err)
+	}
+}
@@ -39,3 +39,37 @@ func Add(id string, interval time.Duration, task func() error) {
 	}
 	utils.Log.Printf("Task added with ID: %v\n", id)
 }
+
+// AddOnce schedules task to run a single time at the given time.
+// Tasks scheduled in the past run right away. A task may schedule itself again with the same ID.
+func AddOnce(id string, at time.Time, task func() error) {
+	delay := time.Until(at)
+	if delay <= 0 {
+		delay = time.Millisecond
+	}
+	// delete any existing task with the same ID
+	Scheduler.Del(id)
+	// RunOnce deletes the task by ID after it ran, which would also delete a task it scheduled again.
+	// The task deletes itself before it runs instead.
+	err := Scheduler.AddWithID(id, &tasks.Task{
+		Interval:          delay,
+		RunSingleInstance: true,
+		TaskFunc: func() error {
+			Scheduler.Del(id)
+			return task()
+		},
+		ErrFunc: func(err error) {
+			utils.Log.Printf("Task failed: %v\n", err)
+		},
+	})
+	if err != nil {
+		utils.Log.Printf("Failed to add task: %v\n", err)
+		return
+	}
+	utils.Log.Printf("Task added with ID: %v, runs at %v\n", id, at.Local())
+}
+
+// Remove deletes a scheduled task
+func Remove(id string) {
+	Scheduler.Del(id)
+}
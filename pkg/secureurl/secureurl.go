@@ -4,18 +4,60 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/store"
 	"github.com/Varun03-max/JIO/pkg/utils"
 )
 
+const (
+	// TOKEN_VERSION is the first byte of every token
+	TOKEN_VERSION = 1
+	// KEYS_STORE_KEY is the store key the encryption keys are persisted under
+	KEYS_STORE_KEY = "urlKeys"
+	// KEY_ROTATION_INTERVAL is how long a key is used to encrypt new tokens
+	KEY_ROTATION_INTERVAL = 7 * 24 * time.Hour
+	// DEFAULT_TOKEN_EXPIRY is used when no expiry is configured
+	DEFAULT_TOKEN_EXPIRY = 24 * time.Hour
+	// MAX_CLOCK_SKEW is how far in the future a token may have been issued
+	MAX_CLOCK_SKEW = time.Minute
+
+	keyIDSize  = 4
+	headerSize = 1 + keyIDSize
+	// issue and expiry times precede the URL in the plaintext
+	timesSize = 16
+)
+
+// Errors
+var (
+	ErrInvalidToken = errors.New("invalid or tampered URL token")
+	ErrTokenExpired = errors.New("URL token expired")
+)
+
+// urlKey is an AES-256 key tokens are encrypted with
+type urlKey struct {
+	id      []byte
+	created time.Time
+	key     []byte
+	aead    cipher.AEAD
+}
+
 var (
-	key                  []byte
+	mu sync.RWMutex
+	// keys are ordered newest first. The first key encrypts new tokens,
+	// the previous one still decrypts tokens issued before the last rotation.
+	keys                 []*urlKey
+	tokenExpiry          time.Duration
 	disableUrlEncryption bool
 )
 
@@ -28,58 +70,181 @@ func generateKey() []byte {
 	return key
 }
 
+// newURLKey prepares key for use
+func newURLKey(key []byte, created time.Time) (*urlKey, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &urlKey{
+		id:      sum[:keyIDSize],
+		created: created,
+		key:     key,
+		aead:    aead,
+	}, nil
+}
+
+// EncryptURL encrypts inputURL into a token that can be passed as a query parameter.
+// The token is authenticated and expires after the configured expiry.
 func EncryptURL(inputURL string) (string, error) {
 	if disableUrlEncryption {
 		return url.QueryEscape(inputURL), nil
 	}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(keys) == 0 {
+		return "", errors.New("secureurl is not initialized")
 	}
+	current := keys[0]
 
-	ciphertext := make([]byte, aes.BlockSize+len(inputURL))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+	header := append([]byte{TOKEN_VERSION}, current.id...)
+	nonce := make([]byte, current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
 		return "", err
 	}
+	now := time.Now()
+	plaintext := make([]byte, timesSize, timesSize+len(inputURL))
+	binary.BigEndian.PutUint64(plaintext[:8], uint64(now.Unix()))
+	binary.BigEndian.PutUint64(plaintext[8:], uint64(now.Add(tokenExpiry).Unix()))
+	plaintext = append(plaintext, inputURL...)
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], []byte(inputURL))
-
-	encryptedURL := base64.URLEncoding.EncodeToString(ciphertext)
-	return encryptedURL, nil
+	token := append(header, nonce...)
+	token = current.aead.Seal(token, nonce, plaintext, header)
+	return base64.RawURLEncoding.EncodeToString(token), nil
 }
 
+// DecryptURL returns the URL of a token created by EncryptURL.
+// Tokens that were modified or encrypted with an unknown key return ErrInvalidToken,
+// tokens past their expiry return ErrTokenExpired.
 func DecryptURL(encryptedURL string) (string, error) {
 	if disableUrlEncryption {
 		decoded_url, err := url.QueryUnescape(encryptedURL)
 		return decoded_url, err
 	}
 
-	ciphertext, err := base64.URLEncoding.DecodeString(encryptedURL)
+	token, err := base64.RawURLEncoding.DecodeString(encryptedURL)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(token) < headerSize || token[0] != TOKEN_VERSION {
+		return "", ErrInvalidToken
 	}
+	header := token[:headerSize]
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+	mu.RLock()
+	var key *urlKey
+	for _, k := range keys {
+		if string(k.id) == string(header[1:]) {
+			key = k
+			break
+		}
 	}
+	mu.RUnlock()
+	if key == nil {
+		return "", fmt.Errorf("%w: unknown key", ErrInvalidToken)
+	}
+
+	nonceSize := key.aead.NonceSize()
+	if len(token) < headerSize+nonceSize {
+		return "", ErrInvalidToken
+	}
+	nonce := token[headerSize : headerSize+nonceSize]
+	plaintext, err := key.aead.Open(nil, nonce, token[headerSize+nonceSize:], header)
+	if err != nil || len(plaintext) < timesSize {
+		return "", ErrInvalidToken
+	}
+
+	now := time.Now()
+	issued := time.Unix(int64(binary.BigEndian.Uint64(plaintext[:8])), 0)
+	expires := time.Unix(int64(binary.BigEndian.Uint64(plaintext[8:timesSize])), 0)
+	if issued.After(now.Add(MAX_CLOCK_SKEW)) {
+		return "", fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+	if now.After(expires) {
+		return "", ErrTokenExpired
+	}
+	return string(plaintext[timesSize:]), nil
+}
 
-	if len(ciphertext) < aes.BlockSize {
-		return "", errors.New("ciphertext too short")
+// Rotate generates a new key for new tokens.
+// Tokens encrypted with the previous key stay valid until they expire, older keys are dropped.
+func Rotate() error {
+	if disableUrlEncryption {
+		return nil
+	}
+	key, err := newURLKey(generateKey(), time.Now())
+	if err != nil {
+		return err
 	}
 
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
+	mu.Lock()
+	defer mu.Unlock()
+	keys = append([]*urlKey{key}, keys...)
+	if len(keys) > 2 {
+		keys = keys[:2]
+	}
+	utils.Log.Println("Rotated URL encryption key")
+	return saveKeys()
+}
 
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
+// RotateIfDue rotates the key once it was used for KEY_ROTATION_INTERVAL
+func RotateIfDue() error {
+	mu.RLock()
+	due := len(keys) == 0 || time.Since(keys[0].created) >= KEY_ROTATION_INTERVAL
+	mu.RUnlock()
+	if !due {
+		return nil
+	}
+	return Rotate()
+}
 
-	decryptedURL := string(ciphertext)
+// loadKeys reads the keys from the store.
+// Keys are stored as comma separated `<created unix time>:<base64 key>` pairs, newest first.
+func loadKeys() error {
+	value, err := store.Get(KEYS_STORE_KEY)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+	var loaded []*urlKey
+	for _, entry := range strings.Split(value, ",") {
+		created, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return errors.New("invalid URL key entry in store")
+		}
+		unix, err := strconv.ParseInt(created, 10, 64)
+		if err != nil {
+			return err
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+		key, err := newURLKey(raw, time.Unix(unix, 0))
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, key)
+	}
+	keys = loaded
+	return nil
+}
 
-	return decryptedURL, nil
+// saveKeys persists the keys in the store. mu must be held.
+func saveKeys() error {
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, strconv.FormatInt(key.created.Unix(), 10)+":"+base64.StdEncoding.EncodeToString(key.key))
+	}
+	return store.Set(KEYS_STORE_KEY, strings.Join(entries, ","))
 }
 
 func Init() {
@@ -88,5 +253,18 @@ func Init() {
 		fmt.Println("Warning! URL encryption is disabled. Anyone can pass modified URLs to your server.")
 		return
 	}
-	key = generateKey()
+	tokenExpiry = DEFAULT_TOKEN_EXPIRY
+	if config.Cfg.URLTokenExpiry > 0 {
+		tokenExpiry = time.Duration(config.Cfg.URLTokenExpiry) * time.Hour
+	}
+
+	mu.Lock()
+	if err := loadKeys(); err != nil {
+		utils.Log.Println("Failed to load URL encryption keys, generating new ones:", err)
+		keys = nil
+	}
+	mu.Unlock()
+	if err := RotateIfDue(); err != nil {
+		utils.Log.Println("Failed to save URL encryption key:", err)
+	}
 }
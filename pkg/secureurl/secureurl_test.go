@@ -0,0 +1,211 @@
+package secureurl
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const testURL = "https://jiotvmblive.cdn.jio.com/bpk-tv/Colors_HD/index.m3u8?hdnea=exp=1700000000~hmac=abc"
+
+func TestMain(m *testing.M) {
+	utils.Log = log.New(io.Discard, "", 0)
+	os.Exit(m.Run())
+}
+
+// setup starts with an empty store and no keys
+func setup(t *testing.T) {
+	t.Helper()
+	store.KVS = store.NewMemoryStore()
+	config.Cfg.DisableURLEncryption = false
+	config.Cfg.URLTokenExpiry = 0
+	mu.Lock()
+	keys = nil
+	mu.Unlock()
+	Init()
+}
+
+// encrypt returns the token of testURL
+func encrypt(t *testing.T) string {
+	t.Helper()
+	token, err := EncryptURL(testURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRoundTrip(t *testing.T) {
+	setup(t)
+	token := encrypt(t)
+	if strings.Contains(token, "jio.com") {
+		t.Errorf("token %q contains the URL", token)
+	}
+	if got, err := DecryptURL(token); err != nil || got != testURL {
+		t.Errorf("DecryptURL() = %q, %v, want %q", got, err, testURL)
+	}
+	if other := encrypt(t); other == token {
+		t.Error("tokens of the same URL are equal")
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	setup(t)
+	raw, err := base64.RawURLEncoding.DecodeString(encrypt(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamper := func(i int) string {
+		modified := append([]byte(nil), raw...)
+		modified[i] ^= 1
+		return base64.RawURLEncoding.EncodeToString(modified)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a token!"},
+		{"empty", ""},
+		{"version", tamper(0)},
+		{"key ID", tamper(1)},
+		{"nonce", tamper(headerSize)},
+		{"ciphertext", tamper(len(raw) - 20)},
+		{"tag", tamper(len(raw) - 1)},
+		{"truncated", base64.RawURLEncoding.EncodeToString(raw[:headerSize+4])},
+	}
+	for _, tt := range tests {
+		if got, err := DecryptURL(tt.token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("DecryptURL() of %s token = %q, %v, want %v", tt.name, got, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	setup(t)
+	tokenExpiry = -time.Second
+	token := encrypt(t)
+	if _, err := DecryptURL(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("DecryptURL() of expired token = %v, want %v", err, ErrTokenExpired)
+	}
+
+	// The configured expiry is used for new tokens
+	config.Cfg.URLTokenExpiry = 2
+	Init()
+	if tokenExpiry != 2*time.Hour {
+		t.Errorf("token expiry = %v, want 2h", tokenExpiry)
+	}
+	if _, err := DecryptURL(encrypt(t)); err != nil {
+		t.Errorf("DecryptURL() = %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	setup(t)
+	oldest := encrypt(t)
+	if err := Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	previous := encrypt(t)
+
+	// Tokens of the previous key stay valid
+	for _, token := range []string{oldest, previous} {
+		if got, err := DecryptURL(token); err != nil || got != testURL {
+			t.Errorf("DecryptURL() after Rotate() = %q, %v", got, err)
+		}
+	}
+
+	// Two rotations drop the first key
+	if err := Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("%d keys after rotating twice, want 2", len(keys))
+	}
+	if _, err := DecryptURL(oldest); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("DecryptURL() under dropped key = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := DecryptURL(previous); err != nil {
+		t.Errorf("DecryptURL() under previous key = %v", err)
+	}
+
+	// Keys are rotated once they are old
+	current := keys[0]
+	if err := RotateIfDue(); err != nil || keys[0] != current {
+		t.Errorf("RotateIfDue() rotated a new key: %v", err)
+	}
+	current.created = time.Now().Add(-KEY_ROTATION_INTERVAL)
+	if err := RotateIfDue(); err != nil || keys[0] == current || keys[1] != current {
+		t.Errorf("RotateIfDue() did not rotate an old key: %v", err)
+	}
+}
+
+func TestStoredKeys(t *testing.T) {
+	setup(t)
+	if err := Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	token := encrypt(t)
+	value, err := store.Get(KEYS_STORE_KEY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := strings.Split(value, ","); len(entries) != 2 {
+		t.Errorf("stored keys = %q, want 2 entries", value)
+	}
+
+	// A restart loads the keys, tokens issued before stay valid
+	mu.Lock()
+	keys = nil
+	mu.Unlock()
+	Init()
+	if len(keys) != 2 {
+		t.Fatalf("%d keys loaded, want 2", len(keys))
+	}
+	if got, err := DecryptURL(token); err != nil || got != testURL {
+		t.Errorf("DecryptURL() after restart = %q, %v", got, err)
+	}
+	if again, _ := store.Get(KEYS_STORE_KEY); again != value {
+		t.Errorf("stored keys changed on restart: %q, want %q", again, value)
+	}
+
+	// Keys that cannot be read are replaced
+	for _, value := range []string{"garbage", "1700000000:not base64", "abc:" + base64.StdEncoding.EncodeToString(make([]byte, 32)), "1700000000:" + base64.StdEncoding.EncodeToString(make([]byte, 5))} {
+		if err := store.Set(KEYS_STORE_KEY, value); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		err := loadKeys()
+		mu.Unlock()
+		if err == nil {
+			t.Errorf("loadKeys() of %q succeeded", value)
+		}
+		Init()
+		if len(keys) != 1 {
+			t.Errorf("%d keys after loading %q, want a new key", len(keys), value)
+		}
+		if _, err := DecryptURL(encrypt(t)); err != nil {
+			t.Errorf("DecryptURL() with new key = %v", err)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	setup(t)
+	config.Cfg.DisableURLEncryption = true
+	defer func() { disableUrlEncryption = false }()
+	Init()
+	token := encrypt(t)
+	if got, err := DecryptURL(token); err != nil || got != testURL {
+		t.Errorf("DecryptURL() without encryption = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,181 @@
+package session
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+var (
+	// current holds the logged in accounts, the default account first and the others by name.
+	// It is replaced as a whole, never modified.
+	current     atomic.Pointer[[]Account]
+	hooksMu     sync.Mutex
+	changeHooks []func(string, *utils.JIOTV_CREDENTIALS)
+	// changeMu orders logins and logouts with token refreshes
+	changeMu sync.Mutex
+	// routes maps channel IDs to the name of the account that played them last
+	routes sync.Map
+)
+
+// TV returns the Television of the default account, nil before the first call of Set.
+// Requests should get it once and use it throughout, so they see one consistent set of tokens and headers
+// while a login, logout or token refresh replaces it.
+func TV() *television.Television {
+	return Get(utils.DEFAULT_ACCOUNT)
+}
+
+// Get returns the Television of an account, nil if the account is not logged in
+func Get(account string) *television.Television {
+	accounts := current.Load()
+	if accounts == nil {
+		return nil
+	}
+	for _, a := range *accounts {
+		if a.Name == account {
+			return a.TV
+		}
+	}
+	return nil
+}
+
+// Accounts returns the logged in accounts, the default account first and the others by name
+func Accounts() []Account {
+	accounts := current.Load()
+	if accounts == nil {
+		return nil
+	}
+	var result []Account
+	for _, a := range *accounts {
+		// The default account is kept for requests that need no login
+		if a.TV.Crm != "" {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// OnChange registers a function that is called with the account and its new credentials after a login or logout.
+// Credentials are nil after a logout.
+func OnChange(fn func(string, *utils.JIOTV_CREDENTIALS)) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	changeHooks = append(changeHooks, fn)
+}
+
+// Set replaces the Television of an account after a login or logout
+// and notifies the functions registered with OnChange. Credentials are nil after a logout.
+func Set(account string, credentials *utils.JIOTV_CREDENTIALS) {
+	changeMu.Lock()
+	if credentials == nil && account != utils.DEFAULT_ACCOUNT {
+		replace(account, nil)
+	} else {
+		replace(account, television.New(credentials))
+	}
+	// Channels are routed again, the account may now be entitled to other channels
+	routes.Range(func(channelID, name any) bool {
+		if name == account {
+			routes.Delete(channelID)
+		}
+		return true
+	})
+	changeMu.Unlock()
+
+	hooksMu.Lock()
+	hooks := append([]func(string, *utils.JIOTV_CREDENTIALS){}, changeHooks...)
+	hooksMu.Unlock()
+	for _, hook := range hooks {
+		hook(account, credentials)
+	}
+}
+
+// Update replaces the Television of an account with refreshed tokens.
+// Tokens of another login, like a refresh that finished after a logout, are ignored.
+func Update(account string, credentials *utils.JIOTV_CREDENTIALS) bool {
+	changeMu.Lock()
+	defer changeMu.Unlock()
+	tv := Get(account)
+	if tv == nil || credentials == nil || tv.Crm == "" || tv.Crm != credentials.CRM {
+		return false
+	}
+	replace(account, television.New(credentials))
+	return true
+}
+
+// replace stores a copy of the accounts with the Television of account replaced, or removed if tv is nil.
+// changeMu must be held.
+func replace(account string, tv *television.Television) {
+	var accounts []Account
+	if previous := current.Load(); previous != nil {
+		for _, a := range *previous {
+			if a.Name != account {
+				accounts = append(accounts, a)
+			}
+		}
+	}
+	if tv != nil {
+		accounts = append(accounts, Account{Name: account, TV: tv})
+	}
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].Name == utils.DEFAULT_ACCOUNT || accounts[j].Name == utils.DEFAULT_ACCOUNT {
+			return accounts[i].Name == utils.DEFAULT_ACCOUNT
+		}
+		return accounts[i].Name < accounts[j].Name
+	})
+	current.Store(&accounts)
+}
+
+// ForChannel returns the Television of the account that played a channel last, the default account's otherwise
+func ForChannel(channelID string) *television.Television {
+	if name, ok := routes.Load(channelID); ok {
+		if tv := Get(name.(string)); tv != nil {
+			return tv
+		}
+	}
+	return TV()
+}
+
+// WithChannel calls fn with the Television of the account that is entitled to a channel.
+// The account that played the channel last is tried first. When JioTV rejects an account for the channel,
+// the next one is tried, and the first account that succeeds is remembered for the channel.
+func WithChannel(channelID string, fn func(tv *television.Television) error) error {
+	accounts := Accounts()
+	if len(accounts) == 0 {
+		return fn(TV())
+	}
+	if name, ok := routes.Load(channelID); ok {
+		sort.SliceStable(accounts, func(i, j int) bool {
+			return accounts[i].Name == name && accounts[j].Name != name
+		})
+	}
+
+	var err error
+	for _, account := range accounts {
+		if err = fn(account.TV); err == nil {
+			routes.Store(channelID, account.Name)
+			return nil
+		}
+		if !errors.Is(err, television.ErrUnauthorized) && !errors.Is(err, television.ErrNotSubscribed) {
+			return err
+		}
+		if len(accounts) > 1 {
+			utils.Log.Printf("Account %s can not play channel %s: %v", account.Name, channelID, err)
+		}
+	}
+	return err
+}
+
+// Live returns the live URLs of a channel from the account that is entitled to it
+func Live(channelID string) (*television.LiveURLOutput, error) {
+	var result *television.LiveURLOutput
+	err := WithChannel(channelID, func(tv *television.Television) error {
+		var err error
+		result, err = tv.Live(channelID)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Varun03-max/JIO/pkg/store"
+	"github.com/Varun03-max/JIO/pkg/television"
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log = log.New(io.Discard, "", 0)
+	// Television reads the device ID from the store
+	store.KVS = store.NewMemoryStore()
+	os.Exit(m.Run())
+}
+
+func credentials(n int) *utils.JIOTV_CREDENTIALS {
+	return &utils.JIOTV_CREDENTIALS{
+		CRM:         fmt.Sprintf("crm-%d", n),
+		UniqueID:    fmt.Sprintf("unique-%d", n),
+		SSOToken:    fmt.Sprintf("sso-%d", n),
+		AccessToken: fmt.Sprintf("access-%d", n),
+	}
+}
+
+// TestLoginWhileStreaming swaps logins while streams request segments and checks
+// that every request carries the headers of a single login. Run with -race.
+func TestLoginWhileStreaming(t *testing.T) {
+	var mismatches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Logged out requests carry neither
+		crm, uniqueID := r.Header.Get("crmid"), r.Header.Get("uniqueId")
+		if strings.TrimPrefix(crm, "crm-") != strings.TrimPrefix(uniqueID, "unique-") {
+			mismatches.Add(1)
+		}
+		w.Write([]byte("#EXTM3U\n"))
+	}))
+	defer upstream.Close()
+
+	var notified atomic.Int32
+	OnChange(func(string, *utils.JIOTV_CREDENTIALS) { notified.Add(1) })
+	t.Cleanup(func() {
+		hooksMu.Lock()
+		changeHooks = nil
+		hooksMu.Unlock()
+	})
+	Set(utils.DEFAULT_ACCOUNT, credentials(0))
+
+	stop := make(chan struct{})
+	var streams sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		streams.Add(1)
+		go func() {
+			defer streams.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Like the handlers, take one snapshot per request
+				tv := TV()
+				if _, status, err := tv.Render(upstream.URL + "/index.m3u8"); err != nil || status != http.StatusOK {
+					t.Errorf("Render() = %d, %v", status, err)
+					return
+				}
+				if tv.SsoToken != "sso-"+strings.TrimPrefix(tv.Crm, "crm-") && tv.SsoToken != "" {
+					mismatches.Add(1)
+				}
+			}
+		}()
+	}
+
+	logins := 50
+	for n := 1; n <= logins; n++ {
+		if n%10 == 0 {
+			// Logout
+			Set(utils.DEFAULT_ACCOUNT, nil)
+			continue
+		}
+		Set(utils.DEFAULT_ACCOUNT, credentials(n))
+		// Token refresh of the same login
+		refreshed := credentials(n)
+		refreshed.AccessToken = fmt.Sprintf("access-%d-refreshed", n)
+		Update(utils.DEFAULT_ACCOUNT, refreshed)
+	}
+	close(stop)
+	streams.Wait()
+
+	if mismatches.Load() != 0 {
+		t.Errorf("%d requests mixed the headers of different logins", mismatches.Load())
+	}
+	if got := notified.Load(); got != int32(logins+1) {
+		t.Errorf("OnChange hooks ran %d times, want %d", got, logins+1)
+	}
+}
+
+func TestUpdateIgnoresOtherLogins(t *testing.T) {
+	Set(utils.DEFAULT_ACCOUNT, credentials(1))
+	if Update(utils.DEFAULT_ACCOUNT, credentials(2)) {
+		t.Error("Update() replaced the login with the tokens of another one")
+	}
+	refreshed := credentials(1)
+	refreshed.AccessToken = "access-new"
+	if !Update(utils.DEFAULT_ACCOUNT, refreshed) || TV().AccessToken != "access-new" {
+		t.Errorf("Update() with refreshed tokens, AccessToken = %q", TV().AccessToken)
+	}
+
+	// A refresh that finishes after a logout does not log in again
+	Set(utils.DEFAULT_ACCOUNT, nil)
+	if Update(utils.DEFAULT_ACCOUNT, refreshed) || TV().AccessToken != "" {
+		t.Errorf("Update() after logout, AccessToken = %q", TV().AccessToken)
+	}
+}
+
+func TestWithChannelFailover(t *testing.T) {
+	Set(utils.DEFAULT_ACCOUNT, credentials(1))
+	Set("family", credentials(2))
+	t.Cleanup(func() {
+		Set("family", nil)
+		Set(utils.DEFAULT_ACCOUNT, nil)
+	})
+	if accounts := Accounts(); len(accounts) != 2 || accounts[0].Name != utils.DEFAULT_ACCOUNT || accounts[1].Name != "family" {
+		t.Fatalf("Accounts() = %+v", accounts)
+	}
+
+	// Only the second account is subscribed to the channel
+	var tried []string
+	play := func(tv *television.Television) error {
+		tried = append(tried, tv.Crm)
+		if tv.Crm != "crm-2" {
+			return television.ErrNotSubscribed
+		}
+		return nil
+	}
+	if err := WithChannel("143", play); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tried, ",") != "crm-1,crm-2" || ForChannel("143").Crm != "crm-2" {
+		t.Errorf("tried %v, ForChannel() = %q", tried, ForChannel("143").Crm)
+	}
+
+	// The account that played the channel is tried first from now on
+	tried = nil
+	if err := WithChannel("143", play); err != nil || strings.Join(tried, ",") != "crm-2" {
+		t.Errorf("WithChannel() = %v, tried %v", err, tried)
+	}
+
+	// Other errors are not retried with other accounts
+	tried = nil
+	if err := WithChannel("144", func(tv *television.Television) error {
+		tried = append(tried, tv.Crm)
+		return television.ErrUpstreamUnavailable
+	}); err != television.ErrUpstreamUnavailable || len(tried) != 1 {
+		t.Errorf("WithChannel() = %v, tried %v", err, tried)
+	}
+
+	// Routes of an account that logs out are forgotten
+	Set("family", nil)
+	if Get("family") != nil || ForChannel("143").Crm != "crm-1" {
+		t.Errorf("after logout ForChannel() = %q", ForChannel("143").Crm)
+	}
+}
@@ -0,0 +1,9 @@
+package session
+
+import "github.com/Varun03-max/JIO/pkg/television"
+
+// Account is a named login with its own Television
+type Account struct {
+	Name string
+	TV   *television.Television
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+
+	"github.com/Varun03-max/JIO/internal/config"
+)
+
+const (
+	// ENCRYPTED_PREFIX marks encrypted values in the store file
+	ENCRYPTED_PREFIX = "enc:"
+	// KEY_SIZE is the size of the data key and the key derived from the passphrase, for AES-256
+	KEY_SIZE = 32
+	// SALT_SIZE is the size of the salt of the key derived from the passphrase
+	SALT_SIZE = 16
+)
+
+// keyIterations is the number of PBKDF2 iterations for new keys derived from a passphrase
+var keyIterations = 600000
+
+// Errors
+var (
+	ErrStoreEncrypted = errors.New("store is encrypted, set store_key, store_key_file or store_key_prompt")
+	ErrWrongStoreKey  = errors.New("wrong store key")
+	ErrNoStoreKey     = errors.New("store key is empty")
+	ErrPlaintextValue = errors.New("value of encrypted store is not encrypted")
+)
+
+var (
+	promptOnce   sync.Once
+	promptSecret string
+	promptErr    error
+)
+
+// secret returns the configured passphrase of the store, empty if the store is not encrypted
+func secret() (string, error) {
+	if config.Cfg.StoreKey != "" {
+		return config.Cfg.StoreKey, nil
+	}
+	if config.Cfg.StoreKeyFile != "" {
+		data, err := os.ReadFile(config.Cfg.StoreKeyFile)
+		if err != nil {
+			return "", fmt.Errorf("reading store key file: %w", err)
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", ErrNoStoreKey
+		}
+		return key, nil
+	}
+	if config.Cfg.StoreKeyPrompt {
+		// Ask only once, even if Init runs again
+		promptOnce.Do(func() {
+			promptSecret, promptErr = ReadPassphrase("Enter store passphrase: ")
+		})
+		return promptSecret, promptErr
+	}
+	return "", nil
+}
+
+// ReadPassphrase prompts for a passphrase on the terminal without echoing it
+func ReadPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return "", ErrNoStoreKey
+	}
+	return string(passphrase), nil
+}
+
+// newEncryption creates a random data key and wraps it with a key derived from passphrase
+func newEncryption(passphrase string) (*Encryption, []byte, error) {
+	dataKey := make([]byte, KEY_SIZE)
+	salt := make([]byte, SALT_SIZE)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := seal(deriveKey(passphrase, salt, keyIterations), dataKey, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Encryption{
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: keyIterations,
+		DataKey:    wrapped,
+	}, dataKey, nil
+}
+
+// openDataKey unwraps the data key of the store with a key derived from passphrase
+func openDataKey(passphrase string, encryption *Encryption) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(encryption.Salt)
+	if err != nil || encryption.Iterations <= 0 {
+		return nil, fmt.Errorf("invalid store encryption: salt %q, %d iterations", encryption.Salt, encryption.Iterations)
+	}
+	dataKey, err := open(deriveKey(passphrase, salt, encryption.Iterations), encryption.DataKey, nil)
+	if err != nil {
+		return nil, ErrWrongStoreKey
+	}
+	return dataKey, nil
+}
+
+// encryptValue encrypts the value of a key with the data key.
+// The key is authenticated too, so values can not be swapped between keys.
+func encryptValue(dataKey []byte, key, value string) (string, error) {
+	return seal(dataKey, []byte(value), []byte(key))
+}
+
+// decryptValue decrypts the value of a key.
+// Values without ENCRYPTED_PREFIX are rejected, they were not written by the store.
+func decryptValue(dataKey []byte, key, value string) (string, error) {
+	if !strings.HasPrefix(value, ENCRYPTED_PREFIX) {
+		return "", fmt.Errorf("decrypting %s: %w", key, ErrPlaintextValue)
+	}
+	plaintext, err := open(dataKey, value, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("decrypting %s: %w", key, err)
+	}
+	return string(plaintext), nil
+}
+
+// seal encrypts plaintext with AES-GCM and returns it with ENCRYPTED_PREFIX
+func seal(key, plaintext, additionalData []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, additionalData)
+	return ENCRYPTED_PREFIX + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// open decrypts a value encrypted by seal
+func open(key []byte, value string, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, ENCRYPTED_PREFIX))
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// newAEAD returns AES-GCM with key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey derives the key that wraps the data key from passphrase with PBKDF2-HMAC-SHA256
+func deriveKey(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, KEY_SIZE, sha256.New)
+}
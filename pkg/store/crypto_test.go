@@ -0,0 +1,164 @@
+package store
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Varun03-max/JIO/internal/config"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// Test vectors of PBKDF2-HMAC-SHA256
+	for _, test := range []struct {
+		iterations int
+		want       string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	} {
+		if got := hex.EncodeToString(deriveKey("password", []byte("salt"), test.iterations)); got != test.want {
+			t.Errorf("deriveKey(%d iterations) = %s, want %s", test.iterations, got, test.want)
+		}
+	}
+}
+
+// setup uses a temporary store file with the passphrase as store key
+func setup(t *testing.T, passphrase string) string {
+	t.Helper()
+	original := keyIterations
+	keyIterations = 1000
+	t.Cleanup(func() {
+		keyIterations = original
+		config.Cfg.StoreKey = ""
+	})
+	config.Cfg.PathPrefix = t.TempDir()
+	config.Cfg.StoreKey = passphrase
+	return filepath.Join(config.Cfg.PathPrefix, "store_v4.toml")
+}
+
+func read(t *testing.T, filename string) string {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestEncryptedStore(t *testing.T) {
+	filename := setup(t, "")
+
+	// Stores without a key stay in plaintext
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("ssoToken", "secret-sso"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(read(t, filename), "secret-sso") || Encrypted() {
+		t.Fatalf("plaintext store = %s", read(t, filename))
+	}
+
+	// Configuring a key migrates the values
+	config.Cfg.StoreKey = "correct horse"
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if data := read(t, filename); strings.Contains(data, "secret-sso") || !strings.Contains(data, ENCRYPTED_PREFIX) {
+		t.Fatalf("migrated store = %s", data)
+	}
+	if err := Set("crm", "secret-crm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := Get("ssoToken"); err != nil || value != "secret-sso" {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+
+	// Without the key or with a wrong one the store does not open
+	config.Cfg.StoreKey = ""
+	if err := Init(); !errors.Is(err, ErrStoreEncrypted) {
+		t.Errorf("Init() without key = %v, want %v", err, ErrStoreEncrypted)
+	}
+	config.Cfg.StoreKey = "wrong"
+	if err := Init(); !errors.Is(err, ErrWrongStoreKey) {
+		t.Errorf("Init() with wrong key = %v, want %v", err, ErrWrongStoreKey)
+	}
+
+	// Values swapped between keys do not decrypt
+	config.Cfg.StoreKey = "correct horse"
+	var stored Config
+	if _, err := toml.DecodeFile(filename, &stored); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := stored.Data["crm"]
+	stored.Data["ssoToken"], stored.Data["crm"] = stored.Data["crm"], stored.Data["ssoToken"]
+	write(t, filename, stored)
+	if err := Init(); err == nil {
+		t.Error("Init() decrypted values that were swapped between keys")
+	}
+
+	// Values in plaintext are not accepted in an encrypted store
+	stored.Data["ssoToken"], stored.Data["crm"] = "injected-sso", encrypted
+	write(t, filename, stored)
+	if err := Init(); !errors.Is(err, ErrPlaintextValue) {
+		t.Errorf("Init() with a plaintext value = %v, want %v", err, ErrPlaintextValue)
+	}
+}
+
+func write(t *testing.T, filename string, stored Config) {
+	t.Helper()
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := toml.NewEncoder(file).Encode(stored); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	filename := setup(t, "old passphrase")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("refreshToken", "secret-refresh"); err != nil {
+		t.Fatal(err)
+	}
+	before := read(t, filename)
+
+	if err := Rekey("new passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if read(t, filename) == before {
+		t.Error("Rekey() did not change the store file")
+	}
+	config.Cfg.StoreKey = "old passphrase"
+	if err := Init(); !errors.Is(err, ErrWrongStoreKey) {
+		t.Errorf("Init() with old key = %v, want %v", err, ErrWrongStoreKey)
+	}
+	config.Cfg.StoreKey = "new passphrase"
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := Get("refreshToken"); err != nil || value != "secret-refresh" {
+		t.Errorf("Get() after Rekey() = %q, %v", value, err)
+	}
+
+	// An empty passphrase decrypts the store
+	if err := Rekey(""); err != nil {
+		t.Fatal(err)
+	}
+	config.Cfg.StoreKey = ""
+	if err := Init(); err != nil || !strings.Contains(read(t, filename), "secret-refresh") {
+		t.Errorf("Init() after decrypting = %v, store = %s", err, read(t, filename))
+	}
+}
@@ -0,0 +1,325 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// codec encodes and decodes a store file
+type codec struct {
+	decode func(data []byte, stored *Config) error
+	encode func(w io.Writer, stored Config) error
+}
+
+var tomlCodec = codec{
+	decode: func(data []byte, stored *Config) error {
+		_, err := toml.Decode(string(data), stored)
+		return err
+	},
+	encode: func(w io.Writer, stored Config) error {
+		return toml.NewEncoder(w).Encode(stored)
+	},
+}
+
+var jsonCodec = codec{
+	decode: func(data []byte, stored *Config) error {
+		return json.Unmarshal(data, stored)
+	},
+	encode: func(w io.Writer, stored Config) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stored)
+	},
+}
+
+// FileStore keeps the values in a TOML or JSON file.
+// Writes replace the file atomically, and an advisory lock on "<file>.lock" orders the writes
+// of several processes, like the server and a login on the command line.
+// Changes written by other processes are read before the next access.
+type FileStore struct {
+	filename   string
+	codec      codec
+	passphrase string
+	mu         sync.Mutex
+	config     Config
+	// dataKey encrypts the values when they are saved, nil if the store is not encrypted
+	dataKey []byte
+	// modTime and size of the file when it was last read or written
+	modTime time.Time
+	size    int64
+}
+
+// NewTomlStore opens the TOML store at filename, creating it if it does not exist.
+// A non-empty passphrase encrypts the values.
+func NewTomlStore(filename, passphrase string) (*FileStore, error) {
+	return openFileStore(filename, tomlCodec, passphrase)
+}
+
+// NewJSONStore opens the JSON store at filename, creating it if it does not exist.
+// A non-empty passphrase encrypts the values.
+func NewJSONStore(filename, passphrase string) (*FileStore, error) {
+	return openFileStore(filename, jsonCodec, passphrase)
+}
+
+// openFileStore reads the store file, creates it if it does not exist and encrypts it if it is in plaintext
+// while a passphrase is set
+func openFileStore(filename string, codec codec, passphrase string) (*FileStore, error) {
+	s := &FileStore{filename: filename, codec: codec, passphrase: passphrase}
+	unlock, err := s.lock(true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, err = os.Stat(filename)
+	switch {
+	case os.IsNotExist(err):
+		// Create a new file with an empty configuration.
+		s.config = Config{
+			Data: make(map[string]string),
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := s.read(); err != nil {
+			return nil, err
+		}
+		if s.config.Encryption != nil || passphrase == "" {
+			return s, nil
+		}
+		// Migrate from plaintext
+	}
+
+	if passphrase != "" {
+		if s.config.Encryption, s.dataKey, err = newEncryption(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.write(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the value of key
+func (s *FileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.changed() {
+		unlock, err := s.lock(false)
+		if err != nil {
+			return "", err
+		}
+		err = s.read()
+		unlock()
+		if err != nil {
+			return "", err
+		}
+	}
+	value, ok := s.config.Data[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	return value, nil
+}
+
+// Set sets the value of key
+func (s *FileStore) Set(key, value string) error {
+	return s.update(func(data map[string]string) {
+		data[key] = value
+	})
+}
+
+// Delete removes key
+func (s *FileStore) Delete(key string) error {
+	return s.update(func(data map[string]string) {
+		delete(data, key)
+	})
+}
+
+// Rekey encrypts the store with a new passphrase and a new data key.
+// An empty passphrase stores the values in plaintext again.
+func (s *FileStore) Rekey(passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if s.changed() {
+		if err := s.read(); err != nil {
+			return err
+		}
+	}
+
+	previous, dataKey := s.config.Encryption, s.dataKey
+	s.config.Encryption, s.dataKey = nil, nil
+	if passphrase != "" {
+		if s.config.Encryption, s.dataKey, err = newEncryption(passphrase); err != nil {
+			s.config.Encryption, s.dataKey = previous, dataKey
+			return err
+		}
+	}
+	if err := s.write(); err != nil {
+		s.config.Encryption, s.dataKey = previous, dataKey
+		return err
+	}
+	s.passphrase = passphrase
+	return nil
+}
+
+// Encrypted reports whether the values of the store are encrypted
+func (s *FileStore) Encrypted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dataKey != nil
+}
+
+// update changes the values under the exclusive lock, after reading the changes of other processes
+func (s *FileStore) update(fn func(data map[string]string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if s.changed() {
+		if err := s.read(); err != nil {
+			return err
+		}
+	}
+	fn(s.config.Data)
+	return s.write()
+}
+
+// lock takes the advisory lock of the file, exclusive for writes, and returns the function that releases it
+func (s *FileStore) lock(exclusive bool) (func(), error) {
+	file, err := os.OpenFile(s.filename+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file, exclusive); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("locking %s: %w", s.filename, err)
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// changed reports whether the file was written by another process since it was last read or written
+func (s *FileStore) changed() bool {
+	info, err := os.Stat(s.filename)
+	return err == nil && (!info.ModTime().Equal(s.modTime) || info.Size() != s.size)
+}
+
+// read loads the file and decrypts its values. The lock must be held.
+func (s *FileStore) read() error {
+	file, err := os.Open(s.filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	var stored Config
+	if err := s.codec.decode(data, &stored); err != nil {
+		return fmt.Errorf("decoding %s: %w", s.filename, err)
+	}
+	if stored.Data == nil {
+		stored.Data = make(map[string]string)
+	}
+	var dataKey []byte
+	if stored.Encryption != nil {
+		switch {
+		case s.dataKey != nil && s.config.Encryption != nil && s.config.Encryption.DataKey == stored.Encryption.DataKey:
+			// Unchanged data key
+			dataKey = s.dataKey
+		case s.passphrase == "":
+			return ErrStoreEncrypted
+		default:
+			if dataKey, err = openDataKey(s.passphrase, stored.Encryption); err != nil {
+				return err
+			}
+		}
+		for key, value := range stored.Data {
+			if stored.Data[key], err = decryptValue(dataKey, key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.config, s.dataKey = stored, dataKey
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// write replaces the file with the current values, encrypted if the store has a data key.
+// The values are written to a temporary file that is synced and renamed over the file,
+// so a crash leaves either the old or the new file. The lock must be held.
+func (s *FileStore) write() error {
+	saved := s.config
+	if s.dataKey != nil {
+		saved.Data = make(map[string]string, len(s.config.Data))
+		for key, value := range s.config.Data {
+			encrypted, err := encryptValue(s.dataKey, key, value)
+			if err != nil {
+				return err
+			}
+			saved.Data[key] = encrypted
+		}
+	}
+
+	dir := filepath.Dir(s.filename)
+	temp, err := os.CreateTemp(dir, filepath.Base(s.filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Fails once the file was renamed
+	defer os.Remove(temp.Name())
+	if err := s.codec.encode(temp, saved); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), s.filename); err != nil {
+		return err
+	}
+	// Persist the rename, directories can not be synced on every platform
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	info, err := os.Stat(s.filename)
+	if err != nil {
+		return err
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package store
+
+import "os"
+
+// lockFile does nothing on platforms without file locks, writes are only ordered within the process
+func lockFile(file *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(file *os.File) error {
+	return nil
+}
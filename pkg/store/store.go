@@ -5,95 +5,128 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sync"
 
-	"github.com/BurntSushi/toml"
 	"github.com/Varun03-max/JIO/internal/config"
 )
 
-// Config represents the structure of the TOML file.
+const (
+	// Store backends selectable with the store_backend config
+	BACKEND_TOML   = "toml"
+	BACKEND_JSON   = "json"
+	BACKEND_MEMORY = "memory"
+)
+
+// Store is a key-value store of strings
+type Store interface {
+	// Get returns the value of key, ErrKeyNotFound if it is not set
+	Get(key string) (string, error)
+	// Set sets the value of key
+	Set(key, value string) error
+	// Delete removes key
+	Delete(key string) error
+}
+
+// encryptable is implemented by stores that can encrypt their values
+type encryptable interface {
+	Rekey(passphrase string) error
+	Encrypted() bool
+}
+
+// Config represents the structure of the store file.
 type Config struct {
-	Data map[string]string `toml:"data"`
+	Data map[string]string `toml:"data" json:"data"`
+	// Encryption is set when the values in Data are encrypted
+	Encryption *Encryption `toml:"encryption,omitempty" json:"encryption,omitempty"`
 }
 
-// TomlStore represents the TOML storage.
-type TomlStore struct {
-	filename string
-	config   Config
-	mu       sync.Mutex
+// Encryption describes the envelope encryption of the store.
+// Values are encrypted with a random data key, which is encrypted with a key derived from the passphrase.
+type Encryption struct {
+	// Salt of the key derived from the passphrase, base64 encoded
+	Salt string `toml:"salt" json:"salt"`
+	// Iterations of PBKDF2 that derive the key from the passphrase
+	Iterations int `toml:"iterations" json:"iterations"`
+	// DataKey is the encrypted data key
+	DataKey string `toml:"data_key" json:"data_key"`
 }
 
 // KVS represents global key-value store.
-var KVS *TomlStore
+// Tests may replace it, for example with NewMemoryStore().
+var KVS Store
 
-// Init initializes the TOML file, creates if not exist, otherwise reads and decodes to struct.
+// Init opens the store selected by the store_backend config.
+// File stores are created if they do not exist. Their values are encrypted when a store key is configured,
+// stores in plaintext are encrypted on the first start with a key.
+// The memory store is kept for the lifetime of the process.
 func Init() error {
-	KVS = &TomlStore{}
-	// store_vX.toml, where X is changed whenever new version requires re-login
-	filename := filepath.Join(GetPathPrefix(), "store_v4.toml")
-
-	KVS.mu.Lock()
-	defer KVS.mu.Unlock()
-
-	KVS.filename = filename
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		// Create a new file with an empty configuration.
-		KVS.config = Config{
-			Data: make(map[string]string),
+	backend := config.Cfg.StoreBackend
+	if backend == BACKEND_MEMORY {
+		if _, ok := KVS.(*MemoryStore); !ok {
+			KVS = NewMemoryStore()
 		}
-		return saveConfig()
+		return nil
+	}
+
+	var open func(filename, passphrase string) (*FileStore, error)
+	var filename string
+	// store_vX, where X is changed whenever new version requires re-login
+	switch backend {
+	case "", BACKEND_TOML:
+		open, filename = NewTomlStore, "store_v4.toml"
+	case BACKEND_JSON:
+		open, filename = NewJSONStore, "store_v4.json"
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
 	}
 
-	// Read and decode existing configuration from the file.
-	_, err := toml.DecodeFile(filename, &KVS.config)
-	return err
+	passphrase, err := secret()
+	if err != nil {
+		return err
+	}
+	store, err := open(filepath.Join(GetPathPrefix(), filename), passphrase)
+	if err != nil {
+		return err
+	}
+	KVS = store
+	return nil
 }
 
-// Get retrieves the value for the specified key from the TOML store.
+// Get retrieves the value for the specified key from the store.
 func Get(key string) (string, error) {
-	KVS.mu.Lock()
-	defer KVS.mu.Unlock()
-
-	value, ok := KVS.config.Data[key]
-	if !ok {
-		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
-	}
-	return value, nil
+	return KVS.Get(key)
 }
 
-// Set sets the value for the specified key in the TOML store.
+// Set sets the value for the specified key in the store.
 func Set(key, value string) error {
-	KVS.mu.Lock()
-	defer KVS.mu.Unlock()
-
-	KVS.config.Data[key] = value
-	return saveConfig()
+	return KVS.Set(key, value)
 }
 
-// Delete removes the entry for the specified key from the TOML store.
+// Delete removes the entry for the specified key from the store.
 func Delete(key string) error {
-	KVS.mu.Lock()
-	defer KVS.mu.Unlock()
-
-	delete(KVS.config.Data, key)
-	return saveConfig()
+	return KVS.Delete(key)
 }
 
-// saveConfig saves the current configuration to the TOML file.
-func saveConfig() error {
-	file, err := os.Create(KVS.filename)
-	if err != nil {
-		return err
+// Rekey encrypts the store with a new passphrase and a new data key.
+// An empty passphrase stores the values in plaintext again.
+func Rekey(passphrase string) error {
+	store, ok := KVS.(encryptable)
+	if !ok {
+		return ErrNotEncryptable
 	}
-	defer file.Close()
+	return store.Rekey(passphrase)
+}
 
-	encoder := toml.NewEncoder(file)
-	return encoder.Encode(KVS.config)
+// Encrypted reports whether the values of the store are encrypted
+func Encrypted() bool {
+	store, ok := KVS.(encryptable)
+	return ok && store.Encrypted()
 }
 
 // Errors
 var (
-	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyNotFound    = errors.New("key not found")
+	ErrUnknownBackend = errors.New("unknown store backend")
+	ErrNotEncryptable = errors.New("store backend does not support encryption")
 )
 
 const (
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/internal/config"
+)
+
+func TestBackends(t *testing.T) {
+	dir := t.TempDir()
+	toml, err := NewTomlStore(filepath.Join(dir, "store.toml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json, err := NewJSONStore(filepath.Join(dir, "store.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, store := range map[string]Store{"toml": toml, "json": json, "memory": NewMemoryStore()} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Get("crm"); !errors.Is(err, ErrKeyNotFound) {
+				t.Errorf("Get() of a missing key = %v, want %v", err, ErrKeyNotFound)
+			}
+			if err := store.Set("crm", "value \"quoted\"\n"); err != nil {
+				t.Fatal(err)
+			}
+			if value, err := store.Get("crm"); err != nil || value != "value \"quoted\"\n" {
+				t.Errorf("Get() = %q, %v", value, err)
+			}
+			if err := store.Delete("crm"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get("crm"); !errors.Is(err, ErrKeyNotFound) {
+				t.Errorf("Get() after Delete() = %v, want %v", err, ErrKeyNotFound)
+			}
+		})
+	}
+
+	// Values survive reopening the file
+	if err := json.Set("deviceId", "device"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewJSONStore(filepath.Join(dir, "store.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := reopened.Get("deviceId"); err != nil || value != "device" {
+		t.Errorf("Get() after reopening = %q, %v", value, err)
+	}
+}
+
+func TestInitBackend(t *testing.T) {
+	config.Cfg.PathPrefix = t.TempDir()
+	t.Cleanup(func() { config.Cfg.StoreBackend = "" })
+	for backend, filename := range map[string]string{"": "store_v4.toml", BACKEND_TOML: "store_v4.toml", BACKEND_JSON: "store_v4.json"} {
+		config.Cfg.StoreBackend = backend
+		if err := Init(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(config.Cfg.PathPrefix, filename)); err != nil {
+			t.Errorf("Init() with backend %q: %v", backend, err)
+		}
+	}
+
+	// The memory store lives as long as the process
+	config.Cfg.StoreBackend = BACKEND_MEMORY
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	Set("ssoToken", "sso")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := Get("ssoToken"); err != nil || value != "sso" {
+		t.Errorf("Get() after second Init() = %q, %v", value, err)
+	}
+	if err := Rekey("passphrase"); !errors.Is(err, ErrNotEncryptable) {
+		t.Errorf("Rekey() of the memory store = %v, want %v", err, ErrNotEncryptable)
+	}
+
+	config.Cfg.StoreBackend = "redis"
+	if err := Init(); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("Init() with unknown backend = %v, want %v", err, ErrUnknownBackend)
+	}
+}
+
+func TestTypedHelpers(t *testing.T) {
+	original := KVS
+	KVS = NewMemoryStore()
+	t.Cleanup(func() { KVS = original })
+
+	if err := SetInt("retries", -3); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := GetInt("retries"); err != nil || n != -3 {
+		t.Errorf("GetInt() = %d, %v", n, err)
+	}
+	SetBool("enabled", true)
+	if b, err := GetBool("enabled"); err != nil || !b {
+		t.Errorf("GetBool() = %v, %v", b, err)
+	}
+	at := time.Unix(1700000000, 0)
+	SetTime("lastTokenRefreshTime", at)
+	if value, _ := Get("lastTokenRefreshTime"); value != "1700000000" {
+		t.Errorf("SetTime() stored %q", value)
+	}
+	if got, err := GetTime("lastTokenRefreshTime"); err != nil || !got.Equal(at) {
+		t.Errorf("GetTime() = %v, %v", got, err)
+	}
+	type favourites struct{ Channels []string }
+	SetJSON("favourites", favourites{Channels: []string{"143", "144"}})
+	var got favourites
+	if err := GetJSON("favourites", &got); err != nil || len(got.Channels) != 2 {
+		t.Errorf("GetJSON() = %+v, %v", got, err)
+	}
+
+	Set("retries", "many")
+	if _, err := GetInt("retries"); err == nil {
+		t.Error("GetInt() of a value that is no integer succeeded")
+	}
+	if _, err := GetBool("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("GetBool() of a missing key = %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+// TestProcesses writes the same file through two stores, like the server and a login on the command line
+func TestProcesses(t *testing.T) {
+	for name, open := range map[string]func(string, string) (*FileStore, error){"toml": NewTomlStore, "json": NewJSONStore} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "store")
+			server, err := open(filename, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli, err := open(filename, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(2)
+				go func(i int) {
+					defer wg.Done()
+					if err := server.Set(fmt.Sprintf("server-%d", i), "value"); err != nil {
+						t.Error(err)
+					}
+				}(i)
+				go func(i int) {
+					defer wg.Done()
+					if err := cli.Set(fmt.Sprintf("cli-%d", i), "value"); err != nil {
+						t.Error(err)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			// Neither store lost the writes of the other
+			for i := 0; i < 20; i++ {
+				for _, key := range []string{fmt.Sprintf("server-%d", i), fmt.Sprintf("cli-%d", i)} {
+					if _, err := server.Get(key); err != nil {
+						t.Errorf("server: %v", err)
+					}
+					if _, err := cli.Get(key); err != nil {
+						t.Errorf("cli: %v", err)
+					}
+				}
+			}
+
+			// Only the store and its lock are left, readable by the owner only
+			entries, err := os.ReadDir(filepath.Dir(filename))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 {
+				t.Errorf("files after writes = %v", entries)
+			}
+			if info, err := os.Stat(filename); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("store file mode = %v, %v", info.Mode(), err)
+			}
+		})
+	}
+}
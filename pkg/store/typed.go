@@ -0,0 +1,79 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// GetInt returns the integer value of key
+func GetInt(key string) (int64, error) {
+	value, err := Get(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is no integer: %w", key, err)
+	}
+	return n, nil
+}
+
+// SetInt sets the integer value of key
+func SetInt(key string, value int64) error {
+	return Set(key, strconv.FormatInt(value, 10))
+}
+
+// GetBool returns the boolean value of key
+func GetBool(key string) (bool, error) {
+	value, err := Get(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s is no boolean: %w", key, err)
+	}
+	return b, nil
+}
+
+// SetBool sets the boolean value of key
+func SetBool(key string, value bool) error {
+	return Set(key, strconv.FormatBool(value))
+}
+
+// GetTime returns the time of key, stored as unix time in seconds like the token refresh times
+func GetTime(key string) (time.Time, error) {
+	seconds, err := GetInt(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// SetTime sets the time of key as unix time in seconds
+func SetTime(key string, value time.Time) error {
+	return SetInt(key, value.Unix())
+}
+
+// GetJSON decodes the JSON value of key into v
+func GetJSON(key string, v any) error {
+	value, err := Get(key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return nil
+}
+
+// SetJSON sets the value of key to v encoded as JSON
+func SetJSON(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return Set(key, string(data))
+}
@@ -0,0 +1,101 @@
+package television
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+const (
+	// MAX_ATTEMPTS is how many times a request to JioTV is tried before giving up
+	MAX_ATTEMPTS = 3
+	// RETRY_BASE_DELAY is the delay before the first retry, doubled on every further retry
+	RETRY_BASE_DELAY = 500 * time.Millisecond
+	// RETRY_MAX_DELAY caps the delay between retries
+	RETRY_MAX_DELAY = 5 * time.Second
+)
+
+// Upstream errors returned by Television methods
+var (
+	ErrUnauthorized        = errors.New("not logged in or the session has expired")
+	ErrNotSubscribed       = errors.New("channel is not part of the subscription")
+	ErrChannelNotFound     = errors.New("channel not found")
+	ErrUpstreamUnavailable = errors.New("JioTV servers are unavailable")
+	ErrRateLimited         = errors.New("too many requests to JioTV servers")
+)
+
+// UpstreamError describes a failed request to JioTV. It wraps one of the upstream errors above.
+type UpstreamError struct {
+	Err        error
+	StatusCode int
+	Detail     string
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Detail
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// statusError maps an unsuccessful HTTP status and response body from JioTV to an UpstreamError
+func statusError(statusCode int, body []byte) error {
+	detail := strings.TrimSpace(string(body))
+	if len(detail) > 200 {
+		detail = detail[:200]
+	}
+	err := &UpstreamError{StatusCode: statusCode, Detail: detail}
+	switch {
+	case statusCode == fasthttp.StatusUnauthorized || statusCode == 419:
+		// JioTV answers 419 when the access token has expired
+		err.Err = ErrUnauthorized
+	case statusCode == fasthttp.StatusForbidden:
+		if strings.Contains(strings.ToLower(detail), "subscri") {
+			err.Err = ErrNotSubscribed
+		} else {
+			err.Err = ErrUnauthorized
+		}
+	case statusCode == fasthttp.StatusNotFound:
+		err.Err = ErrChannelNotFound
+	case statusCode == fasthttp.StatusTooManyRequests:
+		err.Err = ErrRateLimited
+	default:
+		err.Err = ErrUpstreamUnavailable
+	}
+	return err
+}
+
+// networkError wraps a failed round trip to JioTV as ErrUpstreamUnavailable
+func networkError(err error) error {
+	return &UpstreamError{Err: ErrUpstreamUnavailable, Detail: err.Error()}
+}
+
+// retryable reports whether a request that failed with err may succeed when tried again
+func retryable(err error) bool {
+	return errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, ErrRateLimited)
+}
+
+// withRetry calls fn until it succeeds, fails with a non retryable error or MAX_ATTEMPTS is reached
+func withRetry(operation string, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= MAX_ATTEMPTS; attempt++ {
+		if err = fn(); err == nil || !retryable(err) {
+			return err
+		}
+		if attempt < MAX_ATTEMPTS {
+			delay := utils.Backoff(attempt, RETRY_BASE_DELAY, RETRY_MAX_DELAY)
+			utils.Log.Printf("%s failed (attempt %d/%d): %v. Retrying in %s", operation, attempt, MAX_ATTEMPTS, err, delay)
+			time.Sleep(delay)
+		}
+	}
+	return fmt.Errorf("%s failed after %d attempts: %w", operation, MAX_ATTEMPTS, err)
+}
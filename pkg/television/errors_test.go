@@ -0,0 +1,83 @@
+package television
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Varun03-max/JIO/pkg/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log = log.New(io.Discard, "", 0)
+	os.Exit(m.Run())
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		body       string
+		want       error
+	}{
+		{401, "", ErrUnauthorized},
+		{419, "token expired", ErrUnauthorized},
+		{403, "Forbidden", ErrUnauthorized},
+		{403, `{"message": "Channel not in your Subscription"}`, ErrNotSubscribed},
+		{404, "", ErrChannelNotFound},
+		{429, "", ErrRateLimited},
+		{500, "", ErrUpstreamUnavailable},
+		{502, "Bad Gateway", ErrUpstreamUnavailable},
+		{503, "", ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		err := statusError(tt.statusCode, []byte(tt.body))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("statusError(%d, %q) = %v, want %v", tt.statusCode, tt.body, err, tt.want)
+		}
+		var upstream *UpstreamError
+		if !errors.As(err, &upstream) || upstream.StatusCode != tt.statusCode {
+			t.Errorf("statusError(%d, %q) = %#v, want an UpstreamError with the status code", tt.statusCode, tt.body, err)
+		}
+	}
+
+	// Long bodies are cut in the error message
+	var upstream *UpstreamError
+	if errors.As(statusError(500, make([]byte, 1000)), &upstream) && len(upstream.Detail) > 200 {
+		t.Errorf("detail has %d bytes", len(upstream.Detail))
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		want     error
+		attempts int
+	}{
+		{"success", []error{nil}, nil, 1},
+		{"unauthorized", []error{statusError(401, nil)}, ErrUnauthorized, 1},
+		{"not subscribed", []error{statusError(403, []byte("not subscribed"))}, ErrNotSubscribed, 1},
+		{"not found", []error{statusError(404, nil)}, ErrChannelNotFound, 1},
+		{"recovers", []error{statusError(503, nil), nil}, nil, 2},
+		{"rate limited, then unauthorized", []error{statusError(429, nil), statusError(401, nil)}, ErrUnauthorized, 2},
+		{"unavailable", []error{networkError(errors.New("connection refused"))}, ErrUpstreamUnavailable, MAX_ATTEMPTS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetry("request", func() error {
+				attempts++
+				// The last error repeats
+				return tt.errs[min(attempts, len(tt.errs))-1]
+			})
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("withRetry() = %v, want %v", err, tt.want)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("withRetry() made %d attempts, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
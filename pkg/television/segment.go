@@ -0,0 +1,171 @@
+package television
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/Varun03-max/JIO/pkg/hls"
+)
+
+const (
+	// PLAYER_USER_AGENT is the user agent of the JioTV player, required for segments and keys
+	PLAYER_USER_AGENT = "plaYtv/7.0.5 (Linux;Android 8.1.0) ExoPlayerLib/2.11.7"
+	// KEY_SRNO is the serial number sent with key requests
+	KEY_SRNO = "230203144000"
+)
+
+// StatusError is returned when JioTV servers respond with an unexpected status code
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request to %s failed with status code: %d", e.URL, e.StatusCode)
+}
+
+// Fetch does a HTTP GET request with the Television headers and returns the response body.
+// Responses other than 200 OK are returned as *StatusError.
+func (tv *Television) Fetch(url string) ([]byte, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+	for key, value := range tv.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.SetUserAgent(PLAYER_USER_AGENT)
+
+	return tv.do(req)
+}
+
+// FetchKey requests the AES key of a segment from JioTV servers
+func (tv *Television) FetchKey(url, channelID string) ([]byte, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+
+	// JioTV uses the query parameters of the key URL as cookies to authenticate
+	if _, params, ok := strings.Cut(url, "?"); ok {
+		for _, param := range strings.Split(params, "&") {
+			key, value, _ := strings.Cut(param, "=")
+			req.Header.SetCookie(key, value)
+		}
+	}
+	for key, value := range tv.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("srno", KEY_SRNO)
+	req.Header.Set("ssotoken", tv.SsoToken)
+	req.Header.Set("channelId", channelID)
+	req.Header.SetUserAgent(PLAYER_USER_AGENT)
+
+	return tv.do(req)
+}
+
+// FetchSegment downloads a segment and decrypts it if it is encrypted.
+// keys caches keys by URI between calls and may be nil.
+func (tv *Television) FetchSegment(segment hls.Segment, channelID string, keys map[string][]byte) ([]byte, error) {
+	data, err := tv.Fetch(segment.URI)
+	if err != nil {
+		return nil, err
+	}
+	if segment.Key == nil || segment.Key.URI == "" || segment.Key.Method == "NONE" {
+		return data, nil
+	}
+	key, ok := keys[segment.Key.URI]
+	if !ok {
+		if key, err = tv.FetchKey(segment.Key.URI, channelID); err != nil {
+			return nil, err
+		}
+		if keys != nil {
+			// Keys rotate, only the current one is worth keeping
+			clear(keys)
+			keys[segment.Key.URI] = key
+		}
+	}
+	return hls.Decrypt(data, key, segment)
+}
+
+// MediaPlaylistURL returns the URL of the best quality media playlist of a channel within the limits of filter
+func (tv *Television) MediaPlaylistURL(channelID string, filter hls.VariantFilter) (string, error) {
+	live, err := tv.Live(channelID)
+	if err != nil {
+		return "", err
+	}
+	streamURL := live.Bitrates.Auto
+	if streamURL == "" {
+		streamURL = live.Result
+	}
+	if streamURL == "" {
+		return "", errors.New("no stream URL for channel " + channelID)
+	}
+
+	data, err := tv.Fetch(streamURL)
+	if err != nil {
+		return "", err
+	}
+	playlist, err := hls.Parse(data)
+	if err != nil {
+		return "", err
+	}
+	if playlist.Type == hls.Media {
+		return streamURL, nil
+	}
+	variant := hls.HighestBandwidth(playlist.SelectVariants(filter))
+	if variant == nil {
+		return "", errors.New("no variants in master playlist of channel " + channelID)
+	}
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		return "", err
+	}
+	return hls.ResolveURI(base, variant.URI)
+}
+
+// TokenExpiry returns when the hdnea token in the query of a JioTV stream URL expires.
+// The token looks like `st=1709290000~exp=1709300000~acl=/*~hmac=...`.
+func TokenExpiry(streamURL string) (time.Time, bool) {
+	parsed, err := url.Parse(streamURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for key, values := range parsed.Query() {
+		if !strings.Contains(key, "hdnea") || len(values) == 0 {
+			continue
+		}
+		for _, field := range strings.Split(values[0], "~") {
+			if exp, ok := strings.CutPrefix(field, "exp="); ok {
+				unix, err := strconv.ParseInt(exp, 10, 64)
+				if err != nil {
+					return time.Time{}, false
+				}
+				return time.Unix(unix, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// do performs req and returns a copy of the response body
+func (tv *Television) do(req *fasthttp.Request) ([]byte, error) {
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := tv.Client.Do(req, resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, &StatusError{URL: req.URI().String(), StatusCode: resp.StatusCode()}
+	}
+	return append([]byte(nil), resp.Body()...), nil
+}
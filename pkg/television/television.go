@@ -3,12 +3,14 @@ package television
 import (
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/valyala/fasthttp"
 
 	"github.com/Varun03-max/JIO/internal/config"
+	"github.com/Varun03-max/JIO/pkg/hls"
 	"github.com/Varun03-max/JIO/pkg/secureurl"
 	"github.com/Varun03-max/JIO/pkg/utils"
 )
@@ -16,6 +18,14 @@ import (
 const (
 	// URL for fetching channels from JioTV API
 	CHANNELS_API_URL = "https://jiotvapi.cdn.jio.com/apis/v3.0/getMobileChannelList/get/?langId=6&os=android&devicetype=phone&usertype=JIO&version=315&langId=6"
+	// CATCHUP_TIME_FORMAT is the layout of begin and end times of catch-up requests
+	CATCHUP_TIME_FORMAT = "20060102T150405"
+)
+
+// Errors
+var (
+	ErrCatchupNotSupported  = errors.New("catch-up is not supported for this channel")
+	ErrInvalidCatchupWindow = errors.New("catch-up window must end after it starts")
 )
 
 // New function creates a new Television instance with the provided credentials
@@ -67,17 +77,41 @@ func New(credentials *utils.JIOTV_CREDENTIALS) *Television {
 // Live method generates m3u8 link from JioTV API with the provided channel ID
 func (tv *Television) Live(channelID string) (*LiveURLOutput, error) {
 	// If channelID starts with sl, then it is a Sony Channel
-	if channelID[:2] == "sl" {
+	if strings.HasPrefix(channelID, "sl") {
 		return getSLChannel(channelID)
 	}
+	return tv.playback(channelID, map[string]string{
+		"stream_type": "Seek",
+		"begin":       utils.GenerateCurrentTime(),
+		"srno":        utils.GenerateDate(),
+	})
+}
+
+// Catchup method generates m3u8 link for the archived window between start and end of a channel
+func (tv *Television) Catchup(channelID string, start, end time.Time) (*LiveURLOutput, error) {
+	if strings.HasPrefix(channelID, "sl") {
+		return nil, ErrCatchupNotSupported
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidCatchupWindow
+	}
+	return tv.playback(channelID, map[string]string{
+		"stream_type": "Catchup",
+		"begin":       start.UTC().Format(CATCHUP_TIME_FORMAT),
+		"end":         end.UTC().Format(CATCHUP_TIME_FORMAT),
+		"srno":        start.UTC().Format("20060102"),
+	})
+}
 
+// playback requests a stream URL of a channel from JioTV API with the given form fields
+func (tv *Television) playback(channelID string, fields map[string]string) (*LiveURLOutput, error) {
 	formData := fasthttp.AcquireArgs()
 	defer fasthttp.ReleaseArgs(formData)
 
 	formData.Add("channel_id", channelID)
-	formData.Add("stream_type", "Seek")
-	formData.Add("begin", utils.GenerateCurrentTime())
-	formData.Add("srno", utils.GenerateDate())
+	for key, value := range fields {
+		formData.Add(key, value)
+	}
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
@@ -96,7 +130,7 @@ func (tv *Television) Live(channelID string) (*LiveURLOutput, error) {
 		req.Header.Set("ssotoken", tv.SsoToken)
 		req.Header.Set("versionCode", "277")
 		url = "https://tv.media.jio.com/apis/v2.2/getchannelurl/getchannelurl"
-		req.Header.SetUserAgent("plaYtv/7.0.5 (Linux;Android 8.1.0) ExoPlayerLib/2.11.7")
+		req.Header.SetUserAgent(PLAYER_USER_AGENT)
 	}
 	req.SetRequestURI(url)
 	req.Header.SetMethod("POST")
@@ -109,39 +143,31 @@ func (tv *Television) Live(channelID string) (*LiveURLOutput, error) {
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	// Perform the HTTP POST request
-	if err := tv.Client.Do(req, resp); err != nil {
-		if strings.Contains(err.Error(), "server closed connection before returning the first response byte") {
-			utils.Log.Println("Retrying the request...")
-			return tv.Live(channelID)
-		}
-		utils.Log.Panic(err)
-		return nil, err
-	}
-
-	if resp.StatusCode() != fasthttp.StatusOK {
-		// Store the response body as a string
-		response := string(resp.Body())
-
-		// Log headers and request data
-		utils.Log.Println("Request headers:", req.Header.String())
-		utils.Log.Println("Request data:", formData.String())
-		utils.Log.Panicln("Response: ", response)
-
-		return nil, fmt.Errorf("Request failed with status code: %d\nresponse: %s", resp.StatusCode(), response)
-	}
-
 	var result LiveURLOutput
-	if err := json.Unmarshal(resp.Body(), &result); err != nil {
-		utils.Log.Panic(err)
+	err := withRetry("Playback request for channel "+channelID, func() error {
+		resp.Reset()
+		// Perform the HTTP POST request
+		if err := tv.Client.Do(req, resp); err != nil {
+			return networkError(err)
+		}
+		if resp.StatusCode() != fasthttp.StatusOK {
+			utils.Log.Println("Request data:", formData.String())
+			return statusError(resp.StatusCode(), resp.Body())
+		}
+		if err := json.Unmarshal(resp.Body(), &result); err != nil {
+			return &UpstreamError{Err: ErrUpstreamUnavailable, StatusCode: resp.StatusCode(), Detail: "invalid response: " + err.Error()}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return &result, nil
 }
 
-// Render method does HTTP GET request to the provided URL and return the response body
-func (tv *Television) Render(url string) ([]byte, int) {
+// Render method does HTTP GET request to the provided URL and return the response body and status code.
+// An error is returned only if JioTV could not be reached.
+func (tv *Television) Render(url string) ([]byte, int, error) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
@@ -156,18 +182,30 @@ func (tv *Television) Render(url string) ([]byte, int) {
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	// Perform the HTTP GET request
-	if err := tv.Client.Do(req, resp); err != nil {
-		utils.Log.Panic(err)
+	err := withRetry("Render request", func() error {
+		resp.Reset()
+		// Perform the HTTP GET request
+		if err := tv.Client.Do(req, resp); err != nil {
+			return networkError(err)
+		}
+		// Other statuses are left to the caller, e.g. to refresh an expired URL
+		if resp.StatusCode() >= fasthttp.StatusInternalServerError || resp.StatusCode() == fasthttp.StatusTooManyRequests {
+			return statusError(resp.StatusCode(), nil)
+		}
+		return nil
+	})
+	if err != nil && resp.StatusCode() == 0 {
+		return nil, 0, err
 	}
 
-	buf := resp.Body()
+	// Copy the body as resp is released on return
+	buf := append([]byte(nil), resp.Body()...)
 
-	return buf, resp.StatusCode()
+	return buf, resp.StatusCode(), nil
 }
 
 // Channels fetch channels from JioTV API
-func Channels() ChannelsResponse {
+func Channels() (ChannelsResponse, error) {
 
 	// Create a fasthttp.Client
 	client := utils.GetRequestClient()
@@ -189,29 +227,36 @@ func Channels() ChannelsResponse {
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	// Perform the HTTP GET request
-	if err := client.Do(req, resp); err != nil {
-		utils.Log.Panic(err)
-	}
-
 	var apiResponse ChannelsResponse
-
-	// Check the response status code
-	if resp.StatusCode() != fasthttp.StatusOK {
-		utils.Log.Panicf("Request failed with status code: %d", resp.StatusCode())
-	}
-
-	resp_body := resp.Body()
-
-	// Parse the JSON response
-	if err := json.Unmarshal(resp_body, &apiResponse); err != nil {
-		utils.Log.Panic(err)
+	err := withRetry("Channels request", func() error {
+		resp.Reset()
+		// Perform the HTTP GET request
+		if err := client.Do(req, resp); err != nil {
+			return networkError(err)
+		}
+		// Check the response status code
+		if resp.StatusCode() != fasthttp.StatusOK {
+			err := statusError(resp.StatusCode(), resp.Body())
+			// The channel list is public, so a missing list means JioTV is down
+			if errors.Is(err, ErrChannelNotFound) {
+				return &UpstreamError{Err: ErrUpstreamUnavailable, StatusCode: resp.StatusCode()}
+			}
+			return err
+		}
+		// Parse the JSON response
+		if err := json.Unmarshal(resp.Body(), &apiResponse); err != nil {
+			return &UpstreamError{Err: ErrUpstreamUnavailable, StatusCode: resp.StatusCode(), Detail: "invalid response: " + err.Error()}
+		}
+		return nil
+	})
+	if err != nil {
+		return ChannelsResponse{}, err
 	}
 
 	// disable sony channels temporarily
 	// apiResponse.Result = append(apiResponse.Result, SONY_CHANNELS_API...)
 
-	return apiResponse
+	return apiResponse, nil
 }
 
 // FilterChannels Function is used to filter channels by language and category
@@ -238,90 +283,113 @@ func FilterChannels(channels []Channel, language, category int) []Channel {
 	return filteredChannels
 }
 
-func ReplaceM3U8(baseUrl, match []byte, params, channel_id string) []byte {
-	coded_url, err := secureurl.EncryptURL(string(baseUrl) + string(match) + "?" + params)
+// RewritePlaylist rewrites all URIs of a playlist fetched from upstreamURL to point at this server
+func RewritePlaylist(playlist []byte, upstreamURL, channel_id string) ([]byte, error) {
+	return hls.Rewrite(playlist, upstreamURL, func(uri string, kind hls.URIKind) string {
+		switch kind {
+		case hls.KindPlaylist:
+			return ReplaceM3U8(uri, channel_id)
+		case hls.KindKey:
+			return ReplaceKey(uri, channel_id)
+		default:
+			if path, _, _ := strings.Cut(uri, "?"); strings.HasSuffix(path, ".aac") {
+				return ReplaceAAC(uri, channel_id)
+			}
+			return ReplaceTS(uri, channel_id)
+		}
+	})
+}
+
+// ReplaceM3U8 returns the render URL for a variant or rendition playlist
+func ReplaceM3U8(uri, channel_id string) string {
+	coded_url, err := secureurl.EncryptURL(uri)
 	if err != nil {
 		utils.Log.Println(err)
-		return nil
+		return ""
 	}
-	return []byte("/render.m3u8?auth=" + coded_url + "&channel_key_id=" + channel_id)
+	return "/render.m3u8?auth=" + coded_url + "&channel_key_id=" + channel_id
 }
 
-func ReplaceTS(baseUrl, match []byte, params string) []byte {
+// ReplaceTS returns the render URL for a media or initialisation segment
+func ReplaceTS(uri, channel_id string) string {
 	if config.Cfg.DisableTSHandler {
-		return []byte(string(baseUrl) + string(match) + "?" + params)
+		return uri
 	}
-	coded_url, err := secureurl.EncryptURL(string(baseUrl) + string(match) + "?" + params)
+	coded_url, err := secureurl.EncryptURL(uri)
 	if err != nil {
 		utils.Log.Println(err)
-		return nil
+		return ""
 	}
-	return []byte("/render.ts?auth=" + coded_url)
+	return "/render.ts?auth=" + coded_url + "&channel_key_id=" + channel_id
 }
 
-func ReplaceAAC(baseUrl, match []byte, params string) []byte {
+// ReplaceAAC returns the render URL for an audio segment
+func ReplaceAAC(uri, channel_id string) string {
 	if config.Cfg.DisableTSHandler {
-		return []byte(string(baseUrl) + string(match) + "?" + params)
+		return uri
 	}
-	coded_url, err := secureurl.EncryptURL(string(baseUrl) + string(match) + "?" + params)
+	coded_url, err := secureurl.EncryptURL(uri)
 	if err != nil {
 		utils.Log.Println(err)
-		return nil
+		return ""
 	}
-	return []byte("/render.ts?auth=" + coded_url)
+	return "/render.ts?auth=" + coded_url + "&channel_key_id=" + channel_id
 }
 
-func ReplaceKey(match []byte, params, channel_id string) []byte {
-	coded_url, err := secureurl.EncryptURL(string(match) + "?" + params)
+// ReplaceKey returns the render URL for a decryption key
+func ReplaceKey(uri, channel_id string) string {
+	coded_url, err := secureurl.EncryptURL(uri)
 	if err != nil {
 		utils.Log.Println(err)
-		return nil
+		return ""
 	}
-	return []byte("/render.key?auth=" + coded_url + "&channel_key_id=" + channel_id)
+	return "/render.key?auth=" + coded_url + "&channel_key_id=" + channel_id
 }
 
+// getSLChannel resolves the stream URL of a Sony channel
 func getSLChannel(channelID string) (*LiveURLOutput, error) {
 	// Check if the channel is available in the SONY_CHANNELS map
-	if val, ok := SONY_JIO_MAP[channelID]; ok {
-		// If the channel is available in the SONY_CHANNELS map, then return the link
-		result := new(LiveURLOutput)
-
-		chu, err := base64.StdEncoding.DecodeString(SONY_CHANNELS[val])
-		if err != nil {
-			utils.Log.Panic(err)
-			return nil, err
-		}
+	val, ok := SONY_JIO_MAP[channelID]
+	if !ok {
+		return nil, ErrChannelNotFound
+	}
 
-		channel_url := string(chu)
+	chu, err := base64.StdEncoding.DecodeString(SONY_CHANNELS[val])
+	if err != nil {
+		return nil, err
+	}
+	channel_url := string(chu)
 
-		// Make a get request to the channel url and store location header in actual_url
-		req := fasthttp.AcquireRequest()
-		defer fasthttp.ReleaseRequest(req)
+	// Make a get request to the channel url and store location header in actual_url
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
 
-		req.SetRequestURI(channel_url)
-		req.Header.SetMethod("GET")
+	req.SetRequestURI(channel_url)
+	req.Header.SetMethod("GET")
 
-		resp := fasthttp.AcquireResponse()
-		defer fasthttp.ReleaseResponse(resp)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
 
+	err = withRetry("Playback request for channel "+channelID, func() error {
+		resp.Reset()
 		// Perform the HTTP GET request
 		if err := utils.GetRequestClient().Do(req, resp); err != nil {
-			utils.Log.Panic(err)
+			return networkError(err)
 		}
-
 		if resp.StatusCode() != fasthttp.StatusFound {
-			utils.Log.Panicf("Request failed with status code: %d", resp.StatusCode())
-			utils.Log.Panicln("Response: ", string(resp.Body()))
+			return statusError(resp.StatusCode(), resp.Body())
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Store the location header in actual_url
-		actual_url := string(resp.Header.Peek("Location"))
+	// Store the location header in actual_url
+	actual_url := string(resp.Header.Peek("Location"))
 
-		result.Result = actual_url
-		result.Bitrates.Auto = actual_url
-		return result, nil
-	} else {
-		// If the channel is not available in the SONY_CHANNELS map, then return an error
-		return nil, fmt.Errorf("Channel not found")
-	}
+	result := new(LiveURLOutput)
+	result.Result = actual_url
+	result.Bitrates.Auto = actual_url
+	return result, nil
 }
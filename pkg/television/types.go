@@ -2,7 +2,7 @@ package television
 
 import (
 	"encoding/json"
-	"strconv"
+	"strings"
 
 	"github.com/valyala/fasthttp"
 )
@@ -26,13 +26,29 @@ type Channel struct {
 	Category int    `json:"channelCategoryId"`
 	Language int    `json:"channelLanguageId"`
 	IsHD     bool   `json:"isHD"`
+	// IsCatchupAvailable reports whether past programmes can be played with Television.Catchup
+	IsCatchupAvailable bool `json:"isCatchupAvailable"`
+	// Number is the channel number shown by players, 0 if none is assigned
+	Number int `json:"channelNumber,omitempty"`
+	// Group replaces the category as group of the channel in playlists if set
+	Group string `json:"group,omitempty"`
 }
 
-// UnmarshalJSON to Override Channel.ID to convert int from json to string
+// Logo returns the URL of the channel logo.
+// JioTV logos are relative and served by the image proxy of the server at baseURL.
+func (c Channel) Logo(baseURL string) string {
+	if strings.HasPrefix(c.LogoURL, "http://") || strings.HasPrefix(c.LogoURL, "https://") {
+		return c.LogoURL
+	}
+	return baseURL + "/jtvimage/" + c.LogoURL
+}
+
+// UnmarshalJSON to Override Channel.ID to convert int from json to string.
+// String IDs, as in channel lists saved by JioTV Go, are kept as they are.
 func (c *Channel) UnmarshalJSON(b []byte) error {
 	type Alias Channel
 	aux := &struct {
-		ID int `json:"channel_id"`
+		ID json.RawMessage `json:"channel_id"`
 		*Alias
 	}{
 		Alias: (*Alias)(c),
@@ -40,7 +56,7 @@ func (c *Channel) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &aux); err != nil {
 		return err
 	}
-	c.ID = strconv.Itoa(aux.ID)
+	c.ID = strings.Trim(string(aux.ID), `"`)
 	return nil
 }
 
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Varun03-max/JIO/pkg/store"
+)
+
+const (
+	// DEFAULT_ACCOUNT is the account logged in without a name.
+	// Its credentials are kept under the keys used before there were several accounts.
+	DEFAULT_ACCOUNT = "default"
+	// ACCOUNTS_KEY lists the names of the other accounts in the store, separated by commas
+	ACCOUNTS_KEY = "accounts"
+)
+
+// ErrInvalidAccountName is returned for account names that can not be stored
+var ErrInvalidAccountName = errors.New("account name may only contain lowercase letters, digits, - and _")
+
+var (
+	validAccountName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	// credentialKeys are the store keys of the credentials of an account
+	credentialKeys = []string{"ssoToken", "crm", "uniqueId", "accessToken", "refreshToken", "lastTokenRefreshTime", "lastSSOTokenRefreshTime"}
+)
+
+// ValidateAccountName checks that name can be used as an account name
+func ValidateAccountName(name string) error {
+	if !validAccountName.MatchString(name) {
+		return ErrInvalidAccountName
+	}
+	return nil
+}
+
+// Accounts returns the names of the logged in accounts, the default account first and the others by name
+func Accounts() []string {
+	var result []string
+	if credentials, err := GetJIOTVCredentials(); err == nil && credentials != nil {
+		result = append(result, DEFAULT_ACCOUNT)
+	}
+	return append(result, otherAccounts()...)
+}
+
+// accountKey returns the store key of a credential of an account
+func accountKey(account, key string) string {
+	if account == DEFAULT_ACCOUNT {
+		return key
+	}
+	return "account." + account + "." + key
+}
+
+// otherAccounts returns the names of the accounts other than the default one, ordered by name
+func otherAccounts() []string {
+	value, err := store.Get(ACCOUNTS_KEY)
+	if err != nil || value == "" {
+		return nil
+	}
+	names := strings.Split(value, ",")
+	sort.Strings(names)
+	return names
+}
+
+// addAccount adds an account to the list of accounts
+func addAccount(account string) error {
+	if account == DEFAULT_ACCOUNT {
+		return nil
+	}
+	if err := ValidateAccountName(account); err != nil {
+		return err
+	}
+	names := otherAccounts()
+	for _, name := range names {
+		if name == account {
+			return nil
+		}
+	}
+	return store.Set(ACCOUNTS_KEY, strings.Join(append(names, account), ","))
+}
+
+// removeAccount removes an account from the list of accounts
+func removeAccount(account string) error {
+	if account == DEFAULT_ACCOUNT {
+		return nil
+	}
+	var names []string
+	for _, name := range otherAccounts() {
+		if name != account {
+			names = append(names, name)
+		}
+	}
+	return store.Set(ACCOUNTS_KEY, strings.Join(names, ","))
+}
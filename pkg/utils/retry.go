@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before retry number attempt (starting at 1).
+// The delay doubles from baseDelay up to maxDelay. Half of it is fixed and half is random (equal jitter),
+// so that clients failing together do not retry together.
+func Backoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		baseDelay, maxDelay time.Duration
+	}{
+		{500 * time.Millisecond, 5 * time.Second},
+		{30 * time.Second, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		for attempt := 1; attempt < 70; attempt++ {
+			limit := tt.maxDelay
+			if attempt < 10 {
+				limit = min(tt.baseDelay<<(attempt-1), tt.maxDelay)
+			}
+			for i := 0; i < 20; i++ {
+				if delay := Backoff(attempt, tt.baseDelay, tt.maxDelay); delay < limit/2 || delay > limit {
+					t.Fatalf("Backoff(%d, %v, %v) = %v, want between %v and %v", attempt, tt.baseDelay, tt.maxDelay, delay, limit/2, limit)
+				}
+			}
+		}
+	}
+}
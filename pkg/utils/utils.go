@@ -107,8 +107,8 @@ func LoginSendOTP(number string) (bool, error) {
 	}
 }
 
-// LoginVerifyOTP verifies OTP for login
-func LoginVerifyOTP(number, otp string) (map[string]string, error) {
+// LoginVerifyOTP verifies OTP for login and saves the credentials as the given account
+func LoginVerifyOTP(account, number, otp string) (map[string]string, error) {
 	// convert number string to base64
 	encoded_number := base64.StdEncoding.EncodeToString([]byte(number))
 
@@ -184,7 +184,7 @@ func LoginVerifyOTP(number, otp string) (map[string]string, error) {
 		crm := result.SessionAttributes.User.SubscriberID
 		uniqueId := result.SessionAttributes.User.Unique
 
-		WriteJIOTVCredentials(&JIOTV_CREDENTIALS{
+		WriteAccountCredentials(account, &JIOTV_CREDENTIALS{
 			SSOToken:             ssoToken,
 			CRM:                  crm,
 			UniqueID:             uniqueId,
@@ -208,8 +208,8 @@ func LoginVerifyOTP(number, otp string) (map[string]string, error) {
 	}
 }
 
-// Login is used to login with username and password
-func Login(username, password string) (map[string]string, error) {
+// Login is used to login with username and password and saves the credentials as the given account
+func Login(account, username, password string) (map[string]string, error) {
 	postData := map[string]string{
 		"username": username,
 		"password": password,
@@ -298,7 +298,7 @@ func Login(username, password string) (map[string]string, error) {
 		crm := result.SessionAttributes.User.SubscriberID
 		uniqueId := result.SessionAttributes.User.Unique
 
-		WriteJIOTVCredentials(&JIOTV_CREDENTIALS{
+		WriteAccountCredentials(account, &JIOTV_CREDENTIALS{
 			SSOToken:    ssoToken,
 			CRM:         crm,
 			UniqueID:    uniqueId,
@@ -349,40 +349,45 @@ func GetDeviceID() string {
 // GetJIOTVCredentials return credentials from environment variables or credentials file
 // Important note: If credentials are provided from environment variables, they will be used instead of credentials file
 func GetJIOTVCredentials() (*JIOTV_CREDENTIALS, error) {
-	ssoToken, err := store.Get("ssoToken")
+	return GetAccountCredentials(DEFAULT_ACCOUNT)
+}
+
+// GetAccountCredentials returns the credentials of an account
+func GetAccountCredentials(account string) (*JIOTV_CREDENTIALS, error) {
+	ssoToken, err := store.Get(accountKey(account, "ssoToken"))
 	if err != nil {
 		return nil, err
 	}
 
-	crm, err := store.Get("crm")
+	crm, err := store.Get(accountKey(account, "crm"))
 	if err != nil {
 		return nil, err
 	}
 
-	uniqueId, err := store.Get("uniqueId")
+	uniqueId, err := store.Get(accountKey(account, "uniqueId"))
 	if err != nil {
 		return nil, err
 	}
 
 	// Empty for Password login
-	accessToken, err := store.Get("accessToken")
+	accessToken, err := store.Get(accountKey(account, "accessToken"))
 	if err != nil {
 		return nil, nil
 	}
 
 	// Empty for Password login
-	refreshToken, err := store.Get("refreshToken")
+	refreshToken, err := store.Get(accountKey(account, "refreshToken"))
 	if err != nil {
 		return nil, nil
 	}
 
 	// Empty for Password login
-	lastTokenRefreshTime, err := store.Get("lastTokenRefreshTime")
+	lastTokenRefreshTime, err := store.Get(accountKey(account, "lastTokenRefreshTime"))
 	if err != nil {
 		return nil, nil
 	}
 
-	lastSSOTokenRefreshTime, err := store.Get("lastSSOTokenRefreshTime")
+	lastSSOTokenRefreshTime, err := store.Get(accountKey(account, "lastSSOTokenRefreshTime"))
 	if err != nil {
 		return nil, nil
 	}
@@ -400,42 +405,51 @@ func GetJIOTVCredentials() (*JIOTV_CREDENTIALS, error) {
 
 // WriteJIOTVCredentials writes credentials data to file
 func WriteJIOTVCredentials(credentials *JIOTV_CREDENTIALS) error {
-	if err := store.Set("ssoToken", credentials.SSOToken); err != nil {
+	return WriteAccountCredentials(DEFAULT_ACCOUNT, credentials)
+}
+
+// WriteAccountCredentials writes the credentials of an account to the store
+func WriteAccountCredentials(account string, credentials *JIOTV_CREDENTIALS) error {
+	if err := addAccount(account); err != nil {
 		return err
 	}
 
-	if err := store.Set("crm", credentials.CRM); err != nil {
+	if err := store.Set(accountKey(account, "ssoToken"), credentials.SSOToken); err != nil {
 		return err
 	}
 
-	if err := store.Set("uniqueId", credentials.UniqueID); err != nil {
+	if err := store.Set(accountKey(account, "crm"), credentials.CRM); err != nil {
 		return err
 	}
 
-	if err := store.Set("accessToken", credentials.AccessToken); err != nil {
+	if err := store.Set(accountKey(account, "uniqueId"), credentials.UniqueID); err != nil {
 		return err
 	}
 
-	if err := store.Set("refreshToken", credentials.RefreshToken); err != nil {
+	if err := store.Set(accountKey(account, "accessToken"), credentials.AccessToken); err != nil {
+		return err
+	}
+
+	if err := store.Set(accountKey(account, "refreshToken"), credentials.RefreshToken); err != nil {
 		return err
 	}
 
 	if credentials.LastTokenRefreshTime != "" {
-		if err := store.Set("lastTokenRefreshTime", credentials.LastTokenRefreshTime); err != nil {
+		if err := store.Set(accountKey(account, "lastTokenRefreshTime"), credentials.LastTokenRefreshTime); err != nil {
 			return err
 		}
 	} else {
-		if err := store.Set("lastTokenRefreshTime", strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		if err := store.Set(accountKey(account, "lastTokenRefreshTime"), strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
 			return err
 		}
 	}
 
 	if credentials.LastSSOTokenRefreshTime != "" {
-		if err := store.Set("lastSSOTokenRefreshTime", credentials.LastSSOTokenRefreshTime); err != nil {
+		if err := store.Set(accountKey(account, "lastSSOTokenRefreshTime"), credentials.LastSSOTokenRefreshTime); err != nil {
 			return err
 		}
 	} else {
-		if err := store.Set("lastSSOTokenRefreshTime", strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		if err := store.Set(accountKey(account, "lastSSOTokenRefreshTime"), strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
 			return err
 		}
 	}
@@ -457,49 +471,31 @@ func CheckLoggedIn() bool {
 
 // Logout function deletes credentials file
 func Logout() error {
+	return LogoutAccount(DEFAULT_ACCOUNT)
+}
+
+// LogoutAccount logs an account out of JioTV and deletes its credentials
+func LogoutAccount(account string) error {
 	// Perform server-side logout first
-	if err := PerformServerLogout(); err != nil {
+	if err := PerformServerLogout(account); err != nil {
 		// Log the error but continue with local logout
 		Log.Printf("PerformServerLogout failed: %v", err)
 	}
 
-	// Delete all key-value pairs from the store
-	if err := store.Delete("ssoToken"); err != nil {
-		return err
-	}
-
-	if err := store.Delete("crm"); err != nil {
-		return err
-	}
-
-	if err := store.Delete("uniqueId"); err != nil {
-		return err
-	}
-
-	if err := store.Delete("accessToken"); err != nil {
-		return err
-	}
-
-	if err := store.Delete("refreshToken"); err != nil {
-		return err
-	}
-
-	if err := store.Delete("lastTokenRefreshTime"); err != nil {
-		return err
-	}
-
-	if err := store.Delete("lastSSOTokenRefreshTime"); err != nil {
-		return err
+	// Delete all key-value pairs of the account from the store
+	for _, key := range credentialKeys {
+		if err := store.Delete(accountKey(account, key)); err != nil {
+			return err
+		}
 	}
-
-	return nil
+	return removeAccount(account)
 }
 
-// PerformServerLogout attempts to log out the user from the JioTV servers.
-func PerformServerLogout() error {
+// PerformServerLogout attempts to log out an account from the JioTV servers.
+func PerformServerLogout(account string) error {
 	Log.Println("Attempting server-side logout...")
 
-	creds, err := GetJIOTVCredentials()
+	creds, err := GetAccountCredentials(account)
 	if err != nil {
 		Log.Printf("Error getting credentials for server logout: %v\n", err)
 		// Depending on the error, we might still proceed if critical info like refreshToken is available
@@ -508,6 +504,9 @@ func PerformServerLogout() error {
 			return fmt.Errorf("failed to get credentials: %w", err)
 		}
 	}
+	if creds == nil {
+		return fmt.Errorf("account %s is not logged in", account)
+	}
 
 	deviceID := GetDeviceID()
 	if deviceID == "" {
@@ -0,0 +1,76 @@
+package xtream
+
+// Category is a live category of the Xtream Codes API
+type Category struct {
+	CategoryID   string `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	ParentID     int    `json:"parent_id"`
+}
+
+// LiveStream is a live channel of the Xtream Codes API
+type LiveStream struct {
+	Num               int    `json:"num"`
+	Name              string `json:"name"`
+	StreamType        string `json:"stream_type"`
+	StreamID          int    `json:"stream_id"`
+	StreamIcon        string `json:"stream_icon"`
+	EPGChannelID      string `json:"epg_channel_id"`
+	Added             string `json:"added"`
+	CategoryID        string `json:"category_id"`
+	CustomSID         string `json:"custom_sid"`
+	TVArchive         int    `json:"tv_archive"`
+	DirectSource      string `json:"direct_source"`
+	TVArchiveDuration int    `json:"tv_archive_duration"`
+}
+
+// EPGListing is a programme returned by `get_short_epg`.
+// Title and description are base64 encoded, as Xtream Codes clients expect.
+type EPGListing struct {
+	ID             string `json:"id"`
+	EPGID          string `json:"epg_id"`
+	Title          string `json:"title"`
+	Lang           string `json:"lang"`
+	Start          string `json:"start"`
+	End            string `json:"end"`
+	Description    string `json:"description"`
+	ChannelID      string `json:"channel_id"`
+	StartTimestamp string `json:"start_timestamp"`
+	StopTimestamp  string `json:"stop_timestamp"`
+}
+
+// ShortEPG is the response body of `get_short_epg`
+type ShortEPG struct {
+	EPGListings []EPGListing `json:"epg_listings"`
+}
+
+// UserInfo describes the authenticated account
+type UserInfo struct {
+	Username             string   `json:"username,omitempty"`
+	Password             string   `json:"password,omitempty"`
+	Message              string   `json:"message,omitempty"`
+	Auth                 int      `json:"auth"`
+	Status               string   `json:"status,omitempty"`
+	ExpDate              *string  `json:"exp_date"`
+	IsTrial              string   `json:"is_trial,omitempty"`
+	ActiveCons           string   `json:"active_cons,omitempty"`
+	CreatedAt            string   `json:"created_at,omitempty"`
+	MaxConnections       string   `json:"max_connections,omitempty"`
+	AllowedOutputFormats []string `json:"allowed_output_formats,omitempty"`
+}
+
+// ServerInfo describes the server clients should stream from
+type ServerInfo struct {
+	URL            string `json:"url"`
+	Port           string `json:"port"`
+	HTTPSPort      string `json:"https_port"`
+	ServerProtocol string `json:"server_protocol"`
+	Timezone       string `json:"timezone"`
+	TimestampNow   int64  `json:"timestamp_now"`
+	TimeNow        string `json:"time_now"`
+}
+
+// Account is the response body of `player_api.php` without an action
+type Account struct {
+	UserInfo   UserInfo    `json:"user_info"`
+	ServerInfo *ServerInfo `json:"server_info,omitempty"`
+}
@@ -0,0 +1,162 @@
+package xtream
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
+const (
+	// DEFAULT_EPG_LIMIT is the number of programmes `get_short_epg` returns without a limit
+	DEFAULT_EPG_LIMIT = 4
+	// LISTING_TIME_FORMAT is the layout of start and end times in EPG listings
+	LISTING_TIME_FORMAT = "2006-01-02 15:04:05"
+)
+
+var (
+	// Users maps usernames to passwords allowed to use the API
+	Users map[string]string
+	// ArchiveDays is the catch-up window advertised for channels with archive
+	ArchiveDays int
+)
+
+// Init sets the accounts allowed to use the API and the catch-up window of channels
+func Init(users map[string]string, archiveDays int) {
+	Users = users
+	ArchiveDays = archiveDays
+}
+
+// Authenticate reports whether username and password belong to a configured account
+func Authenticate(username, password string) bool {
+	expected, ok := Users[username]
+	if !ok || username == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+}
+
+// CategoryID returns the Xtream category of a channel.
+// Categories combine language and genre, like `Hindi - Entertainment`.
+func CategoryID(channel television.Channel) string {
+	return strconv.Itoa(channel.Language*100 + channel.Category)
+}
+
+// categoryName returns the name of the category of a channel
+func categoryName(channel television.Channel) string {
+	return television.LanguageMap[channel.Language] + " - " + television.CategoryMap[channel.Category]
+}
+
+// Categories returns the categories that have channels, ordered by language and genre
+func Categories(channels []television.Channel) []Category {
+	seen := make(map[string]bool)
+	categories := make([]Category, 0)
+	for _, channel := range channels {
+		id := CategoryID(channel)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		categories = append(categories, Category{
+			CategoryID:   id,
+			CategoryName: categoryName(channel),
+		})
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, _ := strconv.Atoi(categories[i].CategoryID)
+		b, _ := strconv.Atoi(categories[j].CategoryID)
+		return a < b
+	})
+	return categories
+}
+
+// LiveStreams returns the channels of a category, or all channels if categoryID is empty.
+// Logos point at the image proxy of the server at baseURL.
+func LiveStreams(channels []television.Channel, categoryID, baseURL string) []LiveStream {
+	streams := make([]LiveStream, 0, len(channels))
+	for i, channel := range channels {
+		if categoryID != "" && CategoryID(channel) != categoryID {
+			continue
+		}
+		streamID, err := strconv.Atoi(channel.ID)
+		if err != nil {
+			continue
+		}
+		num := i + 1
+		if channel.Number > 0 {
+			num = channel.Number
+		}
+		stream := LiveStream{
+			Num:          num,
+			Name:         channel.Name,
+			StreamType:   "live",
+			StreamID:     streamID,
+			StreamIcon:   channel.Logo(baseURL),
+			EPGChannelID: channel.ID,
+			CategoryID:   CategoryID(channel),
+		}
+		if channel.IsCatchupAvailable {
+			stream.TVArchive = 1
+			stream.TVArchiveDuration = ArchiveDays
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// NewShortEPG returns up to limit programmes that have not ended at now
+func NewShortEPG(programmes []epg.Programme, now time.Time, limit int) ShortEPG {
+	if limit <= 0 {
+		limit = DEFAULT_EPG_LIMIT
+	}
+	listings := make([]EPGListing, 0, limit)
+	for _, programme := range programmes {
+		if len(listings) == limit {
+			break
+		}
+		start, err := programme.StartTime()
+		if err != nil {
+			continue
+		}
+		stop, err := programme.StopTime()
+		if err != nil || !stop.After(now) {
+			continue
+		}
+		id := programme.Channel + "_" + strconv.FormatInt(start.Unix(), 10)
+		listings = append(listings, EPGListing{
+			ID:             id,
+			EPGID:          id,
+			Title:          base64.StdEncoding.EncodeToString([]byte(programme.Title.Value)),
+			Lang:           programme.Title.Lang,
+			Start:          start.Local().Format(LISTING_TIME_FORMAT),
+			End:            stop.Local().Format(LISTING_TIME_FORMAT),
+			Description:    base64.StdEncoding.EncodeToString([]byte(programme.Desc.Value)),
+			ChannelID:      programme.Channel,
+			StartTimestamp: strconv.FormatInt(start.Unix(), 10),
+			StopTimestamp:  strconv.FormatInt(stop.Unix(), 10),
+		})
+	}
+	return ShortEPG{EPGListings: listings}
+}
+
+// NewAccount returns the account details of an authenticated user
+func NewAccount(username, password string, server ServerInfo) Account {
+	return Account{
+		UserInfo: UserInfo{
+			Username:             username,
+			Password:             password,
+			Auth:                 1,
+			Status:               "Active",
+			IsTrial:              "0",
+			ActiveCons:           "0",
+			CreatedAt:            "0",
+			MaxConnections:       "1",
+			AllowedOutputFormats: []string{"m3u8", "ts"},
+		},
+		ServerInfo: &server,
+	}
+}
@@ -0,0 +1,152 @@
+package xtream
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Varun03-max/JIO/pkg/epg"
+	"github.com/Varun03-max/JIO/pkg/television"
+)
+
+const testBaseURL = "http://192.168.1.10:5001"
+
+// readChannels reads a channel list recorded from JioTV API
+func readChannels(t *testing.T) []television.Channel {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "channels.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response television.ChannelsResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Result
+}
+
+func TestAuthenticate(t *testing.T) {
+	Init(map[string]string{"family": "secret"}, 7)
+	tests := []struct {
+		username, password string
+		want               bool
+	}{
+		{"family", "secret", true},
+		{"family", "wrong", false},
+		{"family", "", false},
+		{"other", "secret", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := Authenticate(tt.username, tt.password); got != tt.want {
+			t.Errorf("Authenticate(%q, %q) = %v, want %v", tt.username, tt.password, got, tt.want)
+		}
+	}
+
+	Init(nil, 7)
+	if Authenticate("", "") {
+		t.Error("Authenticate succeeded without configured accounts")
+	}
+}
+
+func TestCategories(t *testing.T) {
+	got := Categories(readChannels(t))
+	want := []Category{
+		{CategoryID: "105", CategoryName: "Hindi - Entertainment"},
+		{CategoryID: "112", CategoryName: "Hindi - News"},
+		{CategoryID: "610", CategoryName: "English - Infotainment"},
+		{CategoryID: "808", CategoryName: "Tamil - Sports"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Categories() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLiveStreams(t *testing.T) {
+	Init(nil, 7)
+	channels := readChannels(t)
+
+	all := LiveStreams(channels, "", testBaseURL)
+	if len(all) != len(channels) {
+		t.Fatalf("LiveStreams() returned %d streams, want %d", len(all), len(channels))
+	}
+	want := LiveStream{
+		Num:               1,
+		Name:              "Colors HD",
+		StreamType:        "live",
+		StreamID:          144,
+		StreamIcon:        testBaseURL + "/jtvimage/Colors_HD.png",
+		EPGChannelID:      "144",
+		CategoryID:        "105",
+		TVArchive:         1,
+		TVArchiveDuration: 7,
+	}
+	if all[0] != want {
+		t.Errorf("LiveStreams()[0] = %+v, want %+v", all[0], want)
+	}
+	if all[1].TVArchive != 0 || all[1].TVArchiveDuration != 0 {
+		t.Errorf("channel without archive advertises catch-up: %+v", all[1])
+	}
+
+	entertainment := LiveStreams(channels, "105", testBaseURL)
+	if len(entertainment) != 2 {
+		t.Fatalf("LiveStreams(105) returned %d streams, want 2", len(entertainment))
+	}
+	for _, stream := range entertainment {
+		if stream.CategoryID != "105" {
+			t.Errorf("stream %d has category %s, want 105", stream.StreamID, stream.CategoryID)
+		}
+	}
+}
+
+func TestNewShortEPG(t *testing.T) {
+	programme := func(start, stop, title string) epg.Programme {
+		return epg.Programme{
+			Channel: "144",
+			Start:   start,
+			Stop:    stop,
+			Title:   epg.Title{Value: title, Lang: "en"},
+			Desc:    epg.Desc{Value: title + " description", Lang: "en"},
+		}
+	}
+	programmes := []epg.Programme{
+		programme("20240301180000 +0530", "20240301190000 +0530", "Ended"),
+		programme("20240301190000 +0530", "20240301200000 +0530", "On Air"),
+		programme("20240301200000 +0530", "20240301210000 +0530", "Next"),
+		programme("20240301210000 +0530", "20240301220000 +0530", "Later"),
+	}
+	now, err := epg.ParseTime("20240301193000 +0530")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := NewShortEPG(programmes, now, 2).EPGListings
+	if len(got) != 2 {
+		t.Fatalf("NewShortEPG() returned %d listings, want 2", len(got))
+	}
+	title, err := base64.StdEncoding.DecodeString(got[0].Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(title) != "On Air" {
+		t.Errorf("first listing is %q, want %q", title, "On Air")
+	}
+	description, _ := base64.StdEncoding.DecodeString(got[1].Description)
+	if string(description) != "Next description" {
+		t.Errorf("second listing description is %q", description)
+	}
+	if got[0].StartTimestamp != "1709299800" || got[0].StopTimestamp != "1709303400" {
+		t.Errorf("listing timestamps are %s - %s", got[0].StartTimestamp, got[0].StopTimestamp)
+	}
+	if got[0].Start != now.Add(-30*time.Minute).Local().Format(LISTING_TIME_FORMAT) {
+		t.Errorf("listing start is %s", got[0].Start)
+	}
+
+	if got := NewShortEPG(programmes, now, 0).EPGListings; len(got) != 3 {
+		t.Errorf("NewShortEPG() without limit returned %d listings, want 3", len(got))
+	}
+}
@@ -112,4 +112,42 @@ epgParent.style.display = 'none';
     const epgData = await epgResponse.json();
     epgParent.style.display = 'block';
     updateEPG(epgData);
+    updateCatchup(epgData);
 })();
+
+const playerFrame = document.getElementById('player_frame');
+const livePlayerURL = playerFrame.src;
+
+// playCatchup plays a programme that already aired, from its start
+function playCatchup(show) {
+    const playerURL = new URL(livePlayerURL);
+    playerURL.searchParams.set('start', Math.floor(show.startEpoch / 1000));
+    playerURL.searchParams.set('end', Math.floor(show.endEpoch / 1000));
+    playerFrame.src = playerURL.href;
+    document.getElementById('watch_live').classList.remove('hidden');
+    window.scrollTo(0, 0);
+}
+
+function playLive() {
+    playerFrame.src = livePlayerURL;
+    document.getElementById('watch_live').classList.add('hidden');
+}
+
+// updateCatchup lists the shows of today that already started, newest first
+function updateCatchup(epgData) {
+    const now = new Date().getTime();
+    const aired = epgData.epg.filter((show) => show.startEpoch < now).reverse();
+    if (aired.length === 0) {
+        return;
+    }
+    const catchupElement = document.getElementById('catchup');
+    aired.forEach((show) => {
+        const button = document.createElement('button');
+        button.className = 'btn btn-outline btn-sm';
+        const startTime = new Date(show.startEpoch).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+        button.innerText = `${startTime} ${show.showname}`;
+        button.onclick = () => playCatchup(show);
+        catchupElement.appendChild(button);
+    });
+    document.getElementById('catchup_parent').classList.remove('hidden');
+}
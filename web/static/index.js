@@ -47,6 +47,8 @@ const loginClick = () => {
   // then redirect to /login?username=xxx&password=xxx
   const username = document.getElementById("username").value;
   const password = document.getElementById("password").value;
+  // Empty account names log in the default account
+  const account = document.getElementById("password_account").value;
   if (!username || !password) {
     return;
   }
@@ -58,7 +60,7 @@ const loginClick = () => {
     headers: {
       "Content-Type": "application/json",
     },
-    body: JSON.stringify({ username, password }),
+    body: JSON.stringify({ username, password, account }),
   })
     .then((res) => res.json())
     .then((data) => {
@@ -110,6 +112,8 @@ const loginOTPVerifyClick = () => {
   // Fetch number and OTP from input
   const number = document.getElementById("number").value;
   const otp = document.getElementById("otp").value;
+  // Empty account names log in the default account
+  const account = document.getElementById("otp_account").value;
   if (!number || !otp) {
     return;
   }
@@ -121,7 +125,7 @@ const loginOTPVerifyClick = () => {
     headers: {
       "Content-Type": "application/json",
     },
-    body: JSON.stringify({ number: `+91${number}`, otp }),
+    body: JSON.stringify({ number: `+91${number}`, otp, account }),
   })
     .then((res) => res.json())
     .then((data) => {
@@ -0,0 +1,35 @@
+const renameRecording = async (id) => {
+  const titleElement = document.querySelector(`#recording-${id} .recording-title`);
+  const title = prompt("New title", titleElement.innerText);
+  if (!title || title === titleElement.innerText) {
+    return;
+  }
+  const response = await fetch(`/api/recordings/${id}`, {
+    method: "PATCH",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    body: JSON.stringify({ title }),
+  });
+  const result = await response.json();
+  if (!response.ok) {
+    alert(result.message);
+    return;
+  }
+  titleElement.innerText = result.title;
+};
+
+const deleteRecording = async (id) => {
+  if (!confirm("Delete this recording? This cannot be undone.")) {
+    return;
+  }
+  const response = await fetch(`/api/recordings/${id}`, {
+    method: "DELETE",
+  });
+  if (!response.ok) {
+    const result = await response.json();
+    alert(result.message);
+    return;
+  }
+  document.getElementById(`recording-${id}`).remove();
+};